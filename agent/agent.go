@@ -0,0 +1,350 @@
+// Package agent 实现本程序的 worker agent 模式：向服务器注册并定期心跳，
+// 收到运行分配后以 Locust --worker 模式连接服务器上的 master
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"loadtest_project/services"
+	"loadtest_project/utils"
+)
+
+// heartbeatInterval 心跳与拉取分配的间隔
+const heartbeatInterval = 3 * time.Second
+
+// configFile worker 工作目录下的运行配置文件名
+const configFile = "config.json"
+
+// credentialFile 工作目录下保存 agent 凭据的文件，重启后凭此重新注册同名 agent
+const credentialFile = "agent.credential"
+
+// Options agent 模式的运行参数
+type Options struct {
+	Server   string // API 服务器地址，如 http://10.0.0.1:8080
+	Name     string // agent 名称，重复注册时按名称识别
+	Token    string // 与服务器共享的 agent 令牌
+	Python   string // Locust 所在 Python 解释器
+	WorkDir  string // 存放分配文件的工作目录
+	MaxUsers int    // 可同时承担的虚拟用户数上限，服务器按剩余容量分配运行
+}
+
+// worker 正在执行的一个运行
+type worker struct {
+	assignment *services.WorkerAssignment
+	cmd        *exec.Cmd
+	exited     chan error
+}
+
+type agent struct {
+	opts       Options
+	masterHost string
+	client     *http.Client
+	cpu        utils.CPUSampler
+	id         int
+	credential string // 注册时服务器签发的 agent 凭据
+
+	workers  map[string]*worker
+	finished map[string]bool // 已结束的运行，避免服务器尚未收回分配时重复启动
+	lastErr  string
+}
+
+// heartbeatResponse 服务器对心跳的响应，只带分配的运行标识，完整分配另行拉取
+type heartbeatResponse struct {
+	RunIDs []string `json:"run_ids"`
+}
+
+// Run 以 agent 模式运行，直到进程退出
+func Run(opts Options) error {
+	u, err := url.Parse(opts.Server)
+	if err != nil || u.Hostname() == "" {
+		return fmt.Errorf("无效的服务器地址: %q", opts.Server)
+	}
+	if opts.Token == "" {
+		return errors.New("缺少 agent 令牌")
+	}
+	if u.Scheme != "https" {
+		log.Println("警告: 未使用 HTTPS 连接服务器，agent 凭据与运行所需的 secret 将以明文传输")
+	}
+	if err := os.MkdirAll(opts.WorkDir, 0700); err != nil {
+		return err
+	}
+	a := &agent{
+		opts:       opts,
+		masterHost: u.Hostname(),
+		client:     &http.Client{Timeout: 30 * time.Second},
+		workers:    make(map[string]*worker),
+		finished:   make(map[string]bool),
+	}
+	if data, err := os.ReadFile(filepath.Join(opts.WorkDir, credentialFile)); err == nil {
+		a.credential = strings.TrimSpace(string(data))
+	}
+	// 建立 CPU 采样基准；不支持的平台心跳不带资源数据，服务器无法据此判断 agent 是否饱和
+	if _, err := a.cpu.Percent(); err != nil {
+		log.Println("警告: 无法采集 CPU / 内存使用率，心跳将不上报资源数据:", err)
+	}
+
+	for {
+		if a.id == 0 {
+			if err := a.register(); err != nil {
+				log.Println("注册失败:", err)
+				time.Sleep(5 * heartbeatInterval)
+				continue
+			}
+			log.Printf("已注册为 agent %d (%s)，最多承担 %d 个虚拟用户", a.id, opts.Name, opts.MaxUsers)
+		}
+		a.checkWorkers()
+		runIDs, err := a.heartbeat()
+		if err != nil {
+			log.Println("心跳失败:", err)
+		} else {
+			a.reconcile(runIDs)
+		}
+		time.Sleep(heartbeatInterval)
+	}
+}
+
+// reconcile 按服务器上的分配启动新运行、停止已被收回的运行
+func (a *agent) reconcile(runIDs []string) {
+	assigned := make(map[string]bool, len(runIDs))
+	for _, id := range runIDs {
+		assigned[id] = true
+	}
+	for id, w := range a.workers {
+		if !assigned[id] {
+			// 服务器已收回分配，master 不再等待本 worker
+			log.Printf("运行 %s 已被收回，停止 worker", id)
+			w.cmd.Process.Kill()
+			a.finish(id, <-w.exited)
+		}
+	}
+	for id := range a.finished {
+		if !assigned[id] {
+			delete(a.finished, id)
+		}
+	}
+
+	for _, id := range runIDs {
+		if a.workers[id] != nil || a.finished[id] {
+			continue
+		}
+		var assignment services.WorkerAssignment
+		err := a.post("/api/agents/assignment", payload{"agent_id": a.id, "run_id": id}, &assignment)
+		if err == nil && assignment.RunID != id {
+			err = errors.New("分配已变更")
+		}
+		if err == nil {
+			err = a.resolveSecrets(&assignment)
+		}
+		if err == nil {
+			err = a.startWorker(&assignment)
+		}
+		if err != nil {
+			log.Printf("启动运行 %s 失败: %v", id, err)
+			a.lastErr = err.Error()
+			a.finished[id] = true
+		}
+	}
+}
+
+// resolveSecrets 分配中的 secret 只是引用，按需向服务器拉取明文并替换到配置与文件中
+func (a *agent) resolveSecrets(as *services.WorkerAssignment) error {
+	needed := services.HasSecretRef(string(as.Config))
+	for _, content := range as.Files {
+		needed = needed || services.HasSecretRef(string(content))
+	}
+	if !needed {
+		return nil
+	}
+	var resp struct {
+		Secrets map[string]string `json:"secrets"`
+	}
+	if err := a.post("/api/agents/secrets", payload{"agent_id": a.id, "run_id": as.RunID}, &resp); err != nil {
+		return fmt.Errorf("拉取 secret 失败: %w", err)
+	}
+	config, err := services.SubstituteSecrets(as.Config, resp.Secrets, true)
+	if err != nil {
+		return err
+	}
+	as.Config = config
+	for name, content := range as.Files {
+		if as.Files[name], err = services.SubstituteSecrets(content, resp.Secrets, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkWorkers 处理已自行退出的 worker（master 结束后 worker 会随之退出）
+func (a *agent) checkWorkers() {
+	for id, w := range a.workers {
+		select {
+		case err := <-w.exited:
+			a.finish(id, err)
+		default:
+		}
+	}
+}
+
+func (a *agent) finish(runID string, err error) {
+	if err != nil {
+		a.lastErr = fmt.Sprintf("运行 %s: %v", runID, err)
+	}
+	log.Printf("运行 %s 结束", runID)
+	// 工作目录中含解析后的 secrets，运行结束即删除
+	os.RemoveAll(a.runDir(runID))
+	delete(a.workers, runID)
+	a.finished[runID] = true
+}
+
+func (a *agent) runDir(runID string) string {
+	return filepath.Join(a.opts.WorkDir, filepath.Base(runID))
+}
+
+// startWorker 写出分配文件并以 --worker 模式启动 Locust
+func (a *agent) startWorker(as *services.WorkerAssignment) error {
+	dir := a.runDir(as.RunID)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	for name, content := range as.Files {
+		if err := os.WriteFile(filepath.Join(dir, filepath.Base(name)), content, 0600); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, configFile), as.Config, 0600); err != nil {
+		return err
+	}
+	logFile, err := os.Create(filepath.Join(dir, "worker.log"))
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(
+		a.opts.Python,
+		"-m", "locust",
+		"-f", services.WorkerLocustfile,
+		"--worker",
+		"--master-host", a.masterHost,
+		"--master-port", strconv.Itoa(as.MasterPort),
+	)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), services.RunnerConfigEnv+"="+configFile)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	if err := cmd.Start(); err != nil {
+		logFile.Close()
+		return err
+	}
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+		logFile.Close()
+	}()
+
+	log.Printf("开始运行 %s（任务 %d，%d 个虚拟用户），master %s:%d",
+		as.RunID, as.TestID, as.Users, a.masterHost, as.MasterPort)
+	a.workers[as.RunID] = &worker{assignment: as, cmd: cmd, exited: exited}
+	return nil
+}
+
+func (a *agent) register() error {
+	hostname, _ := os.Hostname()
+	var resp struct {
+		Agent struct {
+			ID int `json:"id"`
+		} `json:"agent"`
+		Credential string `json:"credential"`
+	}
+	body := payload{
+		"name":      a.opts.Name,
+		"hostname":  hostname,
+		"cpu_cores": runtime.NumCPU(),
+		"max_users": a.opts.MaxUsers,
+	}
+	if err := a.post("/api/agents/register", body, &resp); err != nil {
+		return err
+	}
+	// 凭据写入工作目录（仅所有者可读），重启后同名注册时出示
+	if err := os.WriteFile(filepath.Join(a.opts.WorkDir, credentialFile), []byte(resp.Credential), 0600); err != nil {
+		return err
+	}
+	a.id = resp.Agent.ID
+	a.credential = resp.Credential
+	return nil
+}
+
+// heartbeat 上报资源使用率、当前运行与最近的错误，返回服务器上分配的运行标识
+func (a *agent) heartbeat() ([]string, error) {
+	running := make([]string, 0, len(a.workers))
+	for id := range a.workers {
+		running = append(running, id)
+	}
+	body := payload{"agent_id": a.id, "run_ids": running, "error": a.lastErr}
+	// 不支持采集的平台不上报，服务器按 0 处理
+	if cpu, err := a.cpu.Percent(); err == nil {
+		body["cpu_percent"] = cpu
+	}
+	if mem, err := utils.MemoryPercent(); err == nil {
+		body["mem_percent"] = mem
+	}
+	var resp heartbeatResponse
+	err := a.post("/api/agents/heartbeat", body, &resp)
+	var status statusError
+	if errors.As(err, &status) && (status == http.StatusNotFound || status == http.StatusUnauthorized) {
+		// 服务器不认识该 agent（如数据库被重建）或凭据已被替换，重新注册
+		a.id = 0
+	}
+	if err != nil {
+		return nil, err
+	}
+	a.lastErr = ""
+	return resp.RunIDs, nil
+}
+
+// payload JSON 请求体
+type payload map[string]interface{}
+
+// statusError 服务器返回的非 200 状态码
+type statusError int
+
+func (e statusError) Error() string {
+	return fmt.Sprintf("服务器返回 %d", int(e))
+}
+
+func (a *agent) post(path string, body interface{}, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, a.opts.Server+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Agent-Token", a.opts.Token)
+	if a.credential != "" {
+		req.Header.Set("X-Agent-Credential", a.credential)
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,22 @@
+// config/agent.go
+package config
+
+import (
+	"errors"
+	"os"
+)
+
+// AgentTokenEnv 服务器与 worker agent 共享的认证令牌所在环境变量
+const AgentTokenEnv = "LOADTEST_AGENT_TOKEN"
+
+// AgentToken agent 接口的共享令牌，未配置时 agent 接口不可用
+var AgentToken string
+
+// LoadAgentToken 从环境变量读取 agent 共享令牌
+func LoadAgentToken() error {
+	AgentToken = os.Getenv(AgentTokenEnv)
+	if AgentToken == "" {
+		return errors.New(AgentTokenEnv + " 未设置")
+	}
+	return nil
+}
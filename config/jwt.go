@@ -0,0 +1,32 @@
+// config/jwt.go
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// JWTKeyEnv 签发登录 access token 的 HS256 密钥（base64 编码，至少 32 字节）所在环境变量
+const JWTKeyEnv = "LOADTEST_JWT_KEY"
+
+// JWTKey 登录 access token 的签名密钥
+var JWTKey []byte
+
+// LoadJWTKey 从环境变量读取签名密钥；未配置时服务不能启动，否则任何人都能伪造 token
+func LoadJWTKey() error {
+	raw := os.Getenv(JWTKeyEnv)
+	if raw == "" {
+		return errors.New(JWTKeyEnv + " 未设置")
+	}
+	key, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		return fmt.Errorf("%s 不是合法的 base64: %w", JWTKeyEnv, err)
+	}
+	if len(key) < 32 {
+		return fmt.Errorf("%s 长度应至少 32 字节，实际 %d", JWTKeyEnv, len(key))
+	}
+	JWTKey = key
+	return nil
+}
@@ -0,0 +1,93 @@
+// config/ldap.go
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LDAP 登录配置所在环境变量
+const (
+	LDAPURLEnv          = "LOADTEST_LDAP_URL"           // ldap://host:389 或 ldaps://host:636
+	LDAPStartTLSEnv     = "LOADTEST_LDAP_STARTTLS"      // 为 true 时在 ldap:// 连接上启用 StartTLS
+	LDAPBindDNEnv       = "LOADTEST_LDAP_BIND_DN"       // 查找用户所用的服务账号，不设则匿名查找
+	LDAPBindPasswordEnv = "LOADTEST_LDAP_BIND_PASSWORD" // 服务账号密码
+	LDAPBaseDNEnv       = "LOADTEST_LDAP_BASE_DN"       // 查找用户的起始 DN
+	LDAPUserFilterEnv   = "LOADTEST_LDAP_USER_FILTER"   // 查找用户的过滤器，%s 为转义后的用户名，默认 (uid=%s)
+	LDAPGroupAttrEnv    = "LOADTEST_LDAP_GROUP_ATTR"    // 用户条目上记录所在组的属性，默认 memberOf
+	LDAPRoleMapEnv      = "LOADTEST_LDAP_ROLE_MAP"      // 组 DN 到系统角色的映射，如 "cn=lt-admins,ou=groups,dc=example,dc=com=admin"
+	LDAPLocalUsersEnv   = "LOADTEST_LDAP_LOCAL_USERS"   // 逗号分隔，始终用本地密码登录的用户，默认 admin
+)
+
+// LDAPConfig LDAP 登录配置
+type LDAPConfig struct {
+	URL          string
+	StartTLS     bool
+	BindDN       string
+	BindPassword string
+	BaseDN       string
+	UserFilter   string
+	GroupAttr    string
+	RoleMap      GroupRoleMap
+	LocalUsers   map[string]bool // 键为小写用户名
+}
+
+// LDAP 登录配置，未配置时为 nil，只使用本地账号
+var LDAP *LDAPConfig
+
+// LoadLDAPConfig 从环境变量读取 LDAP 配置
+func LoadLDAPConfig() error {
+	cfg := &LDAPConfig{
+		URL:          os.Getenv(LDAPURLEnv),
+		StartTLS:     os.Getenv(LDAPStartTLSEnv) == "true",
+		BindDN:       os.Getenv(LDAPBindDNEnv),
+		BindPassword: os.Getenv(LDAPBindPasswordEnv),
+		BaseDN:       os.Getenv(LDAPBaseDNEnv),
+		UserFilter:   os.Getenv(LDAPUserFilterEnv),
+		GroupAttr:    os.Getenv(LDAPGroupAttrEnv),
+		LocalUsers:   map[string]bool{},
+	}
+	if cfg.URL == "" {
+		return errors.New(LDAPURLEnv + " 未设置")
+	}
+	if cfg.BaseDN == "" {
+		return errors.New(LDAPBaseDNEnv + " 未设置")
+	}
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = "(uid=%s)"
+	}
+	if strings.Count(cfg.UserFilter, "%s") != 1 {
+		return fmt.Errorf("%s 应包含且只包含一个 %%s", LDAPUserFilterEnv)
+	}
+	if cfg.GroupAttr == "" {
+		cfg.GroupAttr = "memberOf"
+	}
+	roleMap, err := ParseGroupRoleMap(os.Getenv(LDAPRoleMapEnv))
+	if err != nil {
+		return fmt.Errorf("%s: %w", LDAPRoleMapEnv, err)
+	}
+	// DN 不区分大小写，统一按小写匹配
+	cfg.RoleMap = GroupRoleMap{}
+	for group, role := range roleMap {
+		cfg.RoleMap[strings.ToLower(group)] = role
+	}
+	localUsers := os.Getenv(LDAPLocalUsersEnv)
+	if localUsers == "" {
+		localUsers = "admin"
+	}
+	// MySQL 比较用户名不区分大小写，保留账号统一按小写匹配，避免 "Admin" 绕过
+	for _, u := range strings.Split(localUsers, ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			cfg.LocalUsers[strings.ToLower(u)] = true
+		}
+	}
+	LDAP = cfg
+	return nil
+}
+
+// IsLocalUser 判断用户名是否为保留的本地账号，不区分大小写
+func (c *LDAPConfig) IsLocalUser(username string) bool {
+	return c.LocalUsers[strings.ToLower(username)]
+}
@@ -0,0 +1,101 @@
+// config/oidc.go
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// OIDC 单点登录配置所在环境变量
+const (
+	OIDCIssuerEnv       = "LOADTEST_OIDC_ISSUER"        // IdP 的 issuer URL，据此获取 /.well-known/openid-configuration
+	OIDCClientIDEnv     = "LOADTEST_OIDC_CLIENT_ID"     // 在 IdP 注册的客户端 ID
+	OIDCClientSecretEnv = "LOADTEST_OIDC_CLIENT_SECRET" // 客户端密钥，公开客户端（仅 PKCE）可不设
+	OIDCRedirectURLEnv  = "LOADTEST_OIDC_REDIRECT_URL"  // 回调地址，如 http://localhost:8080/api/oidc/callback
+	OIDCScopesEnv       = "LOADTEST_OIDC_SCOPES"        // 空格分隔，默认 "openid profile email groups"
+	OIDCGroupsClaimEnv  = "LOADTEST_OIDC_GROUPS_CLAIM"  // ID Token 中组信息的字段名，默认 groups
+	OIDCRoleMapEnv      = "LOADTEST_OIDC_ROLE_MAP"      // 组到系统角色的映射，如 "lt-admins=admin;lt-users=user"
+)
+
+// OIDCConfig OIDC 单点登录配置
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	GroupsClaim  string
+	RoleMap      GroupRoleMap
+}
+
+// OIDC 单点登录配置，未配置时为 nil，SSO 登录不可用
+var OIDC *OIDCConfig
+
+// LoadOIDCConfig 从环境变量读取 OIDC 配置
+func LoadOIDCConfig() error {
+	issuer := strings.TrimRight(os.Getenv(OIDCIssuerEnv), "/")
+	if issuer == "" {
+		return errors.New(OIDCIssuerEnv + " 未设置")
+	}
+	cfg := &OIDCConfig{
+		Issuer:       issuer,
+		ClientID:     os.Getenv(OIDCClientIDEnv),
+		ClientSecret: os.Getenv(OIDCClientSecretEnv),
+		RedirectURL:  os.Getenv(OIDCRedirectURLEnv),
+		Scopes:       strings.Fields(os.Getenv(OIDCScopesEnv)),
+		GroupsClaim:  os.Getenv(OIDCGroupsClaimEnv),
+	}
+	if cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return fmt.Errorf("%s 与 %s 必须设置", OIDCClientIDEnv, OIDCRedirectURLEnv)
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "profile", "email", "groups"}
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	roleMap, err := ParseGroupRoleMap(os.Getenv(OIDCRoleMapEnv))
+	if err != nil {
+		return fmt.Errorf("%s: %w", OIDCRoleMapEnv, err)
+	}
+	cfg.RoleMap = roleMap
+	OIDC = cfg
+	return nil
+}
+
+// GroupRoleMap 外部身份源（IdP、LDAP）的组到系统角色（admin / user）的映射
+type GroupRoleMap map[string]string
+
+// ParseGroupRoleMap 解析 "组=角色;组=角色"，组名区分大小写。
+// 用分号分隔是因为 LDAP 组 DN 本身含逗号与等号，角色取最后一个等号之后的部分
+func ParseGroupRoleMap(s string) (GroupRoleMap, error) {
+	m := GroupRoleMap{}
+	for _, item := range strings.Split(s, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		i := strings.LastIndex(item, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("无效的映射 %q，应为 组=角色", item)
+		}
+		group, role := strings.TrimSpace(item[:i]), strings.TrimSpace(item[i+1:])
+		if role != "admin" && role != "user" {
+			return nil, fmt.Errorf("组 %q 的角色 %q 无效，只能是 admin 或 user", group, role)
+		}
+		m[group] = role
+	}
+	return m, nil
+}
+
+// Role 按用户所在的组确定系统角色：命中任一映射为 admin 的组即为 admin，否则为 user
+func (m GroupRoleMap) Role(groups []string) string {
+	for _, g := range groups {
+		if m[g] == "admin" {
+			return "admin"
+		}
+	}
+	return "user"
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGroupRoleMap(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    GroupRoleMap
+		wantErr bool
+	}{
+		{"", GroupRoleMap{}, false},
+		{"lt-admins=admin; lt-users=user", GroupRoleMap{"lt-admins": "admin", "lt-users": "user"}, false},
+		// LDAP 组 DN 含逗号与等号
+		{"cn=ops,ou=groups,dc=example,dc=com=admin", GroupRoleMap{"cn=ops,ou=groups,dc=example,dc=com": "admin"}, false},
+		{"lt-admins", nil, true},
+		{"=admin", nil, true},
+		{"lt-admins=root", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseGroupRoleMap(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseGroupRoleMap(%q) err = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseGroupRoleMap(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestGroupRoleMapRole(t *testing.T) {
+	m := GroupRoleMap{"lt-admins": "admin", "lt-users": "user"}
+	tests := []struct {
+		groups []string
+		want   string
+	}{
+		{nil, "user"},
+		{[]string{"lt-users"}, "user"},
+		{[]string{"lt-users", "lt-admins"}, "admin"},
+		{[]string{"LT-Admins"}, "user"}, // 区分大小写，LDAP 在加载配置时统一转为小写
+	}
+	for _, tt := range tests {
+		if got := m.Role(tt.groups); got != tt.want {
+			t.Errorf("Role(%v) = %q, want %q", tt.groups, got, tt.want)
+		}
+	}
+}
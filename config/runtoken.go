@@ -0,0 +1,37 @@
+// config/runtoken.go
+package config
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+)
+
+// RunTokenKeyEnv 签发 runner 结果上传令牌的 HMAC 密钥（base64 编码，至少 32 字节）所在环境变量
+const RunTokenKeyEnv = "LOADTEST_RUN_TOKEN_KEY"
+
+// RunTokenKey runner 结果上传令牌的 HMAC 密钥
+var RunTokenKey []byte
+
+// LoadRunTokenKey 从环境变量读取 HMAC 密钥；未配置时生成随机密钥，
+// 令牌只在运行期间使用，重启后进行中的运行无法再上传结果
+func LoadRunTokenKey() error {
+	raw := os.Getenv(RunTokenKeyEnv)
+	if raw == "" {
+		RunTokenKey = make([]byte, 32)
+		if _, err := rand.Read(RunTokenKey); err != nil {
+			return err
+		}
+		return fmt.Errorf("%s 未设置，使用随机密钥", RunTokenKeyEnv)
+	}
+	key, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		return fmt.Errorf("%s 不是合法的 base64: %w", RunTokenKeyEnv, err)
+	}
+	if len(key) < 32 {
+		return fmt.Errorf("%s 长度应至少 32 字节，实际 %d", RunTokenKeyEnv, len(key))
+	}
+	RunTokenKey = key
+	return nil
+}
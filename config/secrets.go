@@ -0,0 +1,32 @@
+// config/secrets.go
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// MasterKeyEnv 存放 secrets 主密钥（base64 编码的 32 字节）的环境变量
+const MasterKeyEnv = "LOADTEST_MASTER_KEY"
+
+// MasterKey 用于 AES-256-GCM 加密 secrets 的主密钥，未配置时为 nil
+var MasterKey []byte
+
+// LoadMasterKey 从环境变量读取主密钥
+func LoadMasterKey() error {
+	raw := os.Getenv(MasterKeyEnv)
+	if raw == "" {
+		return errors.New(MasterKeyEnv + " 未设置")
+	}
+	key, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		return fmt.Errorf("%s 不是合法的 base64: %w", MasterKeyEnv, err)
+	}
+	if len(key) != 32 {
+		return fmt.Errorf("%s 长度应为 32 字节，实际 %d", MasterKeyEnv, len(key))
+	}
+	MasterKey = key
+	return nil
+}
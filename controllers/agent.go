@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"loadtest_project/config"
+	"loadtest_project/models"
+	"loadtest_project/services"
+	"loadtest_project/utils"
+)
+
+// maxWorkers 单个任务可使用的 worker 数上限
+const maxWorkers = 100
+
+// agentAuthorized 校验 X-Agent-Token 头中的共享令牌，失败时直接写出响应
+func agentAuthorized(c *gin.Context) bool {
+	token := c.GetHeader("X-Agent-Token")
+	if config.AgentToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(config.AgentToken)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的 agent 令牌"})
+		return false
+	}
+	return true
+}
+
+// agentCredentialMatches X-Agent-Credential 头中的 agent 凭据是否与保存的哈希一致
+func agentCredentialMatches(c *gin.Context, hash string) bool {
+	credential := c.GetHeader("X-Agent-Credential")
+	return hash != "" && credential != "" &&
+		subtle.ConstantTimeCompare([]byte(utils.HashToken(credential)), []byte(hash)) == 1
+}
+
+// insecureAgentOnce 只提示一次 agent 接口未经 HTTPS 访问
+var insecureAgentOnce sync.Once
+
+// warnInsecureAgent agent 凭据与 secret 经明文 HTTP 传输时在服务器日志中告警
+func warnInsecureAgent(c *gin.Context) {
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		return
+	}
+	insecureAgentOnce.Do(func() {
+		log.Println("警告: agent 接口经 HTTP 访问，agent 凭据与运行所需的 secret 将以明文传输，请通过 HTTPS 暴露服务器")
+	})
+}
+
+// RegisterAgent agent 启动时注册，按名称识别同一个 agent，并签发该 agent 专用的凭据。
+// 同名 agent 仍在线时须出示其当前凭据，避免持有共享令牌者冒名接管其分配
+func RegisterAgent(c *gin.Context) {
+	if !agentAuthorized(c) {
+		return
+	}
+	warnInsecureAgent(c)
+	var a models.Agent
+	if err := c.ShouldBindJSON(&a); err != nil || a.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少 name"})
+		return
+	}
+	existing, hash, err := models.GetAgentByName(a.Name)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "注册失败"})
+		return
+	case time.Since(existing.LastSeen) <= services.AgentOnlineWindow && hash != "" && !agentCredentialMatches(c, hash):
+		c.JSON(http.StatusConflict, gin.H{"error": "同名 agent 在线，需提供其 agent 凭据"})
+		return
+	}
+	credential, hash, err := utils.GenerateAgentCredential()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成 agent 凭据失败"})
+		return
+	}
+	if err := models.RegisterAgent(&a, hash); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "注册失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"agent": a, "credential": credential})
+}
+
+// agentRequest agent 心跳与拉取分配的请求体
+type agentRequest struct {
+	AgentID    int      `json:"agent_id"`
+	RunIDs     []string `json:"run_ids"`     // 心跳：agent 正在执行的运行
+	RunID      string   `json:"run_id"`      // 拉取分配：要拉取的运行
+	CPUPercent float64  `json:"cpu_percent"` // 心跳：整机 CPU 使用率
+	MemPercent float64  `json:"mem_percent"` // 心跳：整机内存使用率
+	Error      string   `json:"error"`       // 上次心跳以来的错误
+}
+
+// bindAgentRequest 解析请求、校验 agent 凭据并记录心跳。agent 不存在时返回 404、
+// 凭据不符时返回 401，agent 收到后重新注册
+func bindAgentRequest(c *gin.Context) (agentRequest, bool) {
+	var req agentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求格式错误"})
+		return req, false
+	}
+	hash, err := models.GetAgentCredentialHash(req.AgentID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "agent 不存在"})
+		return req, false
+	}
+	if !agentCredentialMatches(c, hash) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的 agent 凭据"})
+		return req, false
+	}
+	if err := models.TouchAgent(req.AgentID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "agent 不存在"})
+		return req, false
+	}
+	return req, true
+}
+
+// AgentHeartbeat 记录心跳与资源使用率，返回分配给该 agent 的全部运行标识
+func AgentHeartbeat(c *gin.Context) {
+	if !agentAuthorized(c) {
+		return
+	}
+	req, ok := bindAgentRequest(c)
+	if !ok {
+		return
+	}
+	if req.Error != "" {
+		fmt.Printf("agent %d 报告错误: %s\n", req.AgentID, req.Error)
+	}
+	if err := models.UpdateAgentStats(req.AgentID, req.CPUPercent, req.MemPercent); err != nil {
+		fmt.Println("更新 agent 资源数据失败:", err)
+	}
+	c.JSON(http.StatusOK, gin.H{"run_ids": services.AgentRunIDs(req.AgentID)})
+}
+
+// GetAgentAssignment 返回完整的运行分配（locustfile、配置及引用的文件），只下发给被分配的 agent
+func GetAgentAssignment(c *gin.Context) {
+	if !agentAuthorized(c) {
+		return
+	}
+	warnInsecureAgent(c)
+	req, ok := bindAgentRequest(c)
+	if !ok {
+		return
+	}
+	as := services.AgentAssignment(req.AgentID, req.RunID)
+	if as == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "没有分配"})
+		return
+	}
+	c.JSON(http.StatusOK, as)
+}
+
+// GetAgentSecrets 返回分配给该 agent 的运行所引用的 secret 明文，分配收回后不再可取
+func GetAgentSecrets(c *gin.Context) {
+	if !agentAuthorized(c) {
+		return
+	}
+	warnInsecureAgent(c)
+	req, ok := bindAgentRequest(c)
+	if !ok {
+		return
+	}
+	secrets, ok := services.AgentSecrets(req.AgentID, req.RunID)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "没有分配"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"secrets": secrets})
+}
+
+// ListAgents 管理员查看全部 agent 的在线状态、资源使用率、已分配用户数与当前运行
+func ListAgents(c *gin.Context) {
+	agents, err := models.ListAgents(time.Time{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+	list := make([]gin.H, 0, len(agents))
+	for _, a := range agents {
+		list = append(list, gin.H{
+			"agent":          a,
+			"online":         time.Since(a.LastSeen) <= services.AgentOnlineWindow,
+			"saturated":      services.AgentSaturated(a),
+			"assigned_users": services.AgentAssignedUsers(a.ID),
+			"run_ids":        services.AgentRunIDs(a.ID),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"agents": list})
+}
+
+// validateDistributedOptions 校验 worker 数量
+func validateDistributedOptions(opts *models.DistributedOptions) error {
+	if opts.Workers < 0 || opts.Workers > maxWorkers {
+		return fmt.Errorf("workers 需在 0~%d 之间", maxWorkers)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"loadtest_project/models"
+	"loadtest_project/utils"
+)
+
+// API 令牌有效期（天）：未指定时取默认值，不允许永久有效
+const (
+	defaultAPIKeyDays = 90
+	maxAPIKeyDays     = 365
+)
+
+// createAPIKeyRequest 创建 API 令牌的请求体
+type createAPIKeyRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+// CreateAPIKey 创建 API 令牌，明文只在响应中出现一次；只能通过登录得到的 Token 调用
+func CreateAPIKey(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	var req createAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少 name"})
+		return
+	}
+	if len(req.Scopes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "至少需要一个权限范围（read、write、approve）"})
+		return
+	}
+	seen := make(map[string]bool)
+	var scopes []string
+	for _, s := range req.Scopes {
+		if !models.ValidScope(s) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的权限范围: " + s})
+			return
+		}
+		if !seen[s] {
+			seen[s] = true
+			scopes = append(scopes, s)
+		}
+	}
+	days := req.ExpiresInDays
+	if days == 0 {
+		days = defaultAPIKeyDays
+	}
+	if days < 0 || days > maxAPIKeyDays {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in_days 应在 1~" + strconv.Itoa(maxAPIKeyDays) + " 之间"})
+		return
+	}
+
+	plain, hash, err := utils.GenerateAPIKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成令牌失败"})
+		return
+	}
+	expires := time.Now().AddDate(0, 0, days)
+	key := models.APIKey{
+		UserID:    claims.UserID,
+		Name:      strings.TrimSpace(req.Name),
+		Prefix:    plain[:len(utils.APIKeyPrefix)+6],
+		TokenHash: hash,
+		Scopes:    scopes,
+		ExpiresAt: &expires,
+	}
+	if err := models.CreateAPIKey(&key); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存令牌失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"token": plain, "api_key": key, "message": "令牌只显示这一次，请妥善保存"})
+}
+
+// ListAPIKeys 列出当前用户的 API 令牌（不含明文）
+func ListAPIKeys(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	list, err := models.ListAPIKeys(claims.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"api_keys": list})
+}
+
+// RevokeAPIKey 吊销 API 令牌 ?id=xxx，立即失效
+func RevokeAPIKey(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 id"})
+		return
+	}
+	found, err := models.RevokeAPIKey(claims.UserID, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "吊销失败"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "令牌不存在或已吊销"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "令牌已吊销"})
+}
@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"loadtest_project/models"
+	"loadtest_project/models/testdb"
+	"loadtest_project/utils"
+)
+
+func TestRequiredScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		method, route string
+		scope         string
+		allowed       bool
+	}{
+		{http.MethodGet, "/api/tests", models.ScopeRead, true},
+		{http.MethodHead, "/api/tests", models.ScopeRead, true},
+		{http.MethodGet, "/api/reports/:id", models.ScopeRead, true},
+		{http.MethodPost, "/api/submit", models.ScopeWrite, true},
+		{http.MethodDelete, "/api/datasets/:id", models.ScopeWrite, true},
+		{http.MethodPost, "/admin/approve", models.ScopeApprove, true},
+		{http.MethodPost, "/admin/reject", models.ScopeApprove, true},
+		// 管理令牌本身的接口只接受登录令牌，避免泄露的 API 令牌再签发新令牌
+		{http.MethodGet, "/api/tokens", "", false},
+		{http.MethodPost, "/api/tokens", "", false},
+		{http.MethodDelete, "/api/tokens", "", false},
+	}
+	for _, tt := range tests {
+		var scope string
+		var allowed bool
+		r := gin.New()
+		r.Handle(tt.method, tt.route, func(c *gin.Context) { scope, allowed = requiredScope(c) })
+		path := strings.Replace(tt.route, ":id", "1", 1)
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, path, nil))
+		if scope != tt.scope || allowed != tt.allowed {
+			t.Errorf("%s %s: requiredScope = %q, %v, want %q, %v", tt.method, tt.route, scope, allowed, tt.scope, tt.allowed)
+		}
+	}
+}
+
+// apiKeyStore 按哈希保存 API 令牌，并记录查询时用的哈希
+type apiKeyStore struct {
+	keys    map[string]models.APIKey
+	lookups []string
+	touched []int64
+}
+
+func (s *apiKeyStore) add(t *testing.T, scopes []string, expiresAt, revokedAt *time.Time) string {
+	t.Helper()
+	plain, hash, err := utils.GenerateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.keys[hash] = models.APIKey{
+		ID: len(s.keys) + 1, UserID: 7, Name: "ci", Prefix: plain[:8], TokenHash: hash,
+		Scopes: scopes, ExpiresAt: expiresAt, RevokedAt: revokedAt, CreatedAt: time.Now(),
+	}
+	return plain
+}
+
+func nullTime(t *time.Time) driver.Value {
+	if t == nil {
+		return nil
+	}
+	return *t
+}
+
+func (s *apiKeyStore) handle(query string, args []driver.Value) (testdb.Result, error) {
+	switch {
+	case strings.Contains(query, "FROM api_keys WHERE token_hash=?"):
+		hash := args[0].(string)
+		s.lookups = append(s.lookups, hash)
+		k, ok := s.keys[hash]
+		if !ok {
+			return testdb.Result{}, nil
+		}
+		return testdb.Row(
+			[]string{"id", "user_id", "name", "prefix", "token_hash", "scopes", "expires_at", "last_used_at", "revoked_at", "created_at"},
+			int64(k.ID), int64(k.UserID), k.Name, k.Prefix, k.TokenHash, strings.Join(k.Scopes, ","),
+			nullTime(k.ExpiresAt), nil, nullTime(k.RevokedAt), k.CreatedAt,
+		), nil
+	case strings.HasPrefix(query, "SELECT role FROM users WHERE id=?"):
+		return testdb.Row([]string{"role"}, "user"), nil
+	case strings.HasPrefix(query, "UPDATE api_keys SET last_used_at=?"):
+		s.touched = append(s.touched, args[1].(int64))
+		return testdb.Result{RowsAffected: 1}, nil
+	}
+	return testdb.Result{}, &unexpectedQueryError{query}
+}
+
+func TestAPIKeyClaims(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := &apiKeyStore{keys: map[string]models.APIKey{}}
+	orig := models.DB
+	t.Cleanup(func() { models.DB = orig })
+	models.DB = testdb.Open(t, store.handle)
+
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	readOnly := store.add(t, []string{models.ScopeRead}, nil, nil)
+	readWrite := store.add(t, []string{models.ScopeRead, models.ScopeWrite}, &future, nil)
+	revoked := store.add(t, []string{models.ScopeRead, models.ScopeWrite}, nil, &past)
+	expired := store.add(t, []string{models.ScopeRead, models.ScopeWrite}, &past, nil)
+
+	tests := []struct {
+		name          string
+		token         string
+		method, route string
+		wantErr       error
+	}{
+		{"只读令牌可调用 GET 接口", readOnly, http.MethodGet, "/api/tests", nil},
+		{"只读令牌不能提交任务", readOnly, http.MethodPost, "/api/submit", errAPIKeyScope},
+		{"读写令牌可提交任务", readWrite, http.MethodPost, "/api/submit", nil},
+		{"没有 approve 范围不能审批", readWrite, http.MethodPost, "/admin/approve", errAPIKeyScope},
+		{"API 令牌不能管理令牌", readWrite, http.MethodGet, "/api/tokens", errAPIKeyNotAllowed},
+		{"已吊销的令牌", revoked, http.MethodGet, "/api/tests", errAPIKeyInvalid},
+		{"已过期的令牌", expired, http.MethodGet, "/api/tests", errAPIKeyInvalid},
+		{"不存在的令牌", utils.APIKeyPrefix + "unknown", http.MethodGet, "/api/tests", errAPIKeyInvalid},
+	}
+	for _, tt := range tests {
+		store.lookups, store.touched = nil, nil
+		var (
+			claims *utils.Claims
+			err    error
+		)
+		r := gin.New()
+		r.Handle(tt.method, tt.route, func(c *gin.Context) { claims, err = tokenClaims(c) })
+		req := httptest.NewRequest(tt.method, tt.route, nil)
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		// 数据库中只按哈希查找，明文不会出现在查询中
+		if len(store.lookups) != 1 || store.lookups[0] != utils.HashToken(tt.token) {
+			t.Errorf("%s: 应按令牌哈希查找一次，got %v", tt.name, store.lookups)
+		}
+		if tt.wantErr == errAPIKeyScope && !strings.Contains(rec.Header().Get("WWW-Authenticate"), "insufficient_scope") {
+			t.Errorf("%s: 权限范围不足时应返回 WWW-Authenticate insufficient_scope", tt.name)
+		}
+		if tt.wantErr != nil {
+			if claims != nil || len(store.touched) != 0 {
+				t.Errorf("%s: 被拒绝的令牌不应得到身份或记录使用时间", tt.name)
+			}
+			continue
+		}
+		key := store.keys[utils.HashToken(tt.token)]
+		if claims == nil || claims.UserID != key.UserID || claims.APIKeyID != key.ID || claims.Role != "user" {
+			t.Errorf("%s: claims = %+v, want 令牌 %d 所属用户 %d", tt.name, claims, key.ID, key.UserID)
+		}
+		if len(store.touched) != 1 || store.touched[0] != int64(key.ID) {
+			t.Errorf("%s: 应记录令牌的使用时间，got %v", tt.name, store.touched)
+		}
+	}
+}
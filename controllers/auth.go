@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"loadtest_project/models"
+	"loadtest_project/utils"
+)
+
+// claimsKey 本次请求已解析的身份在 gin.Context 中的键，避免重复查询与重复记录令牌使用
+const claimsKey = "auth_claims"
+
+var (
+	errAPIKeyInvalid    = errors.New("无效的 API 令牌")
+	errAPIKeyScope      = errors.New("API 令牌的权限范围不足")
+	errAPIKeyNotAllowed = errors.New("该接口不接受 API 令牌")
+)
+
+// tokenClaims 从 Authorization 头解析当前用户，支持“Bearer <token>”或直接"<token>"。
+// token 可以是登录得到的 JWT（已登出或被吊销的除外），也可以是 API 令牌（ltk_ 开头）
+func tokenClaims(c *gin.Context) (*utils.Claims, error) {
+	if v, ok := c.Get(claimsKey); ok {
+		return v.(*utils.Claims), nil
+	}
+	tok := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	var (
+		claims *utils.Claims
+		err    error
+	)
+	if utils.IsAPIKey(tok) {
+		claims, err = apiKeyClaims(c, tok)
+	} else {
+		if claims, err = utils.ParseToken(tok); err == nil {
+			err = checkAccessToken(claims)
+		}
+		if err == nil {
+			// token 中的 role 只是签发时的快照，以用户当前的角色为准
+			if claims.Role, err = models.GetUserRole(claims.UserID); err != nil {
+				err = errTokenRevoked
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.Set(claimsKey, claims)
+	return claims, nil
+}
+
+// apiKeyClaims 校验 API 令牌：未吊销、未过期且权限范围覆盖本次请求，身份按所属用户当前的角色
+func apiKeyClaims(c *gin.Context, tok string) (*utils.Claims, error) {
+	key, err := models.GetAPIKeyByHash(utils.HashToken(tok))
+	if err != nil || key.RevokedAt != nil || (key.ExpiresAt != nil && time.Now().After(*key.ExpiresAt)) {
+		return nil, errAPIKeyInvalid
+	}
+	scope, allowed := requiredScope(c)
+	if !allowed {
+		return nil, errAPIKeyNotAllowed
+	}
+	if !key.HasScope(scope) {
+		c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+scope+`"`)
+		return nil, errAPIKeyScope
+	}
+	role, err := models.GetUserRole(key.UserID)
+	if err != nil {
+		return nil, errAPIKeyInvalid
+	}
+	models.TouchAPIKey(key.ID)
+	return &utils.Claims{UserID: key.UserID, Role: role, APIKeyID: key.ID}, nil
+}
+
+// approveRoutes 需要 approve 权限范围的接口
+var approveRoutes = map[string]bool{
+	"/admin/approve": true,
+	"/admin/reject":  true,
+}
+
+// requiredScope 本次请求需要的 API 令牌权限范围；管理令牌本身的接口不接受 API 令牌
+func requiredScope(c *gin.Context) (scope string, allowed bool) {
+	path := c.FullPath()
+	switch {
+	case strings.HasPrefix(path, "/api/tokens"):
+		return "", false
+	case approveRoutes[path]:
+		return models.ScopeApprove, true
+	case c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead:
+		return models.ScopeRead, true
+	default:
+		return models.ScopeWrite, true
+	}
+}
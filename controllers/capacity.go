@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"loadtest_project/models"
+)
+
+// validateCapacityConfig 校验容量探测的阶梯参数，window 为任务的起止时间窗口，至少要容纳一个阶段
+func validateCapacityConfig(cfg *models.CapacityConfig, window time.Duration) error {
+	if cfg == nil {
+		return errors.New("缺少 capacity 参数")
+	}
+	if cfg.StartUsers <= 0 || cfg.StepUsers <= 0 || cfg.StepDuration <= 0 {
+		return errors.New("start_users、step_users、step_duration 必须大于 0")
+	}
+	if cfg.MaxUsers < cfg.StartUsers {
+		return errors.New("max_users 不能小于 start_users")
+	}
+	if window < time.Duration(cfg.StepDuration)*time.Second {
+		return fmt.Errorf("任务时间窗口 %s 不足一个阶段（step_duration %d 秒）", window, cfg.StepDuration)
+	}
+	if cfg.MaxP95 <= 0 && cfg.MaxErrorRate <= 0 {
+		return errors.New("至少需要设置 max_p95 或 max_error_rate 之一")
+	}
+	if cfg.MaxErrorRate < 0 || cfg.MaxErrorRate > 1 {
+		return errors.New("max_error_rate 取值范围为 0~1")
+	}
+	return nil
+}
+
+// GetCapacityResult 查询容量探测结论 ?test_id=xxx
+func GetCapacityResult(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	testID, err := strconv.Atoi(c.Query("test_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 test_id"})
+		return
+	}
+	task, err := models.GetLoadTestByID(testID)
+	if err != nil || !canAccessTask(claims, task) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+
+	result, err := models.GetCapacityResult(testID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "暂无容量探测结果"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": result})
+}
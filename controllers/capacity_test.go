@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"loadtest_project/models"
+)
+
+func TestValidateCapacityConfig(t *testing.T) {
+	valid := func() *models.CapacityConfig {
+		return &models.CapacityConfig{StartUsers: 10, StepUsers: 10, StepDuration: 60, MaxUsers: 100, MaxP95: 500}
+	}
+	tests := []struct {
+		name    string
+		modify  func(*models.CapacityConfig)
+		window  time.Duration
+		wantErr bool
+	}{
+		{"合法配置", nil, time.Hour, false},
+		{"窗口恰好容纳一个阶段", nil, 60 * time.Second, false},
+		{"窗口比一个阶段少 1 秒", nil, 59 * time.Second, true},
+		{"窗口为 0（结束时间等于开始时间）", nil, 0, true},
+		{"窗口为负（结束时间早于开始时间）", nil, -time.Minute, true},
+		{"start_users 为 0", func(c *models.CapacityConfig) { c.StartUsers = 0 }, time.Hour, true},
+		{"step_users 为负", func(c *models.CapacityConfig) { c.StepUsers = -1 }, time.Hour, true},
+		{"step_duration 为 0", func(c *models.CapacityConfig) { c.StepDuration = 0 }, time.Hour, true},
+		{"max_users 等于 start_users，只跑一个阶段", func(c *models.CapacityConfig) { c.MaxUsers = 10 }, time.Hour, false},
+		{"max_users 小于 start_users", func(c *models.CapacityConfig) { c.MaxUsers = 9 }, time.Hour, true},
+		{"未设置任何 SLO", func(c *models.CapacityConfig) { c.MaxP95 = 0 }, time.Hour, true},
+		{"只设置错误率", func(c *models.CapacityConfig) { c.MaxP95, c.MaxErrorRate = 0, 0.01 }, time.Hour, false},
+		{"错误率上限为 1", func(c *models.CapacityConfig) { c.MaxErrorRate = 1 }, time.Hour, false},
+		{"错误率上限超过 1", func(c *models.CapacityConfig) { c.MaxErrorRate = 1.5 }, time.Hour, true},
+		{"错误率上限为负", func(c *models.CapacityConfig) { c.MaxErrorRate = -0.1 }, time.Hour, true},
+	}
+	for _, tt := range tests {
+		cfg := valid()
+		if tt.modify != nil {
+			tt.modify(cfg)
+		}
+		err := validateCapacityConfig(cfg, tt.window)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+	if err := validateCapacityConfig(nil, time.Hour); err == nil {
+		t.Error("缺少 capacity 参数时应报错")
+	}
+}
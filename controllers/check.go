@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"loadtest_project/models"
+)
+
+// validateStepCheck 校验单个响应断言
+func validateStepCheck(check models.StepCheck) error {
+	switch check.Type {
+	case models.CheckStatus:
+		if _, err := strconv.Atoi(check.Value); err != nil {
+			return fmt.Errorf("status 断言的 value 必须是整数: %q", check.Value)
+		}
+	case models.CheckBodyContains:
+		if check.Value == "" {
+			return errors.New("body_contains 断言的 value 不能为空")
+		}
+	case models.CheckBodyRegex:
+		// Python re 与 Go regexp 语法大体兼容，提前发现明显错误
+		if _, err := regexp.Compile(check.Value); err != nil {
+			return fmt.Errorf("body_regex 断言正则无效: %w", err)
+		}
+	case models.CheckJSONPath:
+		if check.Path == "" {
+			return errors.New("json_path 断言需要 path")
+		}
+	case models.CheckMaxLatency:
+		if v, err := strconv.ParseFloat(check.Value, 64); err != nil || v <= 0 {
+			return fmt.Errorf("max_latency 断言的 value 必须是正数（毫秒）: %q", check.Value)
+		}
+	default:
+		return fmt.Errorf("不支持的断言类型: %q", check.Type)
+	}
+	return nil
+}
+
+// variableNamePattern 与 Runner 中 ${name} 的变量名规则一致
+var variableNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// validateStepExtractor 校验变量提取器
+func validateStepExtractor(ex models.StepExtractor) error {
+	if !variableNamePattern.MatchString(ex.Variable) {
+		return fmt.Errorf("提取器变量名无效: %q", ex.Variable)
+	}
+	if ex.Expression == "" {
+		return fmt.Errorf("提取器 %s 缺少 expression", ex.Variable)
+	}
+	switch ex.Type {
+	case models.ExtractJSONPath, models.ExtractHeader:
+	case models.ExtractRegex:
+		if _, err := regexp.Compile(ex.Expression); err != nil {
+			return fmt.Errorf("提取器 %s 正则无效: %w", ex.Variable, err)
+		}
+	default:
+		return fmt.Errorf("不支持的提取器类型: %q", ex.Type)
+	}
+	return nil
+}
+
+// GetCheckResults 查询任务最近一次结果的断言统计 ?test_id=xxx
+func GetCheckResults(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	testID, err := strconv.Atoi(c.Query("test_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 test_id"})
+		return
+	}
+	task, err := models.GetLoadTestByID(testID)
+	if err != nil || !canAccessTask(claims, task) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+
+	results, err := models.GetTestResultsByTestID(testID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+	if len(results) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "暂无测试结果"})
+		return
+	}
+	latest := results[len(results)-1]
+	checks, err := models.GetCheckResults(latest.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result_id": latest.ID, "check_pass_rate": latest.CheckPassRate, "checks": checks})
+}
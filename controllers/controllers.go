@@ -2,11 +2,11 @@
 package controllers
 
 import (
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
+	"loadtest_project/config"
 	"loadtest_project/models"
 	"loadtest_project/services"
 	"loadtest_project/utils"
@@ -17,8 +17,12 @@ import (
 	"time"
 )
 
-// Register 用户注册接口，角色强制为普通用户
+// Register 用户注册接口，角色强制为普通用户；启用 LDAP 时账号由目录管理，不开放注册
 func Register(c *gin.Context) {
+	if services.LDAP != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "已启用 LDAP 登录，请使用目录账号"})
+		return
+	}
 	var user models.User
 	if err := c.ShouldBindJSON(&user); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
@@ -41,13 +45,17 @@ func Register(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "注册成功"})
 }
 
-// Login 用户登录接口，返回 JWT 和角色
+// Login 用户登录接口，返回 JWT、refresh token 和角色；配置了 LDAP 时通过目录认证
 func Login(c *gin.Context) {
 	var req models.User
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
 		return
 	}
+	if useLDAP(req.Username) {
+		ldapLogin(c, req.Username, req.Password)
+		return
+	}
 	var user models.User
 	err := models.DB.QueryRow(
 		"SELECT id, username, password, role FROM users WHERE username = ?", req.Username,
@@ -60,29 +68,14 @@ func Login(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "密码错误"})
 		return
 	}
-	// 生成包含角色的 Token
-	token, err := utils.GenerateToken(user.ID, user.Role)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Token生成失败"})
-		return
-	}
-	// 返回 token 和 role
-	c.JSON(http.StatusOK, gin.H{
-		"token": token,
-		"role":  user.Role,
-	})
+	// 签发包含角色的短期 Token 与 refresh token
+	startSession(c, user.ID, user.Role)
 }
 
 // AdminOnlyMiddleware 验证仅管理员可访问
 func AdminOnlyMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		// 支持“Bearer <token>”或直接"<token>"
-		token := authHeader
-		if strings.HasPrefix(authHeader, "Bearer ") {
-			token = strings.TrimPrefix(authHeader, "Bearer ")
-		}
-		claims, err := utils.ParseToken(token)
+		claims, err := tokenClaims(c)
 		if err != nil || claims.Role != "admin" {
 			c.JSON(http.StatusForbidden, gin.H{"error": "仅管理员可访问"})
 			c.Abort()
@@ -92,9 +85,15 @@ func AdminOnlyMiddleware() gin.HandlerFunc {
 	}
 }
 
+// canAccessTask 任务所属项目的成员（viewer 及以上）或系统管理员可查看任务数据
+func canAccessTask(claims *utils.Claims, task models.LoadTest) bool {
+	return hasProjectRole(claims, task.ProjectID, models.RoleViewer)
+}
+
 type PendingTaskItem struct {
 	ID        int       `json:"id"`
 	Username  string    `json:"username"` // 新增：用户名字段
+	ProjectID int       `json:"project_id"`
 	NumUsers  int       `json:"num_users"`
 	RampUp    int       `json:"ramp_up"`
 	TargetURL string    `json:"target_url"`
@@ -103,14 +102,21 @@ type PendingTaskItem struct {
 	Status    string    `json:"status"`
 }
 
-// GetTasksByStatus 根据 query 参数 ?status=xxx 拉任务
+// GetTasksByStatus 根据 query 参数 ?status=xxx 拉任务，只返回当前用户可审批（approver 及以上）的项目中的任务
 func GetTasksByStatus(c *gin.Context) {
-	// 1. 校验管理员身份
-	authHeader := c.GetHeader("Authorization")
-	tok := strings.TrimPrefix(authHeader, "Bearer ")
-	claims, err := utils.ParseToken(tok)
-	if err != nil || claims.Role != "admin" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "仅管理员可访问"})
+	// 1. 校验身份，确定可审批的项目
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	projectIDs, all, err := accessibleProjects(claims, models.RoleApprover)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+	if !all && len(projectIDs) == 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "仅审批人或管理员可访问"})
 		return
 	}
 
@@ -119,32 +125,39 @@ func GetTasksByStatus(c *gin.Context) {
 	if status == "" {
 		status = "pending"
 	}
-	valid := map[string]bool{"pending": true, "approved": true, "rejected": true, "all": true}
+	valid := map[string]bool{"pending": true, "approved": true, "rejected": true, "aborted": true, "timeout": true, "all": true}
 	if !valid[status] {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的 status 参数"})
 		return
 	}
 
-	// 3. 构造 SQL
-	var rows *sql.Rows
-	if status == "all" {
-		rows, err = models.DB.Query(`
-            SELECT lt.id, u.username, lt.num_users, lt.ramp_up,
-                   lt.target_url, lt.start_time, lt.end_time, lt.status
-              FROM load_tests lt
-              JOIN users u ON lt.user_id = u.id
-          ORDER BY lt.start_time ASC
-        `)
-	} else {
-		rows, err = models.DB.Query(`
-            SELECT lt.id, u.username, lt.num_users, lt.ramp_up,
-                   lt.target_url, lt.start_time, lt.end_time, lt.status
-              FROM load_tests lt
-              JOIN users u ON lt.user_id = u.id
-             WHERE lt.status = ?
-          ORDER BY lt.start_time ASC
-        `, status)
+	// 3. 构造 SQL：按状态与可审批的项目过滤
+	var (
+		conds []string
+		args  []interface{}
+	)
+	if status != "all" {
+		conds = append(conds, "lt.status = ?")
+		args = append(args, status)
+	}
+	if !all {
+		conds = append(conds, "lt.project_id IN ("+inPlaceholders(len(projectIDs))+")")
+		for _, id := range projectIDs {
+			args = append(args, id)
+		}
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = "WHERE " + strings.Join(conds, " AND ")
 	}
+	rows, err := models.DB.Query(`
+        SELECT lt.id, u.username, lt.project_id, lt.num_users, lt.ramp_up,
+               lt.target_url, lt.start_time, lt.end_time, lt.status
+          FROM load_tests lt
+          JOIN users u ON lt.user_id = u.id
+        `+where+`
+      ORDER BY lt.start_time ASC
+    `, args...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
 		return
@@ -156,7 +169,7 @@ func GetTasksByStatus(c *gin.Context) {
 	for rows.Next() {
 		var t PendingTaskItem
 		if err := rows.Scan(
-			&t.ID, &t.Username, &t.NumUsers, &t.RampUp,
+			&t.ID, &t.Username, &t.ProjectID, &t.NumUsers, &t.RampUp,
 			&t.TargetURL, &t.StartTime, &t.EndTime, &t.Status,
 		); err != nil {
 			continue
@@ -168,29 +181,40 @@ func GetTasksByStatus(c *gin.Context) {
 
 // SubmitRequest 接收前端 JSON，自动把 start_time/end_time 解析成 time.Time
 type SubmitRequest struct {
-	NumUsers  int       `json:"num_users"`
-	RampUp    int       `json:"ramp_up"`
-	TargetURL string    `json:"target_url"`
-	StartTime time.Time `json:"start_time"`
-	EndTime   time.Time `json:"end_time"`
+	// ProjectID 任务所属项目，需 tester 及以上角色；为 0 时归入提交者的个人项目
+	ProjectID  int                      `json:"project_id"`
+	NumUsers   int                      `json:"num_users"`
+	RampUp     int                      `json:"ramp_up"`
+	TargetURL  string                   `json:"target_url"`
+	StartTime  time.Time                `json:"start_time"`
+	EndTime    time.Time                `json:"end_time"`
+	TestType   string                   `json:"test_type"`
+	Capacity   *models.CapacityConfig   `json:"capacity"`
+	Thresholds []models.Threshold       `json:"thresholds"`
+	Abort      *models.AbortCondition   `json:"abort"`
+	Target     *models.TargetOptions    `json:"target"`
+	Steps      []models.ScenarioStep    `json:"steps"`
+	Dataset    *models.TestDataset      `json:"dataset"`
+	GRPC       *models.GRPCOptions      `json:"grpc"`
+	Transport  *models.TransportOptions `json:"transport"`
+	// Distributed 配置后由 worker agent 承担虚拟用户
+	Distributed *models.DistributedOptions `json:"distributed"`
 }
 
 // UnmarshalJSON 自定义反序列化，兼容多种输入格式
 func (s *SubmitRequest) UnmarshalJSON(data []byte) error {
-	// 先用一个中间结构拿到 raw 值
+	// 先用一个中间结构拿到 raw 值；其余字段经别名类型按原样解析，
+	// 外层同名 tag 优先，因此时间字段落在 StartRaw/EndRaw 上
+	type plain SubmitRequest
 	var raw struct {
-		NumUsers  int         `json:"num_users"`
-		RampUp    int         `json:"ramp_up"`
-		TargetURL string      `json:"target_url"`
-		StartRaw  interface{} `json:"start_time"`
-		EndRaw    interface{} `json:"end_time"`
+		*plain
+		StartRaw interface{} `json:"start_time"`
+		EndRaw   interface{} `json:"end_time"`
 	}
+	raw.plain = (*plain)(s)
 	if err := json.Unmarshal(data, &raw); err != nil {
 		return err
 	}
-	s.NumUsers = raw.NumUsers
-	s.RampUp = raw.RampUp
-	s.TargetURL = raw.TargetURL
 
 	// 统一解析函数：尝试多种常见格式
 	parseTime := func(v interface{}) (time.Time, error) {
@@ -223,9 +247,7 @@ func (s *SubmitRequest) UnmarshalJSON(data []byte) error {
 // SubmitLoadTest 用户提交压测任务
 func SubmitLoadTest(c *gin.Context) {
 	// —— 1. 验证 Token，取出用户 ID ——
-	authHeader := c.GetHeader("Authorization")
-	tok := strings.TrimPrefix(authHeader, "Bearer ")
-	claims, err := utils.ParseToken(tok)
+	claims, err := tokenClaims(c)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的 Token"})
 		return
@@ -240,72 +262,105 @@ func SubmitLoadTest(c *gin.Context) {
 		return
 	}
 
+	// 确定所属项目并校验提交权限
+	if req.ProjectID == 0 {
+		if req.ProjectID, err = models.EnsurePersonalProject(userID); err != nil {
+			log.Println("创建个人项目失败:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "任务提交失败", "detail": err.Error()})
+			return
+		}
+	}
+	if !hasProjectRole(claims, req.ProjectID, models.RoleTester) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "没有在该项目中提交任务的权限"})
+		return
+	}
+
+	// 校验附加配置（容量探测、SLO、中止条件、目标选项、场景等）
+	if label, err := validateSubmitOptions(userID, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": label, "detail": err.Error()})
+		return
+	}
+
 	// —— 3. 构造 LoadTest 并保存 ——
 	task := models.LoadTest{
 		UserID:    userID,
+		ProjectID: req.ProjectID,
 		NumUsers:  req.NumUsers,
 		RampUp:    req.RampUp,
 		TargetURL: req.TargetURL,
 		StartTime: req.StartTime,
 		EndTime:   req.EndTime,
 		Status:    "pending",
+		TestType:  req.TestType,
 	}
 	if err := models.CreateLoadTest(&task); err != nil {
 		log.Println("任务提交失败:", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "任务提交失败", "detail": err.Error()})
 		return
 	}
+	if err := saveSubmitOptions(task.ID, &req); err != nil {
+		log.Println("任务配置保存失败:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "任务提交失败", "detail": err.Error()})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "任务提交成功，等待审批"})
+	c.JSON(http.StatusOK, gin.H{"message": "任务提交成功，等待审批", "id": task.ID})
 }
 
-// ApproveLoadTest 管理员审批并启动压测
-func ApproveLoadTest(c *gin.Context) {
-	AdminOnlyMiddleware()(c)
-	if c.IsAborted() {
-		return
+// approvableTask 解析表单 id 并校验当前用户是任务所属项目的审批人（approver 及以上）且不是提交者
+func approvableTask(c *gin.Context) (models.LoadTest, bool) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return models.LoadTest{}, false
 	}
-	idStr := c.PostForm("id")
-	id, err := strconv.Atoi(idStr)
+	id, err := strconv.Atoi(c.PostForm("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+		return models.LoadTest{}, false
+	}
+	task, err := models.GetLoadTestByID(id)
+	if err != nil || !canAccessTask(claims, task) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return task, false
+	}
+	if !hasProjectRole(claims, task.ProjectID, models.RoleApprover) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "仅项目审批人或管理员可审批"})
+		return task, false
+	}
+	// 提交者不能审批自己的任务（系统管理员除外，与引入项目前一致）
+	if task.UserID == claims.UserID && !isSystemAdmin(claims) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "不能审批自己提交的任务"})
+		return task, false
+	}
+	return task, true
+}
+
+// ApproveLoadTest 项目审批人审批并启动压测
+func ApproveLoadTest(c *gin.Context) {
+	task, ok := approvableTask(c)
+	if !ok {
 		return
 	}
 	// 更新状态
-	models.DB.Exec("UPDATE load_tests SET status='approved' WHERE id=?", id)
-	// 查询详情
-	var task models.LoadTest
-	models.DB.QueryRow("SELECT id, target_url, num_users FROM load_tests WHERE id=?", id).
-		Scan(&task.ID, &task.TargetURL, &task.NumUsers)
+	if err := models.UpdateLoadTestStatus(task.ID, "approved"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "审批失败"})
+		return
+	}
+	task.Status = "approved"
 	// 异步启动压测
 	go services.StartLoadTest(task)
 	c.JSON(http.StatusOK, gin.H{"message": "任务审批通过，压测已启动"})
 }
 
-// RejectLoadTest
+// RejectLoadTest 项目审批人拒绝任务
 func RejectLoadTest(c *gin.Context) {
-	// 服用中间件逻辑，剥离 Bearer 前缀
-	authHeader := c.GetHeader("Authorization")
-	token := authHeader
-	if strings.HasPrefix(authHeader, "Bearer ") {
-		token = strings.TrimPrefix(authHeader, "Bearer ")
-	}
-	claims, err := utils.ParseToken(token)
-	if err != nil || claims.Role != "admin" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "仅管理员可访问"})
-		c.Abort()
-		return
-	}
-
-	// 解析表单 id
-	idStr := c.PostForm("id")
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+	task, ok := approvableTask(c)
+	if !ok {
 		return
 	}
 	// 更新状态为 rejected
-	if err := models.UpdateLoadTestStatus(id, "rejected"); err != nil {
+	if err := models.UpdateLoadTestStatus(task.ID, "rejected"); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "拒绝任务失败"})
 		return
 	}
@@ -314,26 +369,53 @@ func RejectLoadTest(c *gin.Context) {
 
 // 获取当前用户所有任务（按 start_time 升序）
 func GetUserTasks(c *gin.Context) {
-	// 从 Header 拿到 token，去掉 Bearer 前缀
-	authHeader := c.GetHeader("Authorization")
-	tok := authHeader
-	if strings.HasPrefix(authHeader, "Bearer ") {
-		tok = strings.TrimPrefix(authHeader, "Bearer ")
-	}
-	claims, err := utils.ParseToken(tok)
+	// 从 Header 拿到 token 或 API 令牌
+	claims, err := tokenClaims(c)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
 		return
 	}
-	userID := claims.UserID
 
-	// 查询该用户的所有任务
+	// 可查看（viewer 及以上）的项目，?project_id=xxx 时只查该项目
+	var projectIDs []int
+	if s := c.Query("project_id"); s != "" {
+		id, err := strconv.Atoi(s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 project_id"})
+			return
+		}
+		if !hasProjectRole(claims, id, models.RoleViewer) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "项目不存在"})
+			return
+		}
+		projectIDs = []int{id}
+	} else {
+		// 系统管理员同样只列出自己参与的项目，全部任务在审批页查看
+		projects, err := models.ListUserProjects(claims.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+			return
+		}
+		for _, p := range projects {
+			projectIDs = append(projectIDs, p.ID)
+		}
+	}
+	if len(projectIDs) == 0 {
+		c.JSON(http.StatusOK, gin.H{"tasks": []map[string]interface{}{}})
+		return
+	}
+	args := make([]interface{}, len(projectIDs))
+	for i, id := range projectIDs {
+		args[i] = id
+	}
+
+	// 查询这些项目中的任务
 	rows, err := models.DB.Query(`
-        SELECT id, num_users, ramp_up, target_url, start_time, end_time, status
+        SELECT id, user_id, project_id, num_users, ramp_up, target_url, start_time, end_time, status, test_type, abort_reason, failure_reason
           FROM load_tests
-         WHERE user_id = ?
+         WHERE project_id IN (`+inPlaceholders(len(projectIDs))+`)
       ORDER BY start_time ASC
-    `, userID)
+    `, args...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
 		return
@@ -344,41 +426,78 @@ func GetUserTasks(c *gin.Context) {
 	for rows.Next() {
 		var t models.LoadTest
 		if err := rows.Scan(
-			&t.ID, &t.NumUsers, &t.RampUp,
-			&t.TargetURL, &t.StartTime, &t.EndTime, &t.Status,
+			&t.ID, &t.UserID, &t.ProjectID, &t.NumUsers, &t.RampUp,
+			&t.TargetURL, &t.StartTime, &t.EndTime, &t.Status, &t.TestType, &t.AbortReason, &t.FailureReason,
 		); err != nil {
 			continue
 		}
-		// 转成 JSON 友好结构
+		// 转成 JSON 友好结构，附带最近一次 SLO 结论（未配置阈值时为 null）
+		var verdict interface{}
+		if v, err := models.GetLatestVerdict(t.ID); err == nil {
+			verdict = v
+		}
 		tasks = append(tasks, map[string]interface{}{
-			"id":         t.ID,
-			"num_users":  t.NumUsers,
-			"ramp_up":    t.RampUp,
-			"target_url": t.TargetURL,
-			"start_time": t.StartTime,
-			"end_time":   t.EndTime,
-			"status":     t.Status,
+			"id":             t.ID,
+			"user_id":        t.UserID,
+			"project_id":     t.ProjectID,
+			"num_users":      t.NumUsers,
+			"ramp_up":        t.RampUp,
+			"target_url":     t.TargetURL,
+			"start_time":     t.StartTime,
+			"end_time":       t.EndTime,
+			"status":         t.Status,
+			"test_type":      t.TestType,
+			"abort_reason":   t.AbortReason,
+			"failure_reason": t.FailureReason,
+			"verdict":        verdict,
 		})
 	}
 	c.JSON(http.StatusOK, gin.H{"tasks": tasks})
 }
 
-// SaveTestResult 保存压测结果
+// SaveTestResult 保存 runner 上传的压测结果。需在 X-Run-Token 头携带运行配置中的签名令牌，
+// 结果归属令牌绑定的任务，每次运行只能有一条结果（runner 已自行保存的运行不再接受上传）
 func SaveTestResult(c *gin.Context) {
+	runToken, err := utils.VerifyRunToken(config.RunTokenKey, c.GetHeader("X-Run-Token"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的运行令牌", "detail": err.Error()})
+		return
+	}
+	run, err := models.GetRunByPrefix(runToken.RunPrefix)
+	if err != nil || run.TestID != runToken.TestID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "运行不存在"})
+		return
+	}
 	var result models.TestResult
 	if err := c.ShouldBindJSON(&result); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
 		return
 	}
-	if err := models.CreateTestResult(&result); err != nil {
+	if result.TestID != 0 && result.TestID != runToken.TestID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "结果与运行令牌的任务不一致"})
+		return
+	}
+	result.TestID = runToken.TestID
+	saved, err := models.SaveRunResult(run.ID, &result)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存测试结果失败"})
 		return
 	}
+	if !saved {
+		c.JSON(http.StatusConflict, gin.H{"error": "该运行已有结果"})
+		return
+	}
+	services.RecordVerdict(result)
 	c.JSON(http.StatusOK, gin.H{"message": "测试结果保存成功"})
 }
 
-// DownloadReport 下载报告
+// DownloadReport 下载报告，需任务所属项目的查看权限
 func DownloadReport(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
 	testIDStr := c.Query("test_id")
 	format := c.Query("format")
 	testID, err := strconv.Atoi(testIDStr)
@@ -386,6 +505,11 @@ func DownloadReport(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 test_id"})
 		return
 	}
+	task, err := models.GetLoadTestByID(testID)
+	if err != nil || !canAccessTask(claims, task) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
 	results, err := models.GetTestResultsByTestID(testID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询测试结果失败"})
@@ -402,6 +526,10 @@ func DownloadReport(c *gin.Context) {
 		}
 		records = append(records, record)
 	}
+	// 附加 SLO 结论
+	if verdict, err := models.GetLatestVerdict(testID); err == nil {
+		records = append(records, verdictRecords(verdict)...)
+	}
 	if format == "csv" {
 		filename := "report_" + testIDStr + ".csv"
 		if err := utils.GenerateCSV(records, filename); err != nil {
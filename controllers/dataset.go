@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"loadtest_project/models"
+)
+
+// datasetDir 上传的数据集存放目录
+const datasetDir = "datasets"
+
+// maxDatasetSize 数据集文件大小上限
+const maxDatasetSize = 50 << 20
+
+// UploadDataset 上传 CSV 数据集，首行为列名（表单字段 file、name）
+func UploadDataset(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	fh, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少文件"})
+		return
+	}
+	if fh.Size > maxDatasetSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "文件过大"})
+		return
+	}
+	name := c.PostForm("name")
+	if name == "" {
+		name = fh.Filename
+	}
+
+	_ = os.MkdirAll(datasetDir, 0755)
+	path := filepath.Join(datasetDir, fmt.Sprintf("dataset_%d_%d.csv", claims.UserID, time.Now().UnixNano()))
+	if err := c.SaveUploadedFile(fh, path); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存文件失败"})
+		return
+	}
+	columns, rowCount, err := inspectCSV(path)
+	if err != nil {
+		os.Remove(path)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "CSV 格式错误", "detail": err.Error()})
+		return
+	}
+
+	ds := models.Dataset{UserID: claims.UserID, Name: name, FilePath: path, Columns: columns, RowCount: rowCount}
+	if err := models.CreateDataset(&ds); err != nil {
+		os.Remove(path)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存数据集失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"dataset": ds})
+}
+
+// inspectCSV 读取列名并统计数据行数，同时校验每行列数一致
+func inspectCSV(path string) ([]string, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, 0, fmt.Errorf("读取表头失败: %w", err)
+	}
+	for i, col := range header {
+		header[i] = strings.TrimSpace(col)
+		if header[i] == "" {
+			return nil, 0, fmt.Errorf("第 %d 列列名为空", i+1)
+		}
+	}
+	rows := 0
+	for {
+		_, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		rows++
+	}
+	if rows == 0 {
+		return nil, 0, errors.New("没有数据行")
+	}
+	return header, rows, nil
+}
+
+// ListDatasets 列出当前用户的数据集
+func ListDatasets(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	list, err := models.ListDatasets(claims.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"datasets": list})
+}
+
+// validateTestDataset 校验数据集归属与分配方式
+func validateTestDataset(userID int, td *models.TestDataset) error {
+	if td.Mode == "" {
+		td.Mode = models.DatasetSequential
+	}
+	switch td.Mode {
+	case models.DatasetSequential, models.DatasetRandom, models.DatasetUnique:
+	default:
+		return errors.New("不支持的 mode")
+	}
+	ds, err := models.GetDatasetByID(td.DatasetID)
+	if err != nil || ds.UserID != userID {
+		return errors.New("数据集不存在")
+	}
+	return nil
+}
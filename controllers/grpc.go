@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"loadtest_project/models"
+	"loadtest_project/services"
+)
+
+// protoDir 编译后的 .proto 描述存放目录
+const protoDir = "protos"
+
+// maxProtoSize 单次上传的 .proto 文件总大小上限
+const maxProtoSize = 5 << 20
+
+// grpcMethodPattern gRPC 方法全名 /package.Service/Method
+var grpcMethodPattern = regexp.MustCompile(`^/[A-Za-z_][\w.]*/[A-Za-z_]\w*$`)
+
+// UploadProto 上传一个或多个 .proto 文件（表单字段 files、name），编译后保存描述
+func UploadProto(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	form, err := c.MultipartForm()
+	if err != nil || len(form.File["files"]) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少文件"})
+		return
+	}
+
+	files := make(map[string]string)
+	var total int64
+	for _, fh := range form.File["files"] {
+		total += fh.Size
+		if total > maxProtoSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "文件过大"})
+			return
+		}
+		// 以文件名作为 import 路径，多个文件可相互引用
+		name := filepath.Base(fh.Filename)
+		if !strings.HasSuffix(name, ".proto") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "仅支持 .proto 文件", "detail": name})
+			return
+		}
+		f, err := fh.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "读取文件失败"})
+			return
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "读取文件失败"})
+			return
+		}
+		files[name] = string(data)
+	}
+
+	descriptorSet, methods, err := services.CompileProto(files)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ".proto 编译失败", "detail": err.Error()})
+		return
+	}
+	name := c.PostForm("name")
+	if name == "" {
+		name = form.File["files"][0].Filename
+	}
+
+	_ = os.MkdirAll(protoDir, 0755)
+	path := filepath.Join(protoDir, fmt.Sprintf("proto_%d_%d.pb", claims.UserID, time.Now().UnixNano()))
+	if err := os.WriteFile(path, descriptorSet, 0644); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存文件失败"})
+		return
+	}
+	desc := models.ProtoDescriptor{UserID: claims.UserID, Name: name, FilePath: path, Methods: methods}
+	if err := models.CreateProtoDescriptor(&desc); err != nil {
+		os.Remove(path)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存描述失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"proto": desc})
+}
+
+// ListProtos 列出当前用户上传的 .proto 描述及其方法
+func ListProtos(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	list, err := models.ListProtoDescriptors(claims.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"protos": list})
+}
+
+// validateGRPCStep 校验 gRPC 步骤，Body 为 JSON 请求消息（客户端流可为 JSON 数组）
+func validateGRPCStep(s *models.ScenarioStep) error {
+	if !strings.HasPrefix(s.Path, "/") {
+		s.Path = "/" + s.Path
+	}
+	if !grpcMethodPattern.MatchString(s.Path) {
+		return fmt.Errorf("grpc 步骤的 path 应为 /package.Service/Method: %q", s.Path)
+	}
+	for _, check := range s.Checks {
+		if err := validateStepCheck(check); err != nil {
+			return err
+		}
+	}
+	for _, ex := range s.Extractors {
+		if err := validateStepExtractor(ex); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateGRPCOptions 校验描述归属，并确认 gRPC 步骤调用的方法都在描述中定义
+func validateGRPCOptions(userID int, opts *models.GRPCOptions, steps []models.ScenarioStep) error {
+	if opts.DescriptorID == 0 {
+		// 使用服务端反射，运行时才能确认方法是否存在
+		return nil
+	}
+	desc, err := models.GetProtoDescriptorByID(opts.DescriptorID)
+	if err != nil || desc.UserID != userID {
+		return errors.New("proto 描述不存在")
+	}
+	defined := make(map[string]bool, len(desc.Methods))
+	for _, m := range desc.Methods {
+		defined[m.Name] = true
+	}
+	for _, s := range steps {
+		if s.Type == models.StepGRPC && !defined[s.Path] {
+			return fmt.Errorf("方法 %s 未在 %s 中定义", s.Path, desc.Name)
+		}
+	}
+	return nil
+}
+
+// GetGRPCResults 查询任务最近一次结果中各 gRPC 方法的延迟与状态码分布 ?test_id=xxx
+func GetGRPCResults(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	testID, err := strconv.Atoi(c.Query("test_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 test_id"})
+		return
+	}
+	task, err := models.GetLoadTestByID(testID)
+	if err != nil || !canAccessTask(claims, task) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+
+	results, err := models.GetTestResultsByTestID(testID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+	if len(results) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "暂无测试结果"})
+		return
+	}
+	latest := results[len(results)-1]
+	methods, err := models.GetGRPCMethodResults(latest.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result_id": latest.ID, "methods": methods})
+}
@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"loadtest_project/models"
+	"loadtest_project/utils"
+)
+
+// histogramSummary 由直方图重新计算的统计值，buckets 为真时附带原始分桶
+func histogramSummary(h *utils.Histogram, buckets bool) gin.H {
+	summary := gin.H{
+		"count": h.Total,
+		"mean":  h.Mean(),
+		"min":   h.Min,
+		"max":   h.Max,
+		"p50":   h.Percentile(50),
+		"p90":   h.Percentile(90),
+		"p95":   h.Percentile(95),
+		"p99":   h.Percentile(99),
+		"p999":  h.Percentile(99.9),
+	}
+	if buckets {
+		summary["histogram"] = h
+	}
+	return summary
+}
+
+// GetHistograms 查询某次结果各端点的延迟直方图 ?test_id=xxx[&result_id=yyy][&buckets=1]，
+// 未指定 result_id 时取最近一次结果
+func GetHistograms(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	testID, err := strconv.Atoi(c.Query("test_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 test_id"})
+		return
+	}
+	task, err := models.GetLoadTestByID(testID)
+	if err != nil || !canAccessTask(claims, task) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+
+	resultID, _ := strconv.Atoi(c.Query("result_id"))
+	if resultID == 0 {
+		results, err := models.GetTestResultsByTestID(testID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+			return
+		}
+		if len(results) == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "暂无测试结果"})
+			return
+		}
+		resultID = results[len(results)-1].ID
+	} else if owner, err := models.GetResultTestID(resultID); err != nil || owner != testID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "结果不存在"})
+		return
+	}
+
+	list, err := models.GetLatencyHistograms(resultID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+	buckets := c.Query("buckets") == "1"
+	endpoints := make([]gin.H, 0, len(list))
+	for _, h := range list {
+		endpoints = append(endpoints, gin.H{"type": h.Type, "name": h.Name, "stats": histogramSummary(h.Histogram, buckets)})
+	}
+	c.JSON(http.StatusOK, gin.H{"result_id": resultID, "endpoints": endpoints})
+}
+
+// MergeHistograms 合并多次结果的直方图并重新计算百分位 ?result_ids=1,2,3，
+// 同时返回各次结果的整体统计便于对比
+func MergeHistograms(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	var resultIDs []int
+	for _, s := range strings.Split(c.Query("result_ids"), ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 result_ids"})
+			return
+		}
+		resultIDs = append(resultIDs, id)
+	}
+
+	type endpointKey struct{ typ, name string }
+	var (
+		order  []endpointKey
+		merged = make(map[endpointKey]*utils.Histogram)
+		runs   = make([]gin.H, 0, len(resultIDs))
+	)
+	for _, resultID := range resultIDs {
+		testID, err := models.GetResultTestID(resultID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "结果不存在", "result_id": resultID})
+			return
+		}
+		task, err := models.GetLoadTestByID(testID)
+		if err != nil || !canAccessTask(claims, task) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "结果不存在", "result_id": resultID})
+			return
+		}
+		list, err := models.GetLatencyHistograms(resultID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+			return
+		}
+		for _, h := range list {
+			if h.Name == models.HistogramAggregated && h.Type == "" {
+				runs = append(runs, gin.H{"result_id": resultID, "test_id": testID, "stats": histogramSummary(h.Histogram, false)})
+			}
+			key := endpointKey{h.Type, h.Name}
+			if merged[key] == nil {
+				merged[key] = utils.NewHistogram()
+				order = append(order, key)
+			}
+			merged[key].Merge(h.Histogram)
+		}
+	}
+
+	endpoints := make([]gin.H, 0, len(order))
+	for _, key := range order {
+		endpoints = append(endpoints, gin.H{"type": key.typ, "name": key.name, "stats": histogramSummary(merged[key], false)})
+	}
+	c.JSON(http.StatusOK, gin.H{"runs": runs, "merged": endpoints})
+}
@@ -0,0 +1,225 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"loadtest_project/models"
+	"loadtest_project/services"
+	"loadtest_project/utils"
+)
+
+// maxImportSize 导入文件大小上限
+const maxImportSize = 20 << 20
+
+// importTarget 解析可选的 ?test_id=xxx。提供时任务必须属于当前用户且仍待审批；
+// 未提供时返回 nil，导入结果仅作预览
+func importTarget(c *gin.Context, claims *utils.Claims) (*models.LoadTest, bool) {
+	idStr := c.Query("test_id")
+	if idStr == "" {
+		return nil, true
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 test_id"})
+		return nil, false
+	}
+	task, err := models.GetLoadTestByID(id)
+	if err != nil || task.UserID != claims.UserID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return nil, false
+	}
+	if task.Status != "pending" {
+		c.JSON(http.StatusConflict, gin.H{"error": "仅待审批的任务可以修改场景"})
+		return nil, false
+	}
+	return &task, true
+}
+
+// respondImportedSteps 校验导入的步骤；指定了任务时替换其场景步骤，否则直接返回预览
+func respondImportedSteps(c *gin.Context, task *models.LoadTest, steps []models.ScenarioStep) {
+	if err := validateScenarioSteps(steps); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "场景步骤错误", "detail": err.Error()})
+		return
+	}
+	if task == nil {
+		c.JSON(http.StatusOK, gin.H{"steps": steps})
+		return
+	}
+	if err := models.ReplaceScenarioSteps(task.ID, steps); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存场景失败", "detail": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "导入成功", "test_id": task.ID, "steps": steps})
+}
+
+// readImportFile 读取表单中的 file 字段
+func readImportFile(c *gin.Context) ([]byte, bool) {
+	fh, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少文件"})
+		return nil, false
+	}
+	if fh.Size > maxImportSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "文件过大"})
+		return nil, false
+	}
+	f, err := fh.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "读取文件失败"})
+		return nil, false
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "读取文件失败"})
+		return nil, false
+	}
+	return data, true
+}
+
+// ImportHAR 将 HAR 录制转换为场景（表单字段 file；可选 rules 为 JSON 过滤规则）
+func ImportHAR(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	task, ok := importTarget(c, claims)
+	if !ok {
+		return
+	}
+	data, ok := readImportFile(c)
+	if !ok {
+		return
+	}
+
+	rules := services.DefaultHARFilterRules()
+	if raw := c.PostForm("rules"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &rules); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "rules 格式错误", "detail": err.Error()})
+			return
+		}
+	}
+	baseURL := ""
+	if task != nil {
+		baseURL = task.TargetURL
+	}
+	steps, err := services.ImportHAR(data, rules, baseURL)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "HAR 导入失败", "detail": err.Error()})
+		return
+	}
+	respondImportedSteps(c, task, steps)
+}
+
+// ImportOpenAPI 从 OpenAPI 3 文档生成场景（表单字段 file）。
+// 未提供 operations 时返回可选操作列表；operations 为 JSON 数组，元素为 operationId 或 "METHOD /path"
+func ImportOpenAPI(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	task, ok := importTarget(c, claims)
+	if !ok {
+		return
+	}
+	data, ok := readImportFile(c)
+	if !ok {
+		return
+	}
+	doc, err := services.ParseOpenAPI(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "OpenAPI 导入失败", "detail": err.Error()})
+		return
+	}
+
+	raw := c.PostForm("operations")
+	if raw == "" {
+		c.JSON(http.StatusOK, gin.H{"operations": doc.Operations()})
+		return
+	}
+	var selected []string
+	if err := json.Unmarshal([]byte(raw), &selected); err != nil || len(selected) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "operations 格式错误"})
+		return
+	}
+	steps, err := doc.Steps(selected)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "OpenAPI 导入失败", "detail": err.Error()})
+		return
+	}
+	respondImportedSteps(c, task, steps)
+}
+
+// ImportPostman 将 Postman v2.1 集合转换为场景（表单字段 file；可选 variables 为 JSON 对象，覆盖集合变量）
+func ImportPostman(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	task, ok := importTarget(c, claims)
+	if !ok {
+		return
+	}
+	data, ok := readImportFile(c)
+	if !ok {
+		return
+	}
+	var vars map[string]string
+	if raw := c.PostForm("variables"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &vars); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "variables 格式错误", "detail": err.Error()})
+			return
+		}
+	}
+	baseURL := ""
+	if task != nil {
+		baseURL = task.TargetURL
+	}
+	steps, err := services.ImportPostman(data, vars, baseURL)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Postman 导入失败", "detail": err.Error()})
+		return
+	}
+	respondImportedSteps(c, task, steps)
+}
+
+// ImportCurlRequest curl 导入请求体，commands 可包含多条命令
+type ImportCurlRequest struct {
+	Commands string `json:"commands"`
+}
+
+// ImportCurl 将 curl 命令转换为场景
+func ImportCurl(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	task, ok := importTarget(c, claims)
+	if !ok {
+		return
+	}
+	var req ImportCurlRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Commands == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+	baseURL := ""
+	if task != nil {
+		baseURL = task.TargetURL
+	}
+	steps, err := services.ImportCurl(req.Commands, baseURL)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "curl 导入失败", "detail": err.Error()})
+		return
+	}
+	respondImportedSteps(c, task, steps)
+}
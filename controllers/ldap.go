@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"loadtest_project/config"
+	"loadtest_project/models"
+	"loadtest_project/services"
+)
+
+// useLDAP 配置了 LDAP 时，除保留的本地账号（引导管理员，目录不可用时仍能登录）外都通过目录认证
+func useLDAP(username string) bool {
+	return services.LDAP != nil && !config.LDAP.IsLocalUser(username)
+}
+
+// ldapLogin 通过目录认证，按组映射角色；首次登录关联同名且没有本地密码的账号，没有时自动创建用户
+func ldapLogin(c *gin.Context, username, password string) {
+	identity, err := services.LDAP.Authenticate(username, password)
+	if errors.Is(err, services.ErrLDAPInvalidCredentials) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "用户名或密码错误"})
+		return
+	}
+	if err != nil {
+		log.Println("LDAP:", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "目录服务不可用"})
+		return
+	}
+	userID, err := models.ProvisionExternalUser("ldap:"+config.LDAP.URL, strings.ToLower(identity.DN), username, identity.Role, !config.LDAP.IsLocalUser(username))
+	if err != nil {
+		log.Println("LDAP: 创建用户失败:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建用户失败"})
+		return
+	}
+	startSession(c, userID, identity.Role)
+}
@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"testing"
+
+	"loadtest_project/config"
+	"loadtest_project/services"
+)
+
+func TestUseLDAP(t *testing.T) {
+	origCfg, origAuth := config.LDAP, services.LDAP
+	t.Cleanup(func() { config.LDAP, services.LDAP = origCfg, origAuth })
+
+	config.LDAP, services.LDAP = nil, nil
+	if useLDAP("bob") {
+		t.Fatal("未配置 LDAP 时应使用本地账号")
+	}
+
+	config.LDAP = &config.LDAPConfig{LocalUsers: map[string]bool{"admin": true}}
+	services.LDAP = services.NewLDAPAuthenticator(config.LDAP)
+	tests := []struct {
+		username string
+		want     bool
+	}{
+		{"admin", false}, // 引导管理员始终用本地密码，目录不可用时仍能登录
+		{"bob", true},
+		// MySQL 比较用户名不区分大小写，换大小写也不能让目录账号接管本地管理员
+		{"Admin", false},
+		{"ADMIN", false},
+	}
+	for _, tt := range tests {
+		if got := useLDAP(tt.username); got != tt.want {
+			t.Errorf("useLDAP(%q) = %v, want %v", tt.username, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"loadtest_project/config"
+	"loadtest_project/models"
+	"loadtest_project/services"
+)
+
+// oidcStateTTL 从跳转到 IdP 到回调之间允许的最长时间
+const oidcStateTTL = 10 * time.Minute
+
+// oidcStateCookie 保存 state 哈希的 cookie，回调时必须与 URL 中的 state 一致，
+// 防止攻击者把自己的回调地址发给受害者，使其登录到攻击者的账号（login CSRF）
+const oidcStateCookie = "oidc_state"
+
+// oidcStateHash cookie 中只保存 state 的哈希
+func oidcStateHash(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
+
+// setOIDCStateCookie 设置或清除（maxAge < 0）state cookie，只在回调路径下发送
+func setOIDCStateCookie(c *gin.Context, value string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, value, maxAge, "/api/oidc/", "", c.Request.TLS != nil, true)
+}
+
+// oidcCallbackPage 登录完成后跳转的前端页面，令牌放在 URL fragment 中，不会发送到服务器或写入日志
+const oidcCallbackPage = "/static/oidc_callback.html"
+
+func randomString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// OIDCLogin 跳转到 IdP 登录（授权码 + PKCE），state 同时写入本浏览器的 cookie
+func OIDCLogin(c *gin.Context) {
+	if services.OIDC == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "未启用单点登录"})
+		return
+	}
+	state, err := randomString(24)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成登录状态失败"})
+		return
+	}
+	nonce, err := randomString(24)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成登录状态失败"})
+		return
+	}
+	verifier, err := services.NewPKCEVerifier()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成登录状态失败"})
+		return
+	}
+	authURL, err := services.OIDC.AuthCodeURL(state, nonce, verifier)
+	if err != nil {
+		log.Println("OIDC:", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "无法连接身份提供方"})
+		return
+	}
+	if err := models.CreateLoginState(models.LoginState{
+		State:        state,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		ExpiresAt:    time.Now().Add(oidcStateTTL),
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存登录状态失败"})
+		return
+	}
+	setOIDCStateCookie(c, oidcStateHash(state), int(oidcStateTTL.Seconds()))
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// oidcFail 回到前端回调页并显示错误
+func oidcFail(c *gin.Context, msg string) {
+	c.Redirect(http.StatusFound, oidcCallbackPage+"#"+url.Values{"error": {msg}}.Encode())
+}
+
+// OIDCCallback IdP 回调：校验 state，换取并校验 ID Token，按组映射角色并自动创建用户，签发本系统的令牌
+func OIDCCallback(c *gin.Context) {
+	if services.OIDC == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "未启用单点登录"})
+		return
+	}
+	// state 必须是本浏览器发起的登录，校验通过后才消耗
+	cookie, _ := c.Cookie(oidcStateCookie)
+	setOIDCStateCookie(c, "", -1)
+	state := c.Query("state")
+	if state == "" || subtle.ConstantTimeCompare([]byte(cookie), []byte(oidcStateHash(state))) != 1 {
+		oidcFail(c, "登录状态与当前浏览器不匹配，请重新登录")
+		return
+	}
+	if e := c.Query("error"); e != "" {
+		models.ConsumeLoginState(state)
+		oidcFail(c, fmt.Sprintf("身份提供方拒绝登录: %s %s", e, c.Query("error_description")))
+		return
+	}
+	st, err := models.ConsumeLoginState(state)
+	if err != nil {
+		oidcFail(c, "登录状态无效或已过期，请重新登录")
+		return
+	}
+	identity, err := services.OIDC.Authenticate(c.Query("code"), st.CodeVerifier, st.Nonce)
+	if err != nil {
+		log.Println("OIDC:", err)
+		oidcFail(c, "单点登录失败")
+		return
+	}
+	role := config.OIDC.RoleMap.Role(identity.Groups)
+	userID, err := models.ProvisionExternalUser("oidc:"+config.OIDC.Issuer, identity.Subject, identity.Username, role, false)
+	if err != nil {
+		log.Println("OIDC: 创建用户失败:", err)
+		oidcFail(c, "创建用户失败")
+		return
+	}
+	resp, err := newSession(userID, role)
+	if err != nil {
+		oidcFail(c, "Token生成失败")
+		return
+	}
+	c.Redirect(http.StatusFound, oidcCallbackPage+"#"+url.Values{
+		"token":         {resp["token"].(string)},
+		"refresh_token": {resp["refresh_token"].(string)},
+		"role":          {role},
+	}.Encode())
+}
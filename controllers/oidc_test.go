@@ -0,0 +1,288 @@
+package controllers
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"loadtest_project/config"
+	"loadtest_project/mockoidc"
+	"loadtest_project/models"
+	"loadtest_project/models/testdb"
+	"loadtest_project/services"
+	"loadtest_project/utils"
+)
+
+// oidcStore 内存中的 login_states 与外部用户，只实现登录流程用到的语句
+type oidcStore struct {
+	states     map[string]models.LoginState
+	users      map[int64][2]string // id -> {username, role}
+	identities map[string]int64    // provider|subject -> user id
+}
+
+func (s *oidcStore) handle(query string, args []driver.Value) (testdb.Result, error) {
+	switch {
+	case strings.HasPrefix(query, "DELETE FROM login_states WHERE expires_at"):
+	case strings.HasPrefix(query, "INSERT INTO login_states"):
+		s.states[args[0].(string)] = models.LoginState{
+			State: args[0].(string), CodeVerifier: args[1].(string), Nonce: args[2].(string), ExpiresAt: args[3].(time.Time),
+		}
+	case strings.HasPrefix(query, "SELECT state, code_verifier, nonce, expires_at FROM login_states"):
+		if st, ok := s.states[args[0].(string)]; ok {
+			return testdb.Row([]string{"state", "code_verifier", "nonce", "expires_at"}, st.State, st.CodeVerifier, st.Nonce, st.ExpiresAt), nil
+		}
+	case strings.HasPrefix(query, "DELETE FROM login_states WHERE state=?"):
+		if _, ok := s.states[args[0].(string)]; ok {
+			delete(s.states, args[0].(string))
+			return testdb.Result{RowsAffected: 1}, nil
+		}
+	case strings.HasPrefix(query, "SELECT user_id, provisioned FROM user_identities"):
+		if id, ok := s.identities[args[0].(string)+"|"+args[1].(string)]; ok {
+			return testdb.Row([]string{"user_id", "provisioned"}, id, true), nil
+		}
+	case strings.HasPrefix(query, "SELECT EXISTS(SELECT 1 FROM users"):
+		return testdb.Row([]string{"exists"}, false), nil
+	case strings.HasPrefix(query, "UPDATE users SET role=?"):
+		u := s.users[args[1].(int64)]
+		s.users[args[1].(int64)] = [2]string{u[0], args[0].(string)}
+	case strings.HasPrefix(query, "INSERT INTO users"):
+		id := int64(len(s.users) + 1)
+		s.users[id] = [2]string{args[0].(string), args[2].(string)}
+		return testdb.Result{LastInsertID: id, RowsAffected: 1}, nil
+	case strings.HasPrefix(query, "INSERT INTO user_identities"):
+		s.identities[args[1].(string)+"|"+args[2].(string)] = args[0].(int64)
+	case strings.HasPrefix(query, "INSERT INTO refresh_tokens"):
+		return testdb.Result{LastInsertID: 1, RowsAffected: 1}, nil
+	default:
+		return testdb.Result{}, &unexpectedQueryError{query}
+	}
+	return testdb.Result{}, nil
+}
+
+type unexpectedQueryError struct{ query string }
+
+func (e *unexpectedQueryError) Error() string { return "未预期的 SQL: " + e.query }
+
+// oidcTestEnv 以 httptest 启动的 mock 身份提供方与指向它的本系统登录路由
+type oidcTestEnv struct {
+	store  *oidcStore
+	router *gin.Engine
+	idp    *httptest.Server
+	client *http.Client
+}
+
+func newOIDCTestEnv(t *testing.T, username string, groups []string) *oidcTestEnv {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	idp := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(idp.Close)
+	h, err := mockoidc.NewHandler(mockoidc.Options{Issuer: idp.URL, ClientID: "loadtest", Username: username, Groups: groups})
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp.Config.Handler = h
+
+	origCfg, origProvider, origDB := config.OIDC, services.OIDC, models.DB
+	t.Cleanup(func() {
+		config.OIDC, services.OIDC, models.DB = origCfg, origProvider, origDB
+		utils.SetJWTKey(nil)
+	})
+	config.OIDC = &config.OIDCConfig{
+		Issuer:      idp.URL,
+		ClientID:    "loadtest",
+		RedirectURL: "http://app.example.com/api/oidc/callback",
+		Scopes:      []string{"openid", "profile"},
+		GroupsClaim: "groups",
+		RoleMap:     config.GroupRoleMap{"lt-admins": "admin"},
+	}
+	services.OIDC = services.NewOIDCProvider(config.OIDC)
+	utils.SetJWTKey([]byte("oidc-test-key"))
+	store := &oidcStore{states: map[string]models.LoginState{}, users: map[int64][2]string{}, identities: map[string]int64{}}
+	models.DB = testdb.Open(t, store.handle)
+
+	router := gin.New()
+	router.GET("/api/oidc/login", OIDCLogin)
+	router.GET("/api/oidc/callback", OIDCCallback)
+	return &oidcTestEnv{
+		store:  store,
+		router: router,
+		idp:    idp,
+		client: &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }},
+	}
+}
+
+// login 发起登录，返回跳转到 IdP 的地址与 state cookie
+func (e *oidcTestEnv) login(t *testing.T) (*url.URL, *http.Cookie) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	e.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/oidc/login", nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("登录应跳转到 IdP，got %d %s", rec.Code, rec.Body)
+	}
+	authURL, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == oidcStateCookie {
+			return authURL, c
+		}
+	}
+	t.Fatal("登录未设置 state cookie")
+	return nil, nil
+}
+
+// authorize 在 mock IdP 上授权，返回回调地址的查询参数
+func (e *oidcTestEnv) authorize(t *testing.T, authURL *url.URL) url.Values {
+	t.Helper()
+	resp, err := e.client.Get(authURL.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	back, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.StatusCode != http.StatusFound {
+		t.Fatalf("IdP 应跳回回调地址，got %d %v", resp.StatusCode, err)
+	}
+	return back.Query()
+}
+
+// callback 带着 cookie 回调，返回前端回调页 fragment 中的参数
+func (e *oidcTestEnv) callback(t *testing.T, query url.Values, cookie *http.Cookie) url.Values {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/oidc/callback?"+query.Encode(), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	e.router.ServeHTTP(rec, req)
+	loc := rec.Header().Get("Location")
+	if rec.Code != http.StatusFound || !strings.HasPrefix(loc, oidcCallbackPage+"#") {
+		t.Fatalf("回调应跳转到前端回调页，got %d %q", rec.Code, loc)
+	}
+	fragment, err := url.ParseQuery(strings.TrimPrefix(loc, oidcCallbackPage+"#"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fragment
+}
+
+func TestOIDCLoginRoundTrip(t *testing.T) {
+	e := newOIDCTestEnv(t, "alice", []string{"lt-admins"})
+
+	authURL, cookie := e.login(t)
+	if !strings.HasPrefix(authURL.String(), e.idp.URL+"/authorize?") {
+		t.Fatalf("应跳转到 discovery 中的 authorization_endpoint，got %s", authURL)
+	}
+	q := authURL.Query()
+	st, ok := e.store.states[q.Get("state")]
+	if !ok {
+		t.Fatalf("state %q 未保存", q.Get("state"))
+	}
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") != services.PKCEChallenge(st.CodeVerifier) {
+		t.Errorf("code_challenge 应为保存的 code_verifier 的 S256 摘要")
+	}
+	if q.Get("nonce") != st.Nonce || cookie.Value != oidcStateHash(st.State) || !cookie.HttpOnly {
+		t.Errorf("nonce 或 state cookie 与保存的登录状态不一致")
+	}
+
+	back := e.authorize(t, authURL)
+	if back.Get("state") != st.State || back.Get("code") == "" {
+		t.Fatalf("IdP 应带回授权码与原 state，got %v", back)
+	}
+	got := e.callback(t, back, cookie)
+	if got.Get("error") != "" || got.Get("token") == "" || got.Get("refresh_token") == "" {
+		t.Fatalf("登录应成功并返回令牌，got %v", got)
+	}
+	if got.Get("role") != "admin" {
+		t.Errorf("lt-admins 组应映射为 admin，got %q", got.Get("role"))
+	}
+	if len(e.store.states) != 0 {
+		t.Error("登录状态应在回调后被消耗")
+	}
+
+	// 首次登录自动创建用户并关联身份
+	if len(e.store.users) != 1 || e.store.users[1] != [2]string{"alice", "admin"} {
+		t.Errorf("首次登录应创建用户 alice（admin），got %v", e.store.users)
+	}
+	if e.store.identities["oidc:"+e.idp.URL+"|mock|alice"] != 1 {
+		t.Errorf("身份应关联到新用户，got %v", e.store.identities)
+	}
+	claims, err := utils.ParseToken(got.Get("token"))
+	if err != nil || claims.UserID != 1 {
+		t.Errorf("签发的 token 应属于新用户，got %+v %v", claims, err)
+	}
+
+	// 再次登录复用同一用户
+	authURL, cookie = e.login(t)
+	if got := e.callback(t, e.authorize(t, authURL), cookie); got.Get("error") != "" || len(e.store.users) != 1 {
+		t.Errorf("再次登录应复用已创建的用户，got %v users=%v", got, e.store.users)
+	}
+}
+
+func TestOIDCCallbackRejectsTamperedLogin(t *testing.T) {
+	tests := []struct {
+		name    string
+		tamper  func(e *oidcTestEnv, back url.Values, cookie **http.Cookie)
+		wantErr string
+	}{
+		{
+			name: "state 与本浏览器的 cookie 不匹配",
+			tamper: func(e *oidcTestEnv, back url.Values, cookie **http.Cookie) {
+				back.Set("state", "attacker-state")
+			},
+			wantErr: "登录状态与当前浏览器不匹配",
+		},
+		{
+			name: "缺少 state cookie",
+			tamper: func(e *oidcTestEnv, back url.Values, cookie **http.Cookie) {
+				*cookie = nil
+			},
+			wantErr: "登录状态与当前浏览器不匹配",
+		},
+		{
+			name: "ID Token 中的 nonce 与登录状态不一致",
+			tamper: func(e *oidcTestEnv, back url.Values, cookie **http.Cookie) {
+				st := e.store.states[back.Get("state")]
+				st.Nonce = "another-nonce"
+				e.store.states[st.State] = st
+			},
+			wantErr: "单点登录失败",
+		},
+		{
+			name: "code_verifier 与 code_challenge 不匹配",
+			tamper: func(e *oidcTestEnv, back url.Values, cookie **http.Cookie) {
+				st := e.store.states[back.Get("state")]
+				st.CodeVerifier = strings.Repeat("x", 43)
+				e.store.states[st.State] = st
+			},
+			wantErr: "单点登录失败",
+		},
+		{
+			name: "登录状态已被使用",
+			tamper: func(e *oidcTestEnv, back url.Values, cookie **http.Cookie) {
+				delete(e.store.states, back.Get("state"))
+			},
+			wantErr: "登录状态无效或已过期",
+		},
+	}
+	for _, tt := range tests {
+		e := newOIDCTestEnv(t, "mallory", nil)
+		authURL, cookie := e.login(t)
+		back := e.authorize(t, authURL)
+		tt.tamper(e, back, &cookie)
+		got := e.callback(t, back, cookie)
+		if !strings.Contains(got.Get("error"), tt.wantErr) || got.Get("token") != "" {
+			t.Errorf("%s: 应拒绝登录并提示 %q，got %v", tt.name, tt.wantErr, got)
+		}
+		if len(e.store.users) != 0 {
+			t.Errorf("%s: 被拒绝的登录不应创建用户", tt.name)
+		}
+	}
+}
@@ -0,0 +1,347 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"loadtest_project/models"
+	"loadtest_project/utils"
+)
+
+// isSystemAdmin 系统管理员（users.role = admin）拥有全部项目的管理权限
+func isSystemAdmin(claims *utils.Claims) bool {
+	return claims.Role == "admin"
+}
+
+// projectRole 当前用户在项目中的有效角色，系统管理员视为项目管理员；非成员或查询失败时为空
+func projectRole(claims *utils.Claims, projectID int) string {
+	if isSystemAdmin(claims) {
+		return models.RoleAdmin
+	}
+	role, err := models.ProjectRole(projectID, claims.UserID)
+	if err != nil {
+		return ""
+	}
+	return role
+}
+
+// hasProjectRole 当前用户在项目中的角色是否不低于 min
+func hasProjectRole(claims *utils.Claims, projectID int, min string) bool {
+	return models.RoleAtLeast(projectRole(claims, projectID), min)
+}
+
+// orgRole 当前用户在组织中的角色，系统管理员视为组织管理员
+func orgRole(claims *utils.Claims, orgID int) string {
+	if isSystemAdmin(claims) {
+		return models.RoleAdmin
+	}
+	role, err := models.OrgRole(orgID, claims.UserID)
+	if err != nil {
+		return ""
+	}
+	return role
+}
+
+// accessibleProjects 当前用户角色不低于 min 的项目 ID；系统管理员返回 nil 与 all=true
+func accessibleProjects(claims *utils.Claims, min string) (ids []int, all bool, err error) {
+	if isSystemAdmin(claims) {
+		return nil, true, nil
+	}
+	projects, err := models.ListUserProjects(claims.UserID)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, p := range projects {
+		if models.RoleAtLeast(p.Role, min) {
+			ids = append(ids, p.ID)
+		}
+	}
+	return ids, false, nil
+}
+
+// inPlaceholders 返回 n 个以逗号分隔的占位符，用于 IN (...) 查询
+func inPlaceholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+// CreateOrganization 创建组织，创建者成为组织管理员
+func CreateOrganization(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少 name"})
+		return
+	}
+	org := models.Organization{Name: strings.TrimSpace(req.Name)}
+	if err := models.CreateOrganization(&org, claims.UserID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建组织失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"organization": org})
+}
+
+// ListOrganizations 列出当前用户所属的组织
+func ListOrganizations(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	list, err := models.ListOrganizations(claims.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"organizations": list})
+}
+
+// CreateProject 在组织下创建项目，需组织管理员
+func CreateProject(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	var req struct {
+		OrgID int    `json:"org_id"`
+		Name  string `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少 org_id 或 name"})
+		return
+	}
+	if orgRole(claims, req.OrgID) != models.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "仅组织管理员可创建项目"})
+		return
+	}
+	p := models.Project{OrgID: req.OrgID, Name: strings.TrimSpace(req.Name)}
+	if err := models.CreateProject(&p); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建项目失败（同一组织内项目名称不能重复）"})
+		return
+	}
+	p.Role = models.RoleAdmin
+	c.JSON(http.StatusOK, gin.H{"project": p})
+}
+
+// ListProjects 列出当前用户可访问的项目及其有效角色
+func ListProjects(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	list, err := models.ListUserProjects(claims.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"projects": list})
+}
+
+// memberRequest 添加或修改成员角色的请求体，按用户名指定成员
+type memberRequest struct {
+	OrgID     int    `json:"org_id"`
+	ProjectID int    `json:"project_id"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+}
+
+// bindMemberRequest 解析成员请求并查找用户
+func bindMemberRequest(c *gin.Context) (memberRequest, int, bool) {
+	var req memberRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Username == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少 username"})
+		return req, 0, false
+	}
+	if !models.ValidRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role 只能是 viewer、tester、approver 或 admin"})
+		return req, 0, false
+	}
+	userID, err := models.GetUserIDByUsername(req.Username)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return req, 0, false
+	}
+	return req, userID, true
+}
+
+// lastOrgAdmin 修改或移除该成员后组织是否会失去最后一个管理员
+func lastOrgAdmin(orgID, userID int) bool {
+	role, err := models.OrgRole(orgID, userID)
+	if err != nil || role != models.RoleAdmin {
+		return false
+	}
+	n, err := models.CountOrgAdmins(orgID)
+	return err == nil && n <= 1
+}
+
+// ListOrgMembers 查询组织成员 ?org_id=xxx，需组织成员
+func ListOrgMembers(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	orgID, err := strconv.Atoi(c.Query("org_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 org_id"})
+		return
+	}
+	if orgRole(claims, orgID) == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "组织不存在"})
+		return
+	}
+	list, err := models.ListOrgMembers(orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"members": list})
+}
+
+// SetOrgMember 添加组织成员或修改其角色，组织角色对其下全部项目生效，需组织管理员
+func SetOrgMember(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	req, userID, ok := bindMemberRequest(c)
+	if !ok {
+		return
+	}
+	if orgRole(claims, req.OrgID) != models.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "仅组织管理员可管理成员"})
+		return
+	}
+	if req.Role != models.RoleAdmin && lastOrgAdmin(req.OrgID, userID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "组织至少需要一名管理员"})
+		return
+	}
+	if err := models.SetOrgMember(req.OrgID, userID, req.Role); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "保存成功"})
+}
+
+// RemoveOrgMember 移除组织成员 ?org_id=xxx&user_id=yyy，需组织管理员
+func RemoveOrgMember(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	orgID, err1 := strconv.Atoi(c.Query("org_id"))
+	userID, err2 := strconv.Atoi(c.Query("user_id"))
+	if err1 != nil || err2 != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 org_id 或 user_id"})
+		return
+	}
+	if orgRole(claims, orgID) != models.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "仅组织管理员可管理成员"})
+		return
+	}
+	if lastOrgAdmin(orgID, userID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "组织至少需要一名管理员"})
+		return
+	}
+	found, err := models.RemoveOrgMember(orgID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除失败"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "成员不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+}
+
+// ListProjectMembers 查询项目成员 ?project_id=xxx（不含仅通过组织获得权限的成员），需项目成员
+func ListProjectMembers(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	projectID, err := strconv.Atoi(c.Query("project_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 project_id"})
+		return
+	}
+	if !hasProjectRole(claims, projectID, models.RoleViewer) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "项目不存在"})
+		return
+	}
+	list, err := models.ListProjectMembers(projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"members": list})
+}
+
+// SetProjectMember 添加项目成员或修改其角色，需项目管理员
+func SetProjectMember(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	req, userID, ok := bindMemberRequest(c)
+	if !ok {
+		return
+	}
+	if _, err := models.GetProjectByID(req.ProjectID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "项目不存在"})
+		return
+	}
+	if !hasProjectRole(claims, req.ProjectID, models.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "仅项目管理员可管理成员"})
+		return
+	}
+	if err := models.SetProjectMember(req.ProjectID, userID, req.Role); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "保存成功"})
+}
+
+// RemoveProjectMember 移除项目成员 ?project_id=xxx&user_id=yyy，需项目管理员
+func RemoveProjectMember(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	projectID, err1 := strconv.Atoi(c.Query("project_id"))
+	userID, err2 := strconv.Atoi(c.Query("user_id"))
+	if err1 != nil || err2 != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 project_id 或 user_id"})
+		return
+	}
+	if !hasProjectRole(claims, projectID, models.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "仅项目管理员可管理成员"})
+		return
+	}
+	found, err := models.RemoveProjectMember(projectID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除失败"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "成员不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+}
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"loadtest_project/models"
+	"loadtest_project/models/testdb"
+	"loadtest_project/utils"
+)
+
+// 项目 1、2 属于不同团队；用户 10 提交了项目 1 中的任务 100 与项目 2 中的任务 200
+const (
+	submitterID = 10
+	approverID  = 11
+	testerID    = 12
+	outsiderID  = 13
+	sysAdminID  = 1
+)
+
+// projectMembers 项目 -> 用户 -> 有效角色（已合并组织角色）
+var projectMembers = map[int64]map[int64]string{
+	1: {submitterID: models.RoleApprover, approverID: models.RoleApprover, testerID: models.RoleTester},
+	2: {submitterID: models.RoleTester, outsiderID: models.RoleAdmin},
+}
+
+var projectTasks = map[int64]models.LoadTest{
+	100: {ID: 100, UserID: submitterID, ProjectID: 1, Status: "pending"},
+	200: {ID: 200, UserID: submitterID, ProjectID: 2, Status: "pending"},
+}
+
+func useProjectDB(t *testing.T) {
+	t.Helper()
+	orig := models.DB
+	t.Cleanup(func() { models.DB = orig })
+	models.DB = testdb.Open(t, func(query string, args []driver.Value) (testdb.Result, error) {
+		switch {
+		case strings.Contains(query, "FROM project_members WHERE project_id = ? AND user_id = ?"):
+			if role := projectMembers[args[0].(int64)][args[1].(int64)]; role != "" {
+				return testdb.Row([]string{"role"}, role), nil
+			}
+			return testdb.Result{}, nil
+		case strings.Contains(query, "FROM load_tests WHERE id=?"):
+			task, ok := projectTasks[args[0].(int64)]
+			if !ok {
+				return testdb.Result{}, nil
+			}
+			return testdb.Row(
+				[]string{"id", "user_id", "project_id", "num_users", "ramp_up", "target_url", "start_time", "end_time", "status", "test_type", "abort_reason", "failure_reason"},
+				int64(task.ID), int64(task.UserID), int64(task.ProjectID), int64(10), int64(1), "http://target", time.Time{}, time.Time{}, task.Status, models.TestTypeLoad, "", "",
+			), nil
+		}
+		return testdb.Result{}, &unexpectedQueryError{query}
+	})
+}
+
+func TestCanAccessTaskIsolatesProjects(t *testing.T) {
+	useProjectDB(t)
+	tests := []struct {
+		name   string
+		claims utils.Claims
+		task   int64
+		want   bool
+	}{
+		{"项目成员可查看本项目任务", utils.Claims{UserID: testerID, Role: "user"}, 100, true},
+		{"其他项目的成员不能查看", utils.Claims{UserID: testerID, Role: "user"}, 200, false},
+		{"另一项目的管理员也不能跨项目查看", utils.Claims{UserID: outsiderID, Role: "user"}, 100, false},
+		{"非成员不能查看", utils.Claims{UserID: 99, Role: "user"}, 100, false},
+		{"系统管理员可查看全部项目", utils.Claims{UserID: sysAdminID, Role: "admin"}, 200, true},
+	}
+	for _, tt := range tests {
+		if got := canAccessTask(&tt.claims, projectTasks[tt.task]); got != tt.want {
+			t.Errorf("%s: canAccessTask = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApprovableTask(t *testing.T) {
+	useProjectDB(t)
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name     string
+		claims   utils.Claims
+		task     string
+		wantOK   bool
+		wantCode int
+	}{
+		{"项目审批人可审批他人的任务", utils.Claims{UserID: approverID, Role: "user"}, "100", true, http.StatusOK},
+		{"审批人不能审批自己提交的任务", utils.Claims{UserID: submitterID, Role: "user"}, "100", false, http.StatusForbidden},
+		{"系统管理员可审批自己提交的任务", utils.Claims{UserID: submitterID, Role: "admin"}, "100", true, http.StatusOK},
+		{"tester 不能审批", utils.Claims{UserID: testerID, Role: "user"}, "100", false, http.StatusForbidden},
+		{"其他项目的管理员看不到该任务", utils.Claims{UserID: outsiderID, Role: "user"}, "100", false, http.StatusNotFound},
+		{"其他项目的审批人不能跨项目审批", utils.Claims{UserID: approverID, Role: "user"}, "200", false, http.StatusNotFound},
+		{"任务不存在", utils.Claims{UserID: approverID, Role: "user"}, "300", false, http.StatusNotFound},
+		{"无效的任务 ID", utils.Claims{UserID: approverID, Role: "user"}, "abc", false, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = httptest.NewRequest(http.MethodPost, "/admin/approve", strings.NewReader("id="+tt.task))
+		c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		claims := tt.claims
+		c.Set(claimsKey, &claims)
+
+		_, ok := approvableTask(c)
+		if ok != tt.wantOK || rec.Code != tt.wantCode {
+			t.Errorf("%s: ok = %v, status = %d, want %v, %d (%s)", tt.name, ok, rec.Code, tt.wantOK, tt.wantCode, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"loadtest_project/models"
+)
+
+// runLogPollInterval 实时跟踪日志时检查新内容的间隔
+const runLogPollInterval = time.Second
+
+// ListRuns 查询任务的运行记录（状态、失败原因、日志大小）?test_id=xxx
+func ListRuns(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	testID, err := strconv.Atoi(c.Query("test_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 test_id"})
+		return
+	}
+	task, err := models.GetLoadTestByID(testID)
+	if err != nil || !canAccessTask(claims, task) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+	runs, err := models.ListRuns(testID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"runs": runs})
+}
+
+// GetRunLog 查看运行日志 ?run_id=xxx：
+//   - 默认返回完整日志，&download=1 时作为附件下载；
+//   - &offset=N 只返回第 N 字节之后的内容，响应头 X-Log-Offset 为下次请求的 offset，
+//     X-Run-Status 为运行状态，可轮询跟踪；
+//   - &follow=1 从 offset 开始持续输出新内容，直到运行结束或客户端断开
+func GetRunLog(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	runID, err := strconv.Atoi(c.Query("run_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 run_id"})
+		return
+	}
+	run, err := models.GetRunByID(runID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "运行记录不存在"})
+		return
+	}
+	task, err := models.GetLoadTestByID(run.TestID)
+	if err != nil || !canAccessTask(claims, task) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "运行记录不存在"})
+		return
+	}
+	if _, err := os.Stat(run.LogPath); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "日志文件不存在"})
+		return
+	}
+
+	if c.Query("download") == "1" {
+		c.FileAttachment(run.LogPath, fmt.Sprintf("run_%d_%s.log", run.ID, run.Prefix))
+		return
+	}
+	var offset int64
+	if s := c.Query("offset"); s != "" {
+		if offset, err = strconv.ParseInt(s, 10, 64); err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 offset"})
+			return
+		}
+	}
+
+	f, err := os.Open(run.LogPath)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "读取日志失败"})
+		return
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 offset"})
+		return
+	}
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+
+	if c.Query("follow") != "1" {
+		var buf []byte
+		if buf, err = io.ReadAll(f); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "读取日志失败"})
+			return
+		}
+		c.Header("X-Log-Offset", strconv.FormatInt(offset+int64(len(buf)), 10))
+		c.Header("X-Run-Status", run.Status)
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", buf)
+		return
+	}
+
+	// 运行结束后日志不再增长，读完剩余内容即结束
+	finished := run.Status != models.RunStatusRunning
+	c.Stream(func(w io.Writer) bool {
+		n, _ := io.Copy(w, f)
+		if n == 0 && finished {
+			return false
+		}
+		if n == 0 {
+			select {
+			case <-c.Request.Context().Done():
+				return false
+			case <-time.After(runLogPollInterval):
+			}
+			if latest, err := models.GetRunByID(run.ID); err != nil || latest.Status != models.RunStatusRunning {
+				finished = true
+			}
+		}
+		return true
+	})
+}
@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"loadtest_project/models"
+)
+
+// validateScenarioSteps 按步骤类型校验场景步骤，并补全默认值与顺序
+func validateScenarioSteps(steps []models.ScenarioStep) error {
+	for i := range steps {
+		s := &steps[i]
+		if s.Type == "" {
+			s.Type = models.StepHTTP
+		}
+		if s.ThinkTime < 0 || s.Timeout < 0 {
+			return fmt.Errorf("第 %d 步: think_time / timeout 不能为负数", i+1)
+		}
+		var err error
+		switch s.Type {
+		case models.StepHTTP:
+			err = validateHTTPStep(s)
+		case models.StepWSConnect, models.StepWSSend, models.StepWSExpect, models.StepWSHold, models.StepWSClose:
+			err = validateWebSocketStep(s)
+		case models.StepGRPC:
+			err = validateGRPCStep(s)
+		default:
+			err = fmt.Errorf("不支持的步骤类型 %q", s.Type)
+		}
+		if err != nil {
+			return fmt.Errorf("第 %d 步: %w", i+1, err)
+		}
+		s.StepOrder = i + 1
+	}
+	return nil
+}
+
+// validateHTTPStep 校验 HTTP 步骤的方法、路径、断言与提取器
+func validateHTTPStep(s *models.ScenarioStep) error {
+	s.Method = strings.ToUpper(s.Method)
+	if s.Method == "" {
+		s.Method = http.MethodGet
+	}
+	switch s.Method {
+	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodHead, http.MethodOptions:
+	default:
+		return fmt.Errorf("不支持的方法 %s", s.Method)
+	}
+	if s.Path == "" {
+		return fmt.Errorf("path 不能为空")
+	}
+	for _, check := range s.Checks {
+		if err := validateStepCheck(check); err != nil {
+			return err
+		}
+	}
+	for _, ex := range s.Extractors {
+		if err := validateStepExtractor(ex); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateWebSocketStep 校验 WebSocket 步骤；ws_expect 可带提取器，从匹配的消息中取值
+func validateWebSocketStep(s *models.ScenarioStep) error {
+	switch s.Type {
+	case models.StepWSConnect:
+		if s.Path == "" {
+			return fmt.Errorf("ws_connect 需要 path")
+		}
+	case models.StepWSSend:
+		if s.Body == "" {
+			return fmt.Errorf("ws_send 需要 body")
+		}
+	case models.StepWSExpect:
+		if s.Body == "" {
+			return fmt.Errorf("ws_expect 需要 body（期望消息包含的内容）")
+		}
+		for _, ex := range s.Extractors {
+			if ex.Type == models.ExtractHeader {
+				return fmt.Errorf("ws_expect 不支持 header 提取器")
+			}
+			if err := validateStepExtractor(ex); err != nil {
+				return err
+			}
+		}
+	case models.StepWSHold:
+		if s.Timeout <= 0 {
+			return fmt.Errorf("ws_hold 需要 timeout（保持时长，毫秒）")
+		}
+	}
+	if len(s.Checks) > 0 {
+		return fmt.Errorf("%s 不支持 checks", s.Type)
+	}
+	return nil
+}
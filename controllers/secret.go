@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"loadtest_project/config"
+	"loadtest_project/models"
+	"loadtest_project/services"
+	"loadtest_project/utils"
+)
+
+// SaveSecretRequest 创建或轮换 secret 的请求体
+type SaveSecretRequest struct {
+	Name      string `json:"name"`
+	Value     string `json:"value"`
+	ProjectID int    `json:"project_id"` // 非 0 时保存为项目共享的 secret，需要项目管理员角色
+}
+
+// secretProject 解析 ?project_id：为 0 时操作当前用户自己的 secret；
+// 否则校验当前用户在项目中至少为 min 角色，失败时已写入响应
+func secretProject(c *gin.Context, claims *utils.Claims, projectID int, min string) bool {
+	if projectID == 0 || hasProjectRole(claims, projectID, min) {
+		return true
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "无权管理该项目的 secret"})
+	return false
+}
+
+// SaveSecret 加密保存 secret，响应中不包含明文
+func SaveSecret(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	if config.MasterKey == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "服务端未配置 secrets 主密钥"})
+		return
+	}
+	var req SaveSecretRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Value == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+	if !services.SecretNamePattern.MatchString(req.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "名称只能包含字母、数字、_ . -"})
+		return
+	}
+
+	ciphertext, err := services.EncryptSecret(req.Value)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "加密失败"})
+		return
+	}
+	if !secretProject(c, claims, req.ProjectID, models.RoleAdmin) {
+		return
+	}
+	secret := models.Secret{UserID: claims.UserID, ProjectID: req.ProjectID, Name: req.Name, Ciphertext: ciphertext}
+	if req.ProjectID != 0 {
+		err = models.SaveProjectSecret(&secret)
+	} else {
+		err = models.SaveSecret(&secret)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "保存成功", "name": req.Name, "reference": "{{secret:" + req.Name + "}}"})
+}
+
+// ListSecrets 列出当前用户的 secret 名称，?project_id=xxx 时列出项目共享的 secret
+func ListSecrets(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	projectID, _ := strconv.Atoi(c.Query("project_id"))
+	if !secretProject(c, claims, projectID, models.RoleTester) {
+		return
+	}
+	var secrets []models.Secret
+	if projectID != 0 {
+		secrets, err = models.ListProjectSecrets(projectID)
+	} else {
+		secrets, err = models.ListSecrets(claims.UserID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"secrets": secrets})
+}
+
+// DeleteSecret 删除 secret ?name=xxx，&project_id=xxx 时删除项目共享的 secret
+func DeleteSecret(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	projectID, _ := strconv.Atoi(c.Query("project_id"))
+	if !secretProject(c, claims, projectID, models.RoleAdmin) {
+		return
+	}
+	var found bool
+	if projectID != 0 {
+		found, err = models.DeleteProjectSecret(projectID, c.Query("name"))
+	} else {
+		found, err = models.DeleteSecret(claims.UserID, c.Query("name"))
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除失败"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "secret 不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+}
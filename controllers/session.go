@@ -0,0 +1,190 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"loadtest_project/models"
+	"loadtest_project/utils"
+)
+
+var errTokenRevoked = errors.New("Token 已失效")
+
+// checkAccessToken 拒绝已登出或签发后被整体吊销的 access token；查询失败时同样拒绝
+func checkAccessToken(claims *utils.Claims) error {
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	revoked, err := models.IsAccessTokenRevoked(claims.ID, claims.UserID, issuedAt)
+	if err != nil || revoked {
+		return errTokenRevoked
+	}
+	return nil
+}
+
+// issueSession 签发 access token 与 refresh token；familyID 为空时开始新的登录会话
+func issueSession(userID int, role, familyID string) (gin.H, *models.RefreshToken, error) {
+	access, err := utils.GenerateToken(userID, role)
+	if err != nil {
+		return nil, nil, err
+	}
+	plain, hash, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, nil, err
+	}
+	if familyID == "" {
+		if familyID, err = utils.NewTokenFamily(); err != nil {
+			return nil, nil, err
+		}
+	}
+	rt := &models.RefreshToken{
+		UserID:    userID,
+		TokenHash: hash,
+		FamilyID:  familyID,
+		ExpiresAt: time.Now().Add(utils.RefreshTokenTTL),
+	}
+	return gin.H{
+		"token":         access,
+		"refresh_token": plain,
+		"expires_in":    int(utils.AccessTokenTTL.Seconds()),
+		"role":          role,
+	}, rt, nil
+}
+
+// newSession 登录成功后开始新的会话，返回给客户端的令牌
+func newSession(userID int, role string) (gin.H, error) {
+	resp, rt, err := issueSession(userID, role, "")
+	if err != nil {
+		return nil, err
+	}
+	return resp, models.CreateRefreshToken(rt)
+}
+
+// startSession 登录成功后签发令牌并返回给客户端
+func startSession(c *gin.Context, userID int, role string) {
+	resp, err := newSession(userID, role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Token生成失败"})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// RefreshToken 用 refresh token 换取新的 access token 与 refresh token，旧 refresh token 随即失效；
+// 重复使用已失效的 refresh token 会吊销该次登录的全部令牌
+func RefreshToken(c *gin.Context) {
+	var req refreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+	old, err := models.GetRefreshTokenByHash(utils.HashToken(req.RefreshToken))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的 refresh token"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		}
+		return
+	}
+	if old.RevokedAt != nil {
+		models.RevokeRefreshFamily(old.FamilyID)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "refresh token 已失效，请重新登录"})
+		return
+	}
+	if time.Now().After(old.ExpiresAt) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "refresh token 已过期，请重新登录"})
+		return
+	}
+	// 按用户当前的角色签发，角色变更在下次刷新时生效
+	role, err := models.GetUserRole(old.UserID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "用户不存在"})
+		return
+	}
+	resp, next, err := issueSession(old.UserID, role, old.FamilyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Token生成失败"})
+		return
+	}
+	rotated, err := models.RotateRefreshToken(old.ID, next)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Token生成失败"})
+		return
+	}
+	if !rotated {
+		// 同一令牌被并发或重复使用
+		models.RevokeRefreshFamily(old.FamilyID)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "refresh token 已失效，请重新登录"})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+type logoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Logout 登出：当前 access token 加入黑名单，同时吊销请求中 refresh token 所在的登录会话
+func Logout(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	if claims.APIKeyID != 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "API 令牌请通过 /api/tokens 吊销"})
+		return
+	}
+	var req logoutRequest
+	c.ShouldBindJSON(&req)
+
+	if claims.ID != "" && claims.ExpiresAt != nil {
+		if err := models.DenyAccessToken(claims.ID, claims.ExpiresAt.Time); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "登出失败"})
+			return
+		}
+	}
+	if req.RefreshToken != "" {
+		rt, err := models.GetRefreshTokenByHash(utils.HashToken(req.RefreshToken))
+		if err == nil && rt.UserID == claims.UserID {
+			if err := models.RevokeRefreshFamily(rt.FamilyID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "登出失败"})
+				return
+			}
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已登出"})
+}
+
+type revokeSessionsRequest struct {
+	UserID int `json:"user_id" binding:"required"`
+}
+
+// RevokeUserSessions 管理员吊销用户的全部登录会话（如离职），已签发的 access token 立即失效；
+// 不影响该用户的 API 令牌，需要时另行吊销
+func RevokeUserSessions(c *gin.Context) {
+	var req revokeSessionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+	if _, err := models.GetUserRole(req.UserID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+	if err := models.RevokeUserSessions(req.UserID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "吊销失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已吊销该用户的全部登录会话"})
+}
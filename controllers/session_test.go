@@ -0,0 +1,211 @@
+package controllers
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"loadtest_project/models"
+	"loadtest_project/models/testdb"
+	"loadtest_project/utils"
+)
+
+// sessionStore 内存中的 refresh_tokens、token_denylist 与 token_revocations
+type sessionStore struct {
+	refresh       []*models.RefreshToken
+	denied        map[string]bool
+	revokedBefore map[int64]time.Time
+}
+
+func (s *sessionStore) handle(query string, args []driver.Value) (testdb.Result, error) {
+	switch {
+	case strings.Contains(query, "FROM refresh_tokens WHERE token_hash=?"):
+		for _, rt := range s.refresh {
+			if rt.TokenHash == args[0] {
+				var revoked driver.Value
+				if rt.RevokedAt != nil {
+					revoked = *rt.RevokedAt
+				}
+				return testdb.Row(
+					[]string{"id", "user_id", "token_hash", "family_id", "expires_at", "revoked_at", "created_at"},
+					int64(rt.ID), int64(rt.UserID), rt.TokenHash, rt.FamilyID, rt.ExpiresAt, revoked, rt.CreatedAt,
+				), nil
+			}
+		}
+	case strings.HasPrefix(query, "INSERT INTO refresh_tokens"):
+		rt := &models.RefreshToken{
+			ID: len(s.refresh) + 1, UserID: int(args[0].(int64)), TokenHash: args[1].(string),
+			FamilyID: args[2].(string), ExpiresAt: args[3].(time.Time), CreatedAt: args[4].(time.Time),
+		}
+		s.refresh = append(s.refresh, rt)
+		return testdb.Result{LastInsertID: int64(rt.ID), RowsAffected: 1}, nil
+	case strings.HasPrefix(query, "UPDATE refresh_tokens SET revoked_at=? WHERE id=? AND revoked_at IS NULL"):
+		return s.revoke(args[0].(time.Time), func(rt *models.RefreshToken) bool { return int64(rt.ID) == args[1] }), nil
+	case strings.HasPrefix(query, "UPDATE refresh_tokens SET revoked_at=? WHERE family_id=? AND revoked_at IS NULL"):
+		return s.revoke(args[0].(time.Time), func(rt *models.RefreshToken) bool { return rt.FamilyID == args[1] }), nil
+	case strings.HasPrefix(query, "UPDATE refresh_tokens SET replaced_by=?"):
+	case strings.HasPrefix(query, "SELECT role FROM users WHERE id=?"):
+		return testdb.Row([]string{"role"}, "user"), nil
+	case strings.HasPrefix(query, "DELETE FROM token_denylist"):
+	case strings.HasPrefix(query, "INSERT IGNORE INTO token_denylist"):
+		s.denied[args[0].(string)] = true
+	case strings.Contains(query, "FROM token_denylist WHERE jti=?"):
+		before, ok := s.revokedBefore[args[1].(int64)]
+		revoked := s.denied[args[0].(string)] || (ok && !before.Before(args[2].(time.Time)))
+		return testdb.Row([]string{"revoked"}, revoked), nil
+	default:
+		return testdb.Result{}, &unexpectedQueryError{query}
+	}
+	return testdb.Result{}, nil
+}
+
+func (s *sessionStore) revoke(now time.Time, match func(*models.RefreshToken) bool) testdb.Result {
+	var n int64
+	for _, rt := range s.refresh {
+		if rt.RevokedAt == nil && match(rt) {
+			rt.RevokedAt = &now
+			n++
+		}
+	}
+	return testdb.Result{RowsAffected: n}
+}
+
+func useSessionStore(t *testing.T) (*sessionStore, *gin.Engine) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	store := &sessionStore{denied: map[string]bool{}, revokedBefore: map[int64]time.Time{}}
+	orig := models.DB
+	t.Cleanup(func() {
+		models.DB = orig
+		utils.SetJWTKey(nil)
+	})
+	models.DB = testdb.Open(t, store.handle)
+	utils.SetJWTKey([]byte("session-test-key"))
+
+	r := gin.New()
+	r.POST("/api/token/refresh", RefreshToken)
+	r.POST("/api/logout", Logout)
+	r.GET("/api/tests", func(c *gin.Context) {
+		if _, err := tokenClaims(c); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	return store, r
+}
+
+func postJSON(r *gin.Engine, path, token string, body interface{}) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func refresh(t *testing.T, r *gin.Engine, token string) (int, string) {
+	t.Helper()
+	rec := postJSON(r, "/api/token/refresh", "", gin.H{"refresh_token": token})
+	var resp struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	return rec.Code, resp.RefreshToken
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	store, r := useSessionStore(t)
+	login, err := newSession(7, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := newSession(7, "user") // 同一用户另一台设备的登录
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := login["refresh_token"].(string)
+
+	code, second := refresh(t, r, first)
+	if code != http.StatusOK || second == "" || second == first {
+		t.Fatalf("首次刷新应轮换出新的 refresh token，got %d %q", code, second)
+	}
+	code, third := refresh(t, r, second)
+	if code != http.StatusOK || third == "" {
+		t.Fatalf("新令牌应可继续刷新，got %d", code)
+	}
+
+	// 重放已轮换掉的令牌：视为泄露，整个家族（包括当前有效的 third）吊销
+	if code, _ := refresh(t, r, first); code != http.StatusUnauthorized {
+		t.Fatalf("重复使用已轮换的令牌应被拒绝，got %d", code)
+	}
+	if code, _ := refresh(t, r, third); code != http.StatusUnauthorized {
+		t.Errorf("重放后同一家族中尚未使用的令牌也应失效，got %d", code)
+	}
+	family := store.refresh[0].FamilyID
+	for _, rt := range store.refresh {
+		if rt.FamilyID == family && rt.RevokedAt == nil {
+			t.Errorf("家族中的令牌 %d 未被吊销", rt.ID)
+		}
+	}
+	if code, _ := refresh(t, r, other["refresh_token"].(string)); code != http.StatusOK {
+		t.Errorf("其他登录会话不应受影响，got %d", code)
+	}
+	if code, _ := refresh(t, r, "rt_unknown"); code != http.StatusUnauthorized {
+		t.Errorf("不存在的 refresh token 应被拒绝，got %d", code)
+	}
+}
+
+func TestDenylistedAccessTokenRejected(t *testing.T) {
+	store, r := useSessionStore(t)
+	access, err := utils.GenerateToken(7, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/tests", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := get(access); code != http.StatusOK {
+		t.Fatalf("登出前 access token 应有效，got %d", code)
+	}
+	if rec := postJSON(r, "/api/logout", access, gin.H{}); rec.Code != http.StatusOK {
+		t.Fatalf("登出失败: %d %s", rec.Code, rec.Body)
+	}
+	claims, _ := utils.ParseToken(access)
+	if !store.denied[claims.ID] {
+		t.Fatalf("登出后 jti %q 应加入黑名单", claims.ID)
+	}
+	if code := get(access); code != http.StatusUnauthorized {
+		t.Errorf("黑名单中的 access token 应被拒绝，got %d", code)
+	}
+	if err := checkAccessToken(claims); !errors.Is(err, errTokenRevoked) {
+		t.Errorf("checkAccessToken = %v, want errTokenRevoked", err)
+	}
+
+	// 签发于整体吊销之前的令牌同样失效，之后签发的不受影响
+	other, _ := utils.GenerateToken(8, "user")
+	otherClaims, _ := utils.ParseToken(other)
+	store.revokedBefore[8] = otherClaims.IssuedAt.Time
+	if code := get(other); code != http.StatusUnauthorized {
+		t.Errorf("整体吊销前签发的 access token 应被拒绝，got %d", code)
+	}
+	store.revokedBefore[8] = otherClaims.IssuedAt.Time.Add(-time.Minute)
+	if code := get(other); code != http.StatusOK {
+		t.Errorf("整体吊销之后签发的 access token 应有效，got %d", code)
+	}
+}
@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+
+	"loadtest_project/models"
+)
+
+// validateSubmitOptions 校验提交任务时附带的各项配置，失败时返回错误标题与原因
+func validateSubmitOptions(userID int, req *SubmitRequest) (string, error) {
+	// 容量探测任务必须附带阶梯参数
+	if req.TestType == "" {
+		req.TestType = models.TestTypeLoad
+	}
+	switch req.TestType {
+	case models.TestTypeLoad:
+	case models.TestTypeCapacity:
+		if err := validateCapacityConfig(req.Capacity, req.EndTime.Sub(req.StartTime)); err != nil {
+			return "容量探测参数错误", err
+		}
+	default:
+		return "不支持的 test_type", errors.New(req.TestType)
+	}
+	if err := validateThresholds(req.Thresholds); err != nil {
+		return "SLO 阈值错误", err
+	}
+	if req.Abort != nil {
+		if err := validateAbortCondition(req.Abort); err != nil {
+			return "中止条件错误", err
+		}
+	}
+	if req.Target != nil {
+		if err := validateTargetOptions(req.Target); err != nil {
+			return "目标选项错误", err
+		}
+	}
+	if err := validateScenarioSteps(req.Steps); err != nil {
+		return "场景步骤错误", err
+	}
+	if req.Dataset != nil {
+		if err := validateTestDataset(userID, req.Dataset); err != nil {
+			return "数据集参数错误", err
+		}
+	}
+	if req.GRPC != nil {
+		if err := validateGRPCOptions(userID, req.GRPC, req.Steps); err != nil {
+			return "gRPC 参数错误", err
+		}
+	}
+	if req.Transport != nil {
+		if err := validateTransportOptions(req.Transport); err != nil {
+			return "传输选项错误", err
+		}
+	}
+	if req.Distributed != nil {
+		if err := validateDistributedOptions(req.Distributed); err != nil {
+			return "分布式参数错误", err
+		}
+		// sequential 与 unique 模式的数据集按行拆给各 worker，每个 worker 至少要分到一行
+		if req.Dataset != nil && req.Dataset.Mode != models.DatasetRandom && req.Distributed.Workers > 1 {
+			if ds, err := models.GetDatasetByID(req.Dataset.DatasetID); err == nil && ds.RowCount < req.Distributed.Workers {
+				return "分布式参数错误", fmt.Errorf("数据集只有 %d 行，少于 workers %d", ds.RowCount, req.Distributed.Workers)
+			}
+		}
+	}
+	return "", nil
+}
+
+// saveSubmitOptions 在任务创建后保存各项附加配置
+func saveSubmitOptions(testID int, req *SubmitRequest) error {
+	if req.TestType == models.TestTypeCapacity {
+		req.Capacity.TestID = testID
+		if err := models.CreateCapacityConfig(req.Capacity); err != nil {
+			return fmt.Errorf("容量探测配置保存失败: %w", err)
+		}
+	}
+	for i := range req.Thresholds {
+		req.Thresholds[i].TestID = testID
+		if err := models.CreateThreshold(&req.Thresholds[i]); err != nil {
+			return fmt.Errorf("SLO 阈值保存失败: %w", err)
+		}
+	}
+	if req.Abort != nil {
+		req.Abort.TestID = testID
+		if err := models.CreateAbortCondition(req.Abort); err != nil {
+			return fmt.Errorf("中止条件保存失败: %w", err)
+		}
+	}
+	if req.Target != nil {
+		req.Target.TestID = testID
+		if err := models.CreateTargetOptions(req.Target); err != nil {
+			return fmt.Errorf("目标选项保存失败: %w", err)
+		}
+	}
+	if err := saveScenarioSteps(testID, req.Steps); err != nil {
+		return err
+	}
+	if req.Dataset != nil {
+		req.Dataset.TestID = testID
+		if err := models.CreateTestDataset(req.Dataset); err != nil {
+			return fmt.Errorf("数据集关联失败: %w", err)
+		}
+	}
+	if req.GRPC != nil {
+		req.GRPC.TestID = testID
+		if err := models.CreateGRPCOptions(req.GRPC); err != nil {
+			return fmt.Errorf("gRPC 配置保存失败: %w", err)
+		}
+	}
+	if req.Transport != nil {
+		req.Transport.TestID = testID
+		if err := models.CreateTransportOptions(req.Transport); err != nil {
+			return fmt.Errorf("传输选项保存失败: %w", err)
+		}
+	}
+	if req.Distributed != nil {
+		req.Distributed.TestID = testID
+		if err := models.CreateDistributedOptions(req.Distributed); err != nil {
+			return fmt.Errorf("分布式参数保存失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// saveScenarioSteps 依次保存场景步骤
+func saveScenarioSteps(testID int, steps []models.ScenarioStep) error {
+	for i := range steps {
+		steps[i].TestID = testID
+		if err := models.CreateScenarioStep(&steps[i]); err != nil {
+			return fmt.Errorf("场景步骤保存失败: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"loadtest_project/models"
+	"loadtest_project/services"
+)
+
+// validateTargetOptions 校验认证方式、证书组合，以及凭据只以 secret 引用提供
+func validateTargetOptions(o *models.TargetOptions) error {
+	switch o.AuthType {
+	case models.AuthNone:
+	case models.AuthBasic:
+		if o.Username == "" {
+			return errors.New("basic 认证需要 username")
+		}
+	case models.AuthBearer:
+		if o.Token == "" {
+			return errors.New("bearer 认证需要 token")
+		}
+	default:
+		return errors.New("不支持的 auth_type")
+	}
+	if o.ClientKey != "" && o.ClientCert == "" {
+		return errors.New("提供 client_key 时必须同时提供 client_cert")
+	}
+	return services.CheckTargetCredentials(o)
+}
+
+// GetTargetOptions 查询任务的目标选项，敏感字段已遮蔽 ?test_id=xxx
+func GetTargetOptions(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	testID, err := strconv.Atoi(c.Query("test_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 test_id"})
+		return
+	}
+	task, err := models.GetLoadTestByID(testID)
+	if err != nil || !canAccessTask(claims, task) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+
+	opts, err := models.GetTargetOptions(testID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusOK, gin.H{"target": nil})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"target": opts.Redacted()})
+}
@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"loadtest_project/models"
+	"loadtest_project/services"
+)
+
+// validateThresholds 校验提交的 SLO 阈值的指标名与运算符
+func validateThresholds(thresholds []models.Threshold) error {
+	for _, t := range thresholds {
+		if _, ok := services.ThresholdMetrics[t.Metric]; !ok {
+			return fmt.Errorf("不支持的指标: %q", t.Metric)
+		}
+		if _, ok := services.ThresholdOperators[t.Operator]; !ok {
+			return fmt.Errorf("不支持的运算符: %q", t.Operator)
+		}
+	}
+	return nil
+}
+
+// verdictRecords 将 SLO 结论转换为报告表格行（与结果表同为 5 列）
+func verdictRecords(v models.Verdict) [][]string {
+	overall := "PASS"
+	if !v.Passed {
+		overall = "FAIL"
+	}
+	records := [][]string{
+		{"SLO Verdict", overall, "", "", ""},
+		{"Metric", "Operator", "Threshold", "Actual", "Result"},
+	}
+	for _, d := range v.Details {
+		result := "PASS"
+		if !d.Passed {
+			result = "FAIL"
+		}
+		records = append(records, []string{
+			d.Metric, d.Operator,
+			fmt.Sprintf("%.4f", d.Value), fmt.Sprintf("%.4f", d.Actual),
+			result,
+		})
+	}
+	return records
+}
+
+// GetVerdict 查询任务的阈值与最近一次 SLO 结论 ?test_id=xxx
+func GetVerdict(c *gin.Context) {
+	claims, err := tokenClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的Token"})
+		return
+	}
+	testID, err := strconv.Atoi(c.Query("test_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 test_id"})
+		return
+	}
+	task, err := models.GetLoadTestByID(testID)
+	if err != nil || !canAccessTask(claims, task) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+
+	thresholds, err := models.GetThresholdsByTestID(testID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+	var verdict interface{}
+	v, err := models.GetLatestVerdict(testID)
+	switch {
+	case err == nil:
+		verdict = v
+	case !errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"thresholds": thresholds, "verdict": verdict})
+}
+
+// validateAbortCondition 校验实时中止条件
+func validateAbortCondition(a *models.AbortCondition) error {
+	if a.MaxErrorRate <= 0 && a.MaxP99 <= 0 {
+		return errors.New("至少需要设置 max_error_rate 或 max_p99 之一")
+	}
+	if a.MaxErrorRate < 0 || a.MaxErrorRate > 1 {
+		return errors.New("max_error_rate 取值范围为 0~1")
+	}
+	if a.WindowSeconds < 0 {
+		return errors.New("window_seconds 不能为负数")
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+
+	"loadtest_project/models"
+)
+
+// maxRequestTimeout 单个请求超时上限（毫秒）
+const maxRequestTimeout = 10 * 60 * 1000
+
+// validateTransportOptions 校验传输层选项并补全默认 HTTP 版本
+func validateTransportOptions(opts *models.TransportOptions) error {
+	switch opts.HTTPVersion {
+	case "":
+		opts.HTTPVersion = models.HTTPVersion11
+	case models.HTTPVersion11, models.HTTPVersion2:
+	default:
+		return fmt.Errorf("不支持的 http_version %q，可选 1.1 或 2", opts.HTTPVersion)
+	}
+	if opts.MaxConnsPerHost < 0 {
+		return errors.New("max_conns_per_host 不能为负数")
+	}
+	if opts.RequestTimeout < 0 || opts.RequestTimeout > maxRequestTimeout {
+		return fmt.Errorf("request_timeout 需在 0~%d 毫秒之间", maxRequestTimeout)
+	}
+	return nil
+}
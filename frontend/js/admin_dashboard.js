@@ -14,8 +14,8 @@ document.addEventListener("DOMContentLoaded", () => {
     const statusSelect = document.getElementById("statusFilter");
 
     // 退出登录
-    logoutBtn.onclick = () => {
-        localStorage.removeItem("token");
+    logoutBtn.onclick = async () => {
+        await logoutSession();
         location.href = "/";
     };
 
@@ -29,9 +29,7 @@ document.addEventListener("DOMContentLoaded", () => {
 async function loadTasks() {
     const status = document.getElementById("statusFilter").value;
     const url = `${API}/tasks?status=${status}`;
-    const res = await fetch(url, {
-        headers: { "Authorization": "Bearer " + token }
-    });
+    const res = await authFetch(url);
 
     if (res.status === 403) {
         alert("你不是管理员或登录已过期");
@@ -81,9 +79,8 @@ async function loadTasks() {
 window.approveTask = async id => {
     const form = new URLSearchParams();
     form.append("id", id);
-    const res = await fetch(`${API}/approve`, {
+    const res = await authFetch(`${API}/approve`, {
         method: "POST",
-        headers: { "Authorization": "Bearer " + token },
         body: form
     });
     if (!res.ok) {
@@ -98,9 +95,8 @@ window.approveTask = async id => {
 window.rejectTask = async id => {
     const form = new URLSearchParams();
     form.append("id", id);
-    const res = await fetch(`${API}/reject`, {
+    const res = await authFetch(`${API}/reject`, {
         method: "POST",
-        headers: { "Authorization": "Bearer " + token },
         body: form
     });
     if (!res.ok) {
@@ -0,0 +1,69 @@
+// frontend/js/auth.js
+// access token 只有 15 分钟有效期：请求返回 401 时用 refresh token 换取新令牌后重试一次
+
+const AUTH_API = "http://localhost:8080/api";
+
+// 同一时刻只发起一次刷新，并发请求共用结果（refresh token 每次使用后即失效）
+let refreshing = null;
+
+async function refreshSession() {
+    const refreshToken = localStorage.getItem("refresh_token");
+    if (!refreshToken) {
+        return false;
+    }
+    if (!refreshing) {
+        refreshing = fetch(`${AUTH_API}/token/refresh`, {
+            method: "POST",
+            headers: { "Content-Type": "application/json" },
+            body: JSON.stringify({ refresh_token: refreshToken })
+        }).then(async res => {
+            if (!res.ok) {
+                return false;
+            }
+            const data = await res.json();
+            localStorage.setItem("token", data.token);
+            localStorage.setItem("refresh_token", data.refresh_token);
+            localStorage.setItem("role", data.role);
+            return true;
+        }).catch(() => false).finally(() => { refreshing = null; });
+    }
+    return refreshing;
+}
+
+// 带当前 Token 发起请求，过期时自动刷新；刷新失败则回到登录页
+async function authFetch(url, options = {}) {
+    const send = () => fetch(url, {
+        ...options,
+        headers: { ...(options.headers || {}), "Authorization": "Bearer " + localStorage.getItem("token") }
+    });
+    let res = await send();
+    if (res.status === 401) {
+        if (await refreshSession()) {
+            res = await send();
+        } else {
+            clearSession();
+            alert("登录已过期，请重新登录");
+            location.href = "/";
+        }
+    }
+    return res;
+}
+
+function clearSession() {
+    localStorage.removeItem("token");
+    localStorage.removeItem("refresh_token");
+    localStorage.removeItem("role");
+}
+
+// 退出登录：服务端吊销当前 Token 与 refresh token 后清除本地状态
+async function logoutSession() {
+    await fetch(`${AUTH_API}/logout`, {
+        method: "POST",
+        headers: {
+            "Content-Type":  "application/json",
+            "Authorization": "Bearer " + localStorage.getItem("token")
+        },
+        body: JSON.stringify({ refresh_token: localStorage.getItem("refresh_token") })
+    }).catch(() => {});
+    clearSession();
+}
@@ -34,12 +34,9 @@ async function submitTask() {
     };
     console.log("提交的数据:", payload);
 
-    const res = await fetch(`${API_BASE}/submit`, {
+    const res = await authFetch(`${API_BASE}/submit`, {
         method: "POST",
-        headers: {
-            "Content-Type":  "application/json",
-            "Authorization": `Bearer ${token}`
-        },
+        headers: { "Content-Type": "application/json" },
         body: JSON.stringify(payload)
     });
     const data = await res.json();
@@ -58,23 +55,37 @@ function downloadReport(format) {
         alert("请输入任务ID");
         return;
     }
-    const url = `${API_BASE}/download_report?test_id=${taskId}&format=${format}`;
-    window.open(url, "_blank");
+    fetchReport(taskId, format);
+}
+
+// 带 Token 拉取报告并触发浏览器下载（报告接口需要认证，不能直接打开链接）
+async function fetchReport(taskId, format) {
+    const res = await authFetch(`${API_BASE}/download_report?test_id=${taskId}&format=${format}`);
+    if (!res.ok) {
+        const data = await res.json().catch(() => ({}));
+        alert("下载失败: " + (data.error || res.status));
+        return;
+    }
+    const blob = await res.blob();
+    const a = document.createElement("a");
+    a.href = URL.createObjectURL(blob);
+    a.download = `report_${taskId}.${format}`;
+    document.body.appendChild(a);
+    a.click();
+    a.remove();
+    URL.revokeObjectURL(a.href);
 }
 
 // 退出登录
-function logout() {
-    localStorage.removeItem("token");
+async function logout() {
+    await logoutSession();
     window.location.href = "index.html";
 }
 
 // 加载“我的任务列表”
 async function loadMyTasks() {
-    const res = await fetch(`${API_BASE}/tasks`, {
-        headers: {
-            "Content-Type":  "application/json",
-            "Authorization": `Bearer ${token}`
-        }
+    const res = await authFetch(`${API_BASE}/tasks`, {
+        headers: { "Content-Type": "application/json" }
     });
     if (!res.ok) {
         console.error("加载我的任务失败:", await res.text());
@@ -92,8 +103,8 @@ async function loadMyTasks() {
         let reportLinks = "";
         if (t.status === "completed") {
             reportLinks = `
-                <a href="${API_BASE}/download_report?test_id=${t.id}&format=csv" target="_blank">CSV</a>
-                <a href="${API_BASE}/download_report?test_id=${t.id}&format=pdf" target="_blank">PDF</a>
+                <a href="#" onclick="fetchReport(${t.id}, 'csv'); return false;">CSV</a>
+                <a href="#" onclick="fetchReport(${t.id}, 'pdf'); return false;">PDF</a>
             `;
         }
 
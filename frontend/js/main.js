@@ -19,8 +19,9 @@ async function login() {
     if (res.ok) {
         alert("登录成功");
 
-        // 存储 token 和 role
+        // 存储 token、refresh token 和 role
         localStorage.setItem("token", data.token);
+        localStorage.setItem("refresh_token", data.refresh_token);
         localStorage.setItem("role", data.role);
 
         // 根据角色跳转
@@ -56,3 +57,10 @@ async function register() {
         alert(data.error || "注册失败");
     }
 }
+
+// 单点登录：跳转到身份提供方，完成后回到 oidc_callback.html
+function ssoLogin() {
+    window.location.href = `${API_BASE}/oidc/login`;
+}
+
+document.getElementById("ssoBtn").onclick = ssoLogin;
@@ -0,0 +1,20 @@
+// frontend/js/oidc_callback.js
+// 单点登录回调：服务器把令牌放在 URL fragment 中，保存后清除地址栏再跳转
+
+const params = new URLSearchParams(location.hash.slice(1));
+history.replaceState(null, "", location.pathname);
+
+if (params.get("error")) {
+    document.getElementById("message").textContent = "登录失败：" + params.get("error");
+} else {
+    localStorage.setItem("token", params.get("token"));
+    localStorage.setItem("refresh_token", params.get("refresh_token"));
+    localStorage.setItem("role", params.get("role"));
+
+    // 根据角色跳转
+    if (params.get("role") === "admin") {
+        location.href = "/static/admin.html";
+    } else {
+        location.href = "/static/dashboard.html";
+    }
+}
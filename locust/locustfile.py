@@ -1,14 +1,761 @@
 from locust import HttpUser, task, between, events
+from locust.exception import StopUser
+from locust.runners import MasterRunner, WorkerRunner
+import gevent
+import csv
+import datetime
+import itertools
 import json
 import os
+import random
+import re
+import ssl
+import threading
 import time
 
+# WebSocket 步骤依赖 websocket-client，仅在场景使用时才需要安装
+try:
+    import websocket
+except ImportError:
+    websocket = None
+
+# HTTP/2 依赖 httpx[http2]，仅在传输选项选择 HTTP/2 时才需要安装
+try:
+    import httpx
+except ImportError:
+    httpx = None
+
+# gRPC 步骤依赖 grpcio，使用服务端反射时还需要 grpcio-reflection
+try:
+    import grpc
+    from google.protobuf import descriptor_pb2, descriptor_pool, json_format
+except ImportError:
+    grpc = None
+
+# 运行配置：由 Go 端写入 JSON 文件并通过 LOADTEST_CONFIG 传入
+CONFIG = {}
+_config_path = os.environ.get("LOADTEST_CONFIG")
+if _config_path:
+    with open(_config_path, encoding="utf-8") as f:
+        CONFIG = json.load(f)
+
+
+def apply_target_options(client, config):
+    """把请求头、Cookie、认证和 TLS 选项应用到 HttpSession 上"""
+    client.headers.update(config.get("headers") or {})
+    client.cookies.update(config.get("cookies") or {})
+
+    auth = config.get("auth")
+    if auth:
+        if auth.get("type") == "basic":
+            client.auth = (auth.get("username", ""), auth.get("password", ""))
+        elif auth.get("type") == "bearer":
+            client.headers["Authorization"] = "Bearer " + auth.get("token", "")
+
+    tls = config.get("tls") or {}
+    if tls.get("skip_verify"):
+        client.verify = False
+    elif tls.get("ca_file"):
+        client.verify = tls["ca_file"]
+    if tls.get("cert_file"):
+        client.cert = (tls["cert_file"], tls["key_file"]) if tls.get("key_file") else tls["cert_file"]
+
+
+# 传输层选项：HTTP 版本、连接复用、超时与重定向
+TRANSPORT = CONFIG.get("transport") or {}
+
+_shared_pool = None
+_shared_pool_lock = threading.Lock()
+
+
+def shared_pool(factory):
+    """max_conns_per_host 大于 0 时，所有虚拟用户共用同一个连接池"""
+    global _shared_pool
+    with _shared_pool_lock:
+        if _shared_pool is None:
+            _shared_pool = factory()
+        return _shared_pool
+
+
+def request_options():
+    """每个请求都带上的超时与重定向参数（requests 风格）"""
+    timeout = TRANSPORT.get("request_timeout")
+    return {
+        "timeout": timeout / 1000.0 if timeout else None,
+        "allow_redirects": not TRANSPORT.get("disable_redirects"),
+    }
+
+
+def apply_transport_options(user):
+    """按传输层选项替换或调整虚拟用户的 HTTP 客户端，需在 apply_target_options 之前调用"""
+    max_conns = TRANSPORT.get("max_conns_per_host") or 0
+    if TRANSPORT.get("http_version") == "2":
+        if httpx is None:
+            raise RuntimeError("HTTP/2 需要安装 httpx[http2]")
+        transport = shared_pool(lambda: http2_transport(CONFIG)) if max_conns else http2_transport(CONFIG)
+        user.client = HTTP2Session(user.host, transport, user.environment.events.request)
+        return
+
+    if TRANSPORT.get("disable_keep_alive"):
+        # 服务端处理完即关闭连接，每个请求都重新建连
+        user.client.headers["Connection"] = "close"
+    if max_conns:
+        from requests.adapters import HTTPAdapter
+        adapter = shared_pool(lambda: HTTPAdapter(pool_connections=1, pool_maxsize=max_conns, pool_block=True))
+        user.client.mount("http://", adapter)
+        user.client.mount("https://", adapter)
+
+
+def http2_transport(config):
+    tls = config.get("tls") or {}
+    verify = False if tls.get("skip_verify") else (tls.get("ca_file") or True)
+    cert = None
+    if tls.get("cert_file"):
+        cert = (tls["cert_file"], tls["key_file"]) if tls.get("key_file") else tls["cert_file"]
+    limits = httpx.Limits(
+        max_connections=TRANSPORT.get("max_conns_per_host") or None,
+        max_keepalive_connections=0 if TRANSPORT.get("disable_keep_alive") else None,
+    )
+    return httpx.HTTPTransport(http2=True, verify=verify, cert=cert, limits=limits)
+
+
+class HTTP2Session:
+    """基于 httpx 的 HTTP/2 会话，提供场景用到的 HttpSession 接口并照常上报 Locust 统计。
+    TLS 选项在创建 transport 时已生效，verify / cert 属性仅为兼容 apply_target_options"""
+
+    def __init__(self, base_url, transport, request_event):
+        self.base_url = base_url
+        self.headers = {}
+        self.cookies = httpx.Cookies()
+        self.auth = None
+        self.verify = True
+        self.cert = None
+        self._client = httpx.Client(transport=transport)
+        self._request_event = request_event
+
+    def get(self, path, **kwargs):
+        return self.request("GET", path, **kwargs)
+
+    def request(self, method, path, name=None, catch_response=False, headers=None, data=None,
+                timeout=None, allow_redirects=True):
+        url = path if "://" in path else self.base_url.rstrip("/") + "/" + path.lstrip("/")
+        start = time.time()
+        try:
+            response = self._client.request(
+                method, url, headers={**self.headers, **(headers or {})}, content=data,
+                cookies=self.cookies, auth=self.auth, timeout=timeout, follow_redirects=allow_redirects,
+            )
+            self.cookies.update(response.cookies)
+            error = None if response.status_code < 400 else Exception("HTTP {}".format(response.status_code))
+        except Exception as e:
+            response, error = None, e
+        result = HTTP2Response(response, self._request_event, method, name or path, start, error)
+        if catch_response:
+            return result
+        result.fire()
+        return result
+
+
+class HTTP2Response:
+    """包装 httpx 响应；catch_response 时作为上下文管理器，由 success / failure 决定上报结果"""
+
+    def __init__(self, response, request_event, method, name, start, error):
+        self._response = response
+        self._request_event = request_event
+        self._method = method
+        self._name = name
+        self._start = start
+        self._error = error
+        self.status_code = response.status_code if response is not None else 0
+        self.text = response.text if response is not None else ""
+        self.headers = response.headers if response is not None else {}
+        self.elapsed = datetime.timedelta(seconds=time.time() - start)
+
+    def json(self):
+        return json.loads(self.text)
+
+    def success(self):
+        self._error = None
+
+    def failure(self, message):
+        self._error = Exception(message)
+
+    def fire(self):
+        self._request_event.fire(
+            request_type=self._method, name=self._name, response_time=self.elapsed.total_seconds() * 1000,
+            response_length=len(self._response.content) if self._response is not None else 0,
+            response=self._response, context={}, exception=self._error,
+        )
+
+    def __enter__(self):
+        return self
+
+    def __exit__(self, exc_type, exc, tb):
+        if exc is not None:
+            self._error = exc
+        self.fire()
+        return False
+
+
+class Dataset:
+    """参数化数据集：sequential 循环取行，random 随机取行，unique 每个虚拟用户独占一行"""
+
+    def __init__(self, path, mode):
+        with open(path, newline="", encoding="utf-8") as f:
+            self.rows = list(csv.DictReader(f))
+        self.mode = mode or "sequential"
+        self._lock = threading.Lock()
+        self._cycle = itertools.cycle(self.rows) if self.rows else None
+        self._next_unique = 0
+
+    def for_user(self):
+        """unique 模式下为新虚拟用户分配一行，行已用尽时返回 None"""
+        with self._lock:
+            if self._next_unique >= len(self.rows):
+                return None
+            row = self.rows[self._next_unique]
+            self._next_unique += 1
+            return row
+
+    def for_iteration(self):
+        if not self.rows:
+            return {}
+        if self.mode == "random":
+            return random.choice(self.rows)
+        with self._lock:
+            return next(self._cycle)
+
+
+DATASET = None
+if CONFIG.get("dataset"):
+    DATASET = Dataset(CONFIG["dataset"]["file"], CONFIG["dataset"].get("mode"))
+
+_var_pattern = re.compile(r"\$\{([A-Za-z0-9_.-]+)\}")
+
+
+def render(template, variables):
+    """替换 ${name} 变量，未定义的变量保持原样"""
+    if not template:
+        return template
+    return _var_pattern.sub(lambda m: str(variables.get(m.group(1), m.group(0))), template)
+
+
+def json_path_get(obj, path):
+    """按 a.b.0.c 形式取 JSON 值，允许 $. 前缀；路径不存在时抛出 KeyError"""
+    if path.startswith("$."):
+        path = path[2:]
+    for part in filter(None, path.split(".")):
+        if isinstance(obj, list):
+            obj = obj[int(part)]
+        else:
+            obj = obj[part]
+    return obj
+
+
+def describe_check(check):
+    if check.get("path"):
+        return "{} {} == {}".format(check["type"], check["path"], check.get("value", ""))
+    return "{} {}".format(check["type"], check.get("value", ""))
+
+
+def run_check(check, response):
+    """返回 (是否通过, 失败原因)"""
+    kind, expected = check["type"], check.get("value", "")
+    try:
+        if kind == "status":
+            return response.status_code == int(expected), "status {}".format(response.status_code)
+        if kind == "body_contains":
+            return expected in response.text, "body 不包含 {!r}".format(expected)
+        if kind == "body_regex":
+            return re.search(expected, response.text) is not None, "body 不匹配 {!r}".format(expected)
+        if kind == "json_path":
+            actual = json_path_get(response.json(), check.get("path", ""))
+            ok = actual == expected if isinstance(actual, str) else json.dumps(actual) == expected
+            return ok, "{} = {!r}".format(check.get("path"), actual)
+        if kind == "max_latency":
+            elapsed = response.elapsed.total_seconds() * 1000
+            return elapsed <= float(expected), "耗时 {:.0f}ms".format(elapsed)
+    except Exception as e:
+        return False, "{}: {}".format(kind, e)
+    return False, "未知断言类型 {}".format(kind)
+
+
+def run_extractor(extractor, response):
+    """从响应中提取值，未找到时抛出异常"""
+    kind, expr = extractor["type"], extractor["expression"]
+    if kind == "json_path":
+        value = json_path_get(response.json(), expr)
+        return value if isinstance(value, str) else json.dumps(value)
+    if kind == "regex":
+        m = re.search(expr, response.text)
+        if m is None:
+            raise ValueError("正则 {!r} 未匹配".format(expr))
+        return m.group(1) if m.groups() else m.group(0)
+    if kind == "header":
+        if expr not in response.headers:
+            raise KeyError("响应头 {} 不存在".format(expr))
+        return response.headers[expr]
+    raise ValueError("未知提取器类型 {}".format(kind))
+
+
+class WSMessage:
+    """把收到的 WebSocket 消息包装成类似响应的对象，复用断言与提取器"""
+
+    def __init__(self, text):
+        self.text = text
+        self.headers = {}
+
+    def json(self):
+        return json.loads(self.text)
+
+
+class CheckStats:
+    """按 (步骤, 断言) 汇总通过 / 失败次数，退出时写给 Go 端"""
+
+    def __init__(self):
+        self.counts = {}
+        self._lock = threading.Lock()
+
+    def record(self, step, check, passed):
+        key = (step, describe_check(check))
+        with self._lock:
+            entry = self.counts.setdefault(key, {"step": key[0], "check": key[1], "passes": 0, "fails": 0})
+            entry["passes" if passed else "fails"] += 1
+
+    def drain(self):
+        """取出并清空已汇总的计数，worker 上报给 master 时使用"""
+        with self._lock:
+            entries, self.counts = list(self.counts.values()), {}
+        return entries
+
+    def merge(self, entries):
+        with self._lock:
+            for e in entries:
+                entry = self.counts.setdefault((e["step"], e["check"]), {"step": e["step"], "check": e["check"], "passes": 0, "fails": 0})
+                entry["passes"] += e["passes"]
+                entry["fails"] += e["fails"]
+
+    def dump(self, path):
+        with open(path, "w", encoding="utf-8") as f:
+            json.dump(list(self.counts.values()), f, ensure_ascii=False)
+
+
+check_stats = CheckStats()
+
+
+def ws_connect_url(host, path):
+    """相对路径按压测目标地址换成 ws:// 或 wss://"""
+    if path.startswith(("ws://", "wss://")):
+        return path
+    base = host.replace("https://", "wss://", 1).replace("http://", "ws://", 1)
+    return base.rstrip("/") + "/" + path.lstrip("/")
+
+
+def grpc_target(host):
+    """压测目标地址转换为 gRPC 目标：https:// 或 grpcs:// 使用 TLS，其余明文"""
+    scheme, _, rest = host.partition("://")
+    if not rest:
+        scheme, rest = "http", host
+    target = rest.split("/", 1)[0]
+    secure = scheme in ("https", "grpcs")
+    if ":" not in target:
+        target += ":443" if secure else ":80"
+    return target, secure
+
+
+def read_file(path):
+    if not path:
+        return None
+    with open(path, "rb") as f:
+        return f.read()
+
+
+def grpc_message_class(desc):
+    try:
+        from google.protobuf.message_factory import GetMessageClass
+    except ImportError:
+        # protobuf 4.22 之前的版本
+        from google.protobuf import message_factory
+        return message_factory.MessageFactory(desc.file.pool).GetPrototype(desc)
+    return GetMessageClass(desc)
+
+
+class GRPCClient:
+    """按方法全名动态构造调用，消息描述来自上传的 FileDescriptorSet 或服务端反射"""
+
+    def __init__(self, host, config):
+        target, secure = grpc_target(host)
+        if secure:
+            tls = config.get("tls") or {}
+            credentials = grpc.ssl_channel_credentials(
+                root_certificates=read_file(tls.get("ca_file")),
+                private_key=read_file(tls.get("key_file")),
+                certificate_chain=read_file(tls.get("cert_file")),
+            )
+            self.channel = grpc.secure_channel(target, credentials)
+        else:
+            self.channel = grpc.insecure_channel(target)
+
+        descriptor_file = (config.get("grpc") or {}).get("descriptor_file")
+        if descriptor_file:
+            self.pool = descriptor_pool.DescriptorPool()
+            files = descriptor_pb2.FileDescriptorSet()
+            files.ParseFromString(read_file(descriptor_file))
+            # Go 端已按依赖顺序排列
+            for fd in files.file:
+                self.pool.Add(fd)
+        else:
+            from grpc_reflection.v1alpha.proto_reflection_descriptor_database import ProtoReflectionDescriptorDatabase
+            self.pool = descriptor_pool.DescriptorPool(ProtoReflectionDescriptorDatabase(self.channel))
+        self.methods = {}
+
+    def method(self, path):
+        """返回 (调用对象, 请求消息类, 是否客户端流, 是否服务端流)"""
+        if path not in self.methods:
+            service, _, name = path.strip("/").rpartition("/")
+            desc = self.pool.FindServiceByName(service).methods_by_name[name]
+            proto = descriptor_pb2.MethodDescriptorProto()
+            desc.CopyToProto(proto)
+            request_cls = grpc_message_class(desc.input_type)
+            response_cls = grpc_message_class(desc.output_type)
+            factory = {
+                (False, False): self.channel.unary_unary,
+                (False, True): self.channel.unary_stream,
+                (True, False): self.channel.stream_unary,
+                (True, True): self.channel.stream_stream,
+            }[(proto.client_streaming, proto.server_streaming)]
+            call = factory(path, request_serializer=request_cls.SerializeToString,
+                           response_deserializer=response_cls.FromString)
+            self.methods[path] = (call, request_cls, proto.client_streaming, proto.server_streaming)
+        return self.methods[path]
+
+    def invoke(self, path, body, metadata, timeout):
+        """发起调用并返回 GRPCResponse；调用失败时抛出 grpc.RpcError"""
+        call, request_cls, client_streaming, server_streaming = self.method(path)
+        payload = json.loads(body) if body else {}
+        if client_streaming:
+            # 客户端流：JSON 数组中的每个元素作为一条消息发送
+            items = payload if isinstance(payload, list) else [payload]
+            request = iter([json_format.ParseDict(item, request_cls()) for item in items])
+        else:
+            request = json_format.ParseDict(payload, request_cls())
+
+        start = time.time()
+        if server_streaming:
+            stream = call(request, metadata=metadata, timeout=timeout)
+            messages = list(stream)
+            size = sum(m.ByteSize() for m in messages)
+            result = [json_format.MessageToDict(m) for m in messages]
+            trailing = stream.trailing_metadata()
+        else:
+            message, state = call.with_call(request, metadata=metadata, timeout=timeout)
+            size = message.ByteSize()
+            result = json_format.MessageToDict(message)
+            trailing = state.trailing_metadata()
+        return GRPCResponse(grpc.StatusCode.OK, result, trailing, time.time() - start, size)
+
+    def close(self):
+        self.channel.close()
+
+
+class GRPCResponse:
+    """把 gRPC 调用结果包装成类似响应的对象，复用断言与提取器：
+    status_code 为数值状态码，响应体为消息的 JSON（服务端流为 JSON 数组），响应头为 trailing metadata"""
+
+    def __init__(self, code, payload, metadata, elapsed, size=0):
+        self.code = code
+        self.status_code = code.value[0]
+        self.payload = payload
+        self.text = json.dumps(payload, ensure_ascii=False)
+        self.headers = dict(metadata or ())
+        self.elapsed = datetime.timedelta(seconds=elapsed)
+        self.size = size
+
+    def json(self):
+        return self.payload
+
+
+class GRPCStats:
+    """按方法汇总状态码分布，退出时写给 Go 端"""
+
+    def __init__(self):
+        self.counts = {}
+        self._lock = threading.Lock()
+
+    def record(self, method, code):
+        with self._lock:
+            codes = self.counts.setdefault(method, {})
+            codes[code] = codes.get(code, 0) + 1
+
+    def drain(self):
+        with self._lock:
+            counts, self.counts = self.counts, {}
+        return counts
+
+    def merge(self, counts):
+        with self._lock:
+            for method, codes in counts.items():
+                merged = self.counts.setdefault(method, {})
+                for code, n in codes.items():
+                    merged[code] = merged.get(code, 0) + n
+
+    def dump(self, path):
+        with open(path, "w", encoding="utf-8") as f:
+            json.dump([{"method": m, "status_codes": c} for m, c in self.counts.items()], f)
+
+
+grpc_stats = GRPCStats()
+
+
+class WSStats:
+    """按事件名汇总 WebSocket 次数、失败与耗时，退出时写给 Go 端。
+    WS 事件不走 events.request，以免近乎 0 毫秒的收发事件混入 HTTP 的 Aggregated 行"""
+
+    def __init__(self):
+        self.entries = {}
+        self._lock = threading.Lock()
+
+    def record(self, name, response_time, exception=None):
+        with self._lock:
+            e = self.entries.setdefault(name, {"requests": 0, "failures": 0, "total_time": 0.0})
+            e["requests"] += 1
+            if exception is not None:
+                e["failures"] += 1
+            else:
+                e["total_time"] += response_time
+
+    def drain(self):
+        with self._lock:
+            entries, self.entries = self.entries, {}
+        return entries
+
+    def merge(self, entries):
+        with self._lock:
+            for name, e in entries.items():
+                merged = self.entries.setdefault(name, {"requests": 0, "failures": 0, "total_time": 0.0})
+                for k in merged:
+                    merged[k] += e.get(k, 0)
+
+    def dump(self, path):
+        with open(path, "w", encoding="utf-8") as f:
+            json.dump([dict(name=n, **e) for n, e in self.entries.items()], f)
+
+
+ws_stats = WSStats()
+
+
 class WebsiteUser(HttpUser):
     wait_time = between(1, 2.5)
 
+    def on_start(self):
+        apply_transport_options(self)
+        apply_target_options(self.client, CONFIG)
+        self.variables = {}
+        self.ws = None
+        self.ws_last_send = None
+        self.grpc = None
+        if DATASET and DATASET.mode == "unique":
+            row = DATASET.for_user()
+            if row is None:
+                # 数据行不足，多出来的虚拟用户不参与压测
+                raise StopUser()
+            self.variables.update(row)
+
     @task
-    def index(self):
-        self.client.get("/")
+    def scenario(self):
+        steps = CONFIG.get("steps")
+        if not steps:
+            self.client.get("/", **request_options())
+            return
+
+        if DATASET and DATASET.mode != "unique":
+            self.variables.update(DATASET.for_iteration())
+        for step in steps:
+            self.run_step(step)
+            if step.get("think_time"):
+                time.sleep(step["think_time"] / 1000.0)
+
+    def on_stop(self):
+        if self.ws is not None:
+            self.ws.close()
+            self.ws = None
+        if self.grpc is not None:
+            self.grpc.close()
+            self.grpc = None
+
+    def fire_ws(self, name, start, exception=None):
+        """记录 WS 事件，Go 端按名称汇总连接耗时、往返耗时、消息速率和失败率"""
+        ws_stats.record(name, (time.time() - start) * 1000, exception)
+
+    def run_step(self, step):
+        kind = step.get("type") or "http"
+        if kind == "grpc":
+            return self.run_grpc_step(step)
+        if kind != "http":
+            return self.run_ws_step(kind, step)
+        path = render(step["path"], self.variables)
+        headers = {k: render(v, self.variables) for k, v in (step.get("headers") or {}).items()}
+        body = render(step.get("body"), self.variables)
+        # 以步骤名汇总统计，避免带变量的 URL 产生大量不同条目
+        name = step.get("name") or step["path"]
+        checks = step.get("checks") or []
+        extractors = step.get("extractors") or []
+        if not checks and not extractors:
+            return self.client.request(step["method"], path, headers=headers, data=body, name=name,
+                                       **request_options())
+
+        with self.client.request(step["method"], path, headers=headers, data=body, name=name,
+                                 catch_response=True, **request_options()) as response:
+            failures = []
+            for check in checks:
+                passed, reason = run_check(check, response)
+                check_stats.record(name, check, passed)
+                if not passed:
+                    failures.append(reason)
+            # 提取的变量写入当前虚拟用户，供后续步骤引用
+            for extractor in extractors:
+                try:
+                    self.variables[extractor["variable"]] = run_extractor(extractor, response)
+                except Exception as e:
+                    failures.append("提取 {} 失败: {}".format(extractor["variable"], e))
+            # 任一断言或提取失败即记为失败请求，200 的错误页不再算成功
+            if failures:
+                response.failure("; ".join(failures))
+            else:
+                response.success()
+            return response
+
+    def run_grpc_step(self, step):
+        if grpc is None:
+            raise RuntimeError("gRPC 步骤需要安装 grpcio")
+        if self.grpc is None:
+            self.grpc = GRPCClient(self.host, CONFIG)
+
+        # 以方法全名汇总统计，Go 端据此得到各方法的延迟分布
+        method = step["path"]
+        name = step.get("name") or method
+        metadata = [(k.lower(), render(v, self.variables)) for k, v in (step.get("headers") or {}).items()]
+        body = render(step.get("body"), self.variables)
+        timeout = step["timeout"] / 1000.0 if step.get("timeout") else None
+        checks = step.get("checks") or []
+        extractors = step.get("extractors") or []
+
+        failures = []
+        start = time.time()
+        try:
+            response = self.grpc.invoke(method, body, metadata, timeout)
+        except grpc.RpcError as e:
+            response = GRPCResponse(e.code(), {"details": e.details()}, e.trailing_metadata(), time.time() - start)
+            # 配置了断言时由断言决定成败（如期望 NOT_FOUND），否则非 OK 即失败
+            if not checks:
+                failures.append("{}: {}".format(e.code().name, e.details()))
+        except Exception as e:
+            # 方法不存在、请求 JSON 无效等客户端错误
+            grpc_stats.record(method, "CLIENT_ERROR")
+            events.request.fire(request_type="GRPC", name=method, response_time=(time.time() - start) * 1000,
+                                response_length=0, response=None, context={}, exception=e)
+            return
+        grpc_stats.record(method, response.code.name)
+
+        for check in checks:
+            passed, reason = run_check(check, response)
+            check_stats.record(name, check, passed)
+            if not passed:
+                failures.append(reason)
+        for extractor in extractors:
+            try:
+                self.variables[extractor["variable"]] = run_extractor(extractor, response)
+            except Exception as e:
+                failures.append("提取 {} 失败: {}".format(extractor["variable"], e))
+        events.request.fire(
+            request_type="GRPC", name=method, response_time=response.elapsed.total_seconds() * 1000,
+            response_length=response.size, response=None, context={},
+            exception=Exception("; ".join(failures)) if failures else None,
+        )
+
+    def run_ws_step(self, kind, step):
+        if websocket is None:
+            raise RuntimeError("WebSocket 步骤需要安装 websocket-client")
+
+        timeout = (step.get("timeout") or 5000) / 1000.0
+        if kind == "ws_connect":
+            url = ws_connect_url(self.host, render(step["path"], self.variables))
+            headers = ["{}: {}".format(k, render(v, self.variables)) for k, v in (step.get("headers") or {}).items()]
+            sslopt = {}
+            tls = CONFIG.get("tls") or {}
+            if tls.get("skip_verify"):
+                sslopt = {"cert_reqs": ssl.CERT_NONE}
+            start = time.time()
+            self.ws_last_send = None
+            try:
+                self.ws = websocket.create_connection(url, header=headers, timeout=timeout, sslopt=sslopt)
+                self.fire_ws("connect", start)
+            except Exception as e:
+                self.ws = None
+                self.fire_ws("connect", start, exception=e)
+            return
+
+        if self.ws is None:
+            # 连接失败或尚未连接时跳过后续 WebSocket 步骤
+            return
+
+        if kind == "ws_send":
+            message = render(step.get("body") or "", self.variables)
+            start = time.time()
+            try:
+                self.ws.send(message)
+                self.ws_last_send = time.time()
+                self.fire_ws("send", start)
+            except Exception as e:
+                self.fire_ws("send", start, exception=e)
+        elif kind == "ws_expect":
+            expected = render(step.get("body") or "", self.variables)
+            # 往返时间从上一次发送算起；该发送只计入这一次等待，无论收到、超时还是出错都随即清空，
+            # 之后没有新发送的 ws_expect 从自身开始等待时计时
+            start = self.ws_last_send or time.time()
+            self.ws_last_send = None
+            deadline = time.time() + timeout
+            while True:
+                remaining = deadline - time.time()
+                if remaining <= 0:
+                    self.fire_ws("roundtrip", start, exception=TimeoutError("未收到包含 {!r} 的消息".format(expected)))
+                    return
+                self.ws.settimeout(remaining)
+                try:
+                    message = self.ws.recv()
+                except websocket.WebSocketTimeoutException:
+                    continue
+                except Exception as e:
+                    self.fire_ws("roundtrip", start, exception=e)
+                    return
+                self.fire_ws("receive", time.time())
+                if expected in message:
+                    # 与 HTTP 步骤一致，任一提取失败即记为失败的往返
+                    failures = []
+                    for extractor in step.get("extractors") or []:
+                        try:
+                            self.variables[extractor["variable"]] = run_extractor(extractor, WSMessage(message))
+                        except Exception as e:
+                            failures.append("提取 {} 失败: {}".format(extractor["variable"], e))
+                    exception = RuntimeError("; ".join(failures)) if failures else None
+                    self.fire_ws("roundtrip", start, exception=exception)
+                    return
+        elif kind == "ws_hold":
+            # 保持连接并持续接收服务端推送
+            deadline = time.time() + timeout
+            while time.time() < deadline:
+                self.ws.settimeout(max(deadline - time.time(), 0.01))
+                try:
+                    message = self.ws.recv()
+                    self.fire_ws("receive", time.time())
+                except websocket.WebSocketTimeoutException:
+                    break
+                except Exception:
+                    break
+        elif kind == "ws_close":
+            self.ws.close()
+            self.ws = None
+
 
 # 收集自定义指标
 class MetricsCollector:
@@ -75,6 +822,68 @@ collector = MetricsCollector()
 def on_request(**kwargs):
     collector.on_request(**kwargs)
 
+def dump_histograms(stats, path):
+    """按端点导出 Locust 的响应时间分布（取整毫秒 -> 次数），Go 端转换为可合并的直方图"""
+    entries = [("", stats.total)] + [(e.method, e) for e in stats.entries.values()]
+    out = []
+    for kind, entry in entries:
+        if not entry.num_requests:
+            continue
+        out.append({
+            "type": kind or "",
+            "name": entry.name,
+            "response_times": {str(ms): n for ms, n in entry.response_times.items()},
+            "min": entry.min_response_time or 0,
+            "max": entry.max_response_time,
+            "sum": entry.total_response_time,
+        })
+    with open(path, "w", encoding="utf-8") as f:
+        json.dump(out, f, ensure_ascii=False)
+
+# 写入各进程 CPU 使用率的间隔，与 Locust 自身采样间隔一致
+CPU_REPORT_INTERVAL = 5
+
+def dump_cpu_usage(environment, path):
+    """定期写出本进程（单机或 master）与各 worker 的 CPU 使用率，Go 端据此判断负载生成器是否饱和"""
+    runner = environment.runner
+    while True:
+        gevent.sleep(CPU_REPORT_INTERVAL)
+        processes = [{"name": "master" if isinstance(runner, MasterRunner) else "local",
+                      "cpu": runner.current_cpu_usage}]
+        if isinstance(runner, MasterRunner):
+            for worker in list(runner.clients.values()):
+                processes.append({"name": "worker " + worker.id, "cpu": worker.cpu_usage})
+        tmp = path + ".tmp"
+        with open(tmp, "w", encoding="utf-8") as f:
+            json.dump(processes, f)
+        os.replace(tmp, path)
+
+@events.init.add_listener
+def on_init(environment, **kwargs):
+    if CONFIG.get("cpu_output") and not isinstance(environment.runner, WorkerRunner):
+        gevent.spawn(dump_cpu_usage, environment, CONFIG["cpu_output"])
+
+# 分布式运行时 worker 随统计报告把断言、gRPC 状态码与 WebSocket 计数发给 master 汇总
+@events.report_to_master.add_listener
+def on_report_to_master(client_id, data, **kwargs):
+    data["check_stats"] = check_stats.drain()
+    data["grpc_stats"] = grpc_stats.drain()
+    data["ws_stats"] = ws_stats.drain()
+
+@events.worker_report.add_listener
+def on_worker_report(client_id, data, **kwargs):
+    check_stats.merge(data.get("check_stats") or [])
+    grpc_stats.merge(data.get("grpc_stats") or {})
+    ws_stats.merge(data.get("ws_stats") or {})
+
 @events.quitting.add_listener
 def on_quit(environment, **kwargs):
     collector.stop()
+    if CONFIG.get("checks_output"):
+        check_stats.dump(CONFIG["checks_output"])
+    if (CONFIG.get("grpc") or {}).get("status_output"):
+        grpc_stats.dump(CONFIG["grpc"]["status_output"])
+    if CONFIG.get("ws_output"):
+        ws_stats.dump(CONFIG["ws_output"])
+    if CONFIG.get("histogram_output"):
+        dump_histograms(environment.stats, CONFIG["histogram_output"])
@@ -1,19 +1,63 @@
 package main
 
 import (
+	"flag"
 	"log"
+	"os"
+	"runtime"
+	"strings"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	_ "github.com/go-sql-driver/mysql"
 
+	"loadtest_project/agent"
 	"loadtest_project/config"
+	"loadtest_project/mockoidc"
 	"loadtest_project/models"
 	"loadtest_project/routes"
 	"loadtest_project/scheduler"
+	"loadtest_project/services"
+	"loadtest_project/utils"
 )
 
 func main() {
+	hostname, _ := os.Hostname()
+	agentMode := flag.Bool("agent", false, "以 worker agent 模式运行，不启动 API 服务")
+	server := flag.String("server", "http://127.0.0.1:8080", "agent 模式：API 服务器地址")
+	name := flag.String("name", hostname, "agent 模式：agent 名称")
+	python := flag.String("python", services.PythonExec, "agent 模式：Locust 所在 Python 解释器")
+	workDir := flag.String("workdir", "agent_work", "agent 模式：运行文件存放目录")
+	// Locust 每个 worker 进程只用一个核，默认按每核 500 个虚拟用户估算
+	maxUsers := flag.Int("max-users", runtime.NumCPU()*500, "agent 模式：可同时承担的虚拟用户数上限")
+	mockOIDC := flag.String("mock-oidc", "", "以本地 mock OIDC 身份提供方模式运行，值为其 issuer URL，如 http://127.0.0.1:9000")
+	mockOIDCClient := flag.String("mock-oidc-client", "loadtest", "mock OIDC：接受的 client_id")
+	mockOIDCUser := flag.String("mock-oidc-user", "alice", "mock OIDC：默认登录用户，授权请求可用 login_hint 覆盖")
+	mockOIDCGroups := flag.String("mock-oidc-groups", "loadtest-users", "mock OIDC：用户所在的组，逗号分隔")
+	flag.Parse()
+
+	if *mockOIDC != "" {
+		err := mockoidc.Run(mockoidc.Options{
+			Issuer:   *mockOIDC,
+			ClientID: *mockOIDCClient,
+			Username: *mockOIDCUser,
+			Groups:   strings.Split(*mockOIDCGroups, ","),
+		})
+		log.Fatal("mock OIDC 退出:", err)
+	}
+
+	if *agentMode {
+		err := agent.Run(agent.Options{
+			Server:   *server,
+			Name:     *name,
+			Token:    os.Getenv(config.AgentTokenEnv),
+			Python:   *python,
+			WorkDir:  *workDir,
+			MaxUsers: *maxUsers,
+		})
+		log.Fatal("agent 退出:", err)
+	}
+
 	// 1. 连接数据库
 	config.ConnectDB()
 	models.DB = config.DB
@@ -23,6 +67,41 @@ func main() {
 		log.Fatal("建表失败:", err)
 	}
 
+	// 加载登录 token 的签名密钥；缺少时拒绝启动
+	if err := config.LoadJWTKey(); err != nil {
+		log.Fatal("JWT 签名密钥不可用:", err)
+	}
+	utils.SetJWTKey(config.JWTKey)
+
+	// 加载 secrets 主密钥；未配置时 secrets 功能不可用
+	if err := config.LoadMasterKey(); err != nil {
+		log.Println("secrets 主密钥不可用:", err)
+	}
+	// 早期明文保存的目标凭据转为加密 secret
+	if err := services.MigrateTargetCredentials(); err != nil {
+		log.Println("迁移目标凭据失败:", err)
+	}
+	// 加载 runner 结果上传令牌的签名密钥
+	if err := config.LoadRunTokenKey(); err != nil {
+		log.Println("runner 上传令牌:", err)
+	}
+	// 加载 OIDC 单点登录配置；未配置时只能用本地账号登录
+	if err := config.LoadOIDCConfig(); err != nil {
+		log.Println("OIDC 单点登录不可用:", err)
+	} else {
+		services.OIDC = services.NewOIDCProvider(config.OIDC)
+	}
+	// 加载 LDAP 登录配置；未配置时只用本地账号登录
+	if err := config.LoadLDAPConfig(); err != nil {
+		log.Println("LDAP 登录不可用:", err)
+	} else {
+		services.LDAP = services.NewLDAPAuthenticator(config.LDAP)
+	}
+	// 加载 agent 共享令牌；未配置时不接受 worker agent
+	if err := config.LoadAgentToken(); err != nil {
+		log.Println("worker agent 不可用:", err)
+	}
+
 	// 3. 启动调度器
 	go scheduler.StartScheduler()
 
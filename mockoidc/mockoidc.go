@@ -0,0 +1,230 @@
+// Package mockoidc 本地开发与联调用的 OIDC 身份提供方：不校验密码，
+// 授权请求直接以配置的用户登录并跳回客户端，支持授权码 + PKCE（S256）。不要用于生产环境
+package mockoidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Options mock 身份提供方的配置
+type Options struct {
+	Issuer   string   // 如 http://127.0.0.1:9000，监听地址取自其中的 host
+	ClientID string   // 只接受该客户端
+	Username string   // 默认登录用户，授权请求可用 login_hint 覆盖
+	Groups   []string // ID Token 中的 groups
+}
+
+// codeTTL 授权码有效期
+const codeTTL = time.Minute
+
+type authCode struct {
+	redirectURI string
+	challenge   string
+	nonce       string
+	username    string
+	expiresAt   time.Time
+}
+
+type provider struct {
+	opts Options
+	key  *rsa.PrivateKey
+	kid  string
+
+	mu    sync.Mutex
+	codes map[string]authCode
+}
+
+// Run 启动 mock 身份提供方，阻塞直到出错
+func Run(opts Options) error {
+	u, err := url.Parse(opts.Issuer)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("无效的 issuer %q", opts.Issuer)
+	}
+	h, err := NewHandler(opts)
+	if err != nil {
+		return err
+	}
+	log.Printf("mock OIDC 身份提供方启动于 %s，客户端 %s，用户 %s，组 %v", opts.Issuer, opts.ClientID, opts.Username, opts.Groups)
+	return http.ListenAndServe(u.Host, h)
+}
+
+// NewHandler 返回 mock 身份提供方的各端点，供 Run 或测试中的 httptest 服务器使用；端点路径取自 issuer
+func NewHandler(opts Options) (http.Handler, error) {
+	u, err := url.Parse(opts.Issuer)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("无效的 issuer %q", opts.Issuer)
+	}
+	opts.Issuer = strings.TrimRight(opts.Issuer, "/")
+	path := strings.TrimRight(u.Path, "/")
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	p := &provider{opts: opts, key: key, kid: randomString(8), codes: map[string]authCode{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(path+"/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc(path+"/jwks", p.jwks)
+	mux.HandleFunc(path+"/authorize", p.authorize)
+	mux.HandleFunc(path+"/token", p.token)
+	return mux, nil
+}
+
+func randomString(n int) string {
+	buf := make([]byte, n)
+	rand.Read(buf)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func tokenError(w http.ResponseWriter, code, desc string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code, "error_description": desc})
+}
+
+func (p *provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.opts.Issuer,
+		"authorization_endpoint":                p.opts.Issuer + "/authorize",
+		"token_endpoint":                        p.opts.Issuer + "/token",
+		"jwks_uri":                              p.opts.Issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *provider) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := p.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": p.kid,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// authorize 不显示登录页，直接签发授权码并跳回 redirect_uri
+func (p *provider) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	redirectURI, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || redirectURI.Host == "" {
+		http.Error(w, "无效的 redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if q.Get("client_id") != p.opts.ClientID {
+		http.Error(w, "未知的 client_id", http.StatusBadRequest)
+		return
+	}
+	back := redirectURI.Query()
+	back.Set("state", q.Get("state"))
+	switch {
+	case q.Get("response_type") != "code":
+		back.Set("error", "unsupported_response_type")
+	case q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256":
+		back.Set("error", "invalid_request")
+		back.Set("error_description", "需要 S256 方式的 PKCE")
+	default:
+		username := p.opts.Username
+		if hint := q.Get("login_hint"); hint != "" {
+			username = hint
+		}
+		code := randomString(24)
+		p.mu.Lock()
+		p.codes[code] = authCode{
+			redirectURI: q.Get("redirect_uri"),
+			challenge:   q.Get("code_challenge"),
+			nonce:       q.Get("nonce"),
+			username:    username,
+			expiresAt:   time.Now().Add(codeTTL),
+		}
+		p.mu.Unlock()
+		back.Set("code", code)
+	}
+	redirectURI.RawQuery = back.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (p *provider) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "只支持 POST", http.StatusMethodNotAllowed)
+		return
+	}
+	r.ParseForm()
+	clientID := r.PostForm.Get("client_id")
+	if id, _, ok := r.BasicAuth(); ok {
+		clientID, _ = url.QueryUnescape(id)
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, "unsupported_grant_type", "")
+		return
+	}
+	if clientID != p.opts.ClientID {
+		tokenError(w, "invalid_client", "")
+		return
+	}
+
+	// 授权码只能使用一次
+	p.mu.Lock()
+	code, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+	if !ok || time.Now().After(code.expiresAt) || code.redirectURI != r.PostForm.Get("redirect_uri") {
+		tokenError(w, "invalid_grant", "授权码无效或已过期")
+		return
+	}
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != code.challenge {
+		tokenError(w, "invalid_grant", "code_verifier 不匹配")
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":                p.opts.Issuer,
+		"sub":                "mock|" + code.username,
+		"aud":                p.opts.ClientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"nonce":              code.nonce,
+		"preferred_username": code.username,
+		"email":              code.username + "@example.com",
+		"groups":             p.opts.Groups,
+	}
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tok.Header["kid"] = p.kid
+	idToken, err := tok.SignedString(p.key)
+	if err != nil {
+		tokenError(w, "server_error", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(24),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
@@ -0,0 +1,41 @@
+package models
+
+// AbortCondition 运行中实时评估的中止条件：错误率或 p99 持续超标 WindowSeconds 秒即中止
+type AbortCondition struct {
+	TestID        int     `json:"test_id"`
+	MaxErrorRate  float64 `json:"max_error_rate"` // 0~1，0 表示不检查
+	MaxP99        float64 `json:"max_p99"`        // 毫秒，0 表示不检查
+	WindowSeconds int     `json:"window_seconds"` // 持续超标多少秒后中止，0 表示立即中止
+}
+
+var abortTables = []string{
+	`CREATE TABLE IF NOT EXISTS abort_conditions (
+		test_id INT PRIMARY KEY,
+		max_error_rate DOUBLE NOT NULL DEFAULT 0,
+		max_p99 DOUBLE NOT NULL DEFAULT 0,
+		window_seconds INT NOT NULL DEFAULT 0,
+		FOREIGN KEY (test_id) REFERENCES load_tests(id)
+	);`,
+}
+
+func CreateAbortCondition(a *AbortCondition) error {
+	_, err := DB.Exec(
+		"INSERT INTO abort_conditions(test_id, max_error_rate, max_p99, window_seconds) VALUES(?,?,?,?)",
+		a.TestID, a.MaxErrorRate, a.MaxP99, a.WindowSeconds,
+	)
+	return err
+}
+
+func GetAbortCondition(testID int) (AbortCondition, error) {
+	var a AbortCondition
+	err := DB.QueryRow(
+		"SELECT test_id, max_error_rate, max_p99, window_seconds FROM abort_conditions WHERE test_id=?", testID,
+	).Scan(&a.TestID, &a.MaxErrorRate, &a.MaxP99, &a.WindowSeconds)
+	return a, err
+}
+
+// AbortLoadTest 将任务标记为 aborted 并记录中止原因
+func AbortLoadTest(id int, reason string) error {
+	_, err := DB.Exec("UPDATE load_tests SET status='aborted', abort_reason=? WHERE id=?", reason, id)
+	return err
+}
@@ -0,0 +1,143 @@
+package models
+
+import (
+	"time"
+)
+
+// Agent 以 agent 模式运行的 worker 节点，资源数据随心跳更新
+type Agent struct {
+	ID         int       `json:"id"`
+	Name       string    `json:"name"`
+	Hostname   string    `json:"hostname"`
+	CPUCores   int       `json:"cpu_cores"`
+	CPUPercent float64   `json:"cpu_percent"`
+	MemPercent float64   `json:"mem_percent"`
+	MaxUsers   int       `json:"max_users"` // 可承担的虚拟用户数上限，0 表示不限制
+	LastSeen   time.Time `json:"last_seen"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// DistributedOptions 分布式压测参数：Workers 为参与压测的 worker 数量，
+// 服务器以 Locust master 模式运行并汇总各 worker 的统计
+type DistributedOptions struct {
+	TestID  int `json:"test_id"`
+	Workers int `json:"workers"`
+}
+
+var agentTables = []string{
+	`CREATE TABLE IF NOT EXISTS agents (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL UNIQUE,
+		hostname VARCHAR(255) NOT NULL,
+		cpu_cores INT NOT NULL DEFAULT 0,
+		cpu_percent DOUBLE NOT NULL DEFAULT 0,
+		mem_percent DOUBLE NOT NULL DEFAULT 0,
+		max_users INT NOT NULL DEFAULT 0,
+		credential_hash VARCHAR(64) NOT NULL DEFAULT '',
+		last_seen DATETIME NOT NULL,
+		created_at DATETIME NOT NULL
+	);`,
+	`CREATE TABLE IF NOT EXISTS distributed_options (
+		test_id INT PRIMARY KEY,
+		workers INT NOT NULL,
+		FOREIGN KEY (test_id) REFERENCES load_tests(id)
+	);`,
+}
+
+const agentColumns = "id, name, hostname, cpu_cores, cpu_percent, mem_percent, max_users, last_seen, created_at"
+
+func scanAgent(row interface{ Scan(...interface{}) error }, a *Agent) error {
+	return row.Scan(&a.ID, &a.Name, &a.Hostname, &a.CPUCores, &a.CPUPercent, &a.MemPercent, &a.MaxUsers, &a.LastSeen, &a.CreatedAt)
+}
+
+// RegisterAgent 按名称注册 agent，已存在时更新主机信息与心跳时间；
+// credentialHash 为本次签发的 agent 凭据的哈希，替换之前的凭据
+func RegisterAgent(a *Agent, credentialHash string) error {
+	now := time.Now()
+	_, err := DB.Exec(
+		`INSERT INTO agents(name, hostname, cpu_cores, max_users, credential_hash, last_seen, created_at) VALUES(?,?,?,?,?,?,?)
+		 ON DUPLICATE KEY UPDATE hostname=VALUES(hostname), cpu_cores=VALUES(cpu_cores),
+		     max_users=VALUES(max_users), credential_hash=VALUES(credential_hash), last_seen=VALUES(last_seen)`,
+		a.Name, a.Hostname, a.CPUCores, a.MaxUsers, credentialHash, now, now,
+	)
+	if err != nil {
+		return err
+	}
+	return scanAgent(DB.QueryRow("SELECT "+agentColumns+" FROM agents WHERE name=?", a.Name), a)
+}
+
+// GetAgentByName 按名称查询 agent 及其凭据哈希
+func GetAgentByName(name string) (Agent, string, error) {
+	var (
+		a    Agent
+		hash string
+	)
+	err := DB.QueryRow("SELECT "+agentColumns+", credential_hash FROM agents WHERE name=?", name).Scan(
+		&a.ID, &a.Name, &a.Hostname, &a.CPUCores, &a.CPUPercent, &a.MemPercent, &a.MaxUsers, &a.LastSeen, &a.CreatedAt, &hash,
+	)
+	return a, hash, err
+}
+
+// GetAgentCredentialHash 查询 agent 当前凭据的哈希
+func GetAgentCredentialHash(id int) (string, error) {
+	var hash string
+	err := DB.QueryRow("SELECT credential_hash FROM agents WHERE id=?", id).Scan(&hash)
+	return hash, err
+}
+
+// UpdateAgentStats 记录心跳上报的资源使用率
+func UpdateAgentStats(id int, cpuPercent, memPercent float64) error {
+	_, err := DB.Exec(
+		"UPDATE agents SET cpu_percent=?, mem_percent=? WHERE id=?", cpuPercent, memPercent, id,
+	)
+	return err
+}
+
+// TouchAgent 记录 agent 心跳
+func TouchAgent(id int) error {
+	res, err := DB.Exec("UPDATE agents SET last_seen=? WHERE id=?", time.Now(), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		// 时间精度为秒，同一秒内重复心跳也会返回 0，再确认一次是否存在
+		var exists int
+		return DB.QueryRow("SELECT id FROM agents WHERE id=?", id).Scan(&exists)
+	}
+	return nil
+}
+
+// ListAgents 列出全部 agent；since 非零时只返回该时间之后有心跳的
+func ListAgents(since time.Time) ([]Agent, error) {
+	rows, err := DB.Query("SELECT "+agentColumns+" FROM agents WHERE last_seen >= ? ORDER BY id", since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Agent
+	for rows.Next() {
+		var a Agent
+		if err := scanAgent(rows, &a); err != nil {
+			continue
+		}
+		list = append(list, a)
+	}
+	return list, nil
+}
+
+func CreateDistributedOptions(o *DistributedOptions) error {
+	_, err := DB.Exec(
+		"INSERT INTO distributed_options(test_id, workers) VALUES(?,?)",
+		o.TestID, o.Workers,
+	)
+	return err
+}
+
+func GetDistributedOptions(testID int) (DistributedOptions, error) {
+	var o DistributedOptions
+	err := DB.QueryRow(
+		"SELECT test_id, workers FROM distributed_options WHERE test_id=?", testID,
+	).Scan(&o.TestID, &o.Workers)
+	return o, err
+}
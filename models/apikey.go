@@ -0,0 +1,150 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// API 令牌的权限范围
+const (
+	ScopeRead    = "read"    // 查询任务、结果、报告与日志（GET 接口）
+	ScopeWrite   = "write"   // 提交任务、上传数据集等修改操作
+	ScopeApprove = "approve" // 审批 / 拒绝任务，仍需项目中的 approver 角色
+)
+
+// ValidScope 是否为合法的权限范围
+func ValidScope(scope string) bool {
+	return scope == ScopeRead || scope == ScopeWrite || scope == ScopeApprove
+}
+
+// APIKey 供 CI 等非交互场景使用的长期令牌，只保存哈希；
+// 请求时按所属用户当前的角色鉴权，并受 Scopes 限制
+type APIKey struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // 令牌开头几位，便于识别
+	TokenHash  string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// HasScope 令牌是否包含指定权限范围
+func (k APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+var apiKeyTables = []string{
+	`CREATE TABLE IF NOT EXISTS api_keys (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		user_id INT NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		prefix VARCHAR(20) NOT NULL,
+		token_hash CHAR(64) NOT NULL UNIQUE,
+		scopes VARCHAR(255) NOT NULL,
+		expires_at DATETIME NULL,
+		last_used_at DATETIME NULL,
+		revoked_at DATETIME NULL,
+		created_at DATETIME NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	);`,
+}
+
+const apiKeyColumns = "id, user_id, name, prefix, token_hash, scopes, expires_at, last_used_at, revoked_at, created_at"
+
+func scanAPIKey(row interface{ Scan(...interface{}) error }, k *APIKey) error {
+	var (
+		scopes                     string
+		expires, lastUsed, revoked sql.NullTime
+	)
+	if err := row.Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.TokenHash, &scopes, &expires, &lastUsed, &revoked, &k.CreatedAt); err != nil {
+		return err
+	}
+	k.Scopes = strings.Split(scopes, ",")
+	if expires.Valid {
+		k.ExpiresAt = &expires.Time
+	}
+	if lastUsed.Valid {
+		k.LastUsedAt = &lastUsed.Time
+	}
+	if revoked.Valid {
+		k.RevokedAt = &revoked.Time
+	}
+	return nil
+}
+
+func CreateAPIKey(k *APIKey) error {
+	k.CreatedAt = time.Now()
+	res, err := DB.Exec(
+		"INSERT INTO api_keys(user_id, name, prefix, token_hash, scopes, expires_at, created_at) VALUES(?,?,?,?,?,?,?)",
+		k.UserID, k.Name, k.Prefix, k.TokenHash, strings.Join(k.Scopes, ","), k.ExpiresAt, k.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if id, err := res.LastInsertId(); err == nil {
+		k.ID = int(id)
+	}
+	return nil
+}
+
+// GetAPIKeyByHash 按令牌哈希查找，不检查是否过期或吊销
+func GetAPIKeyByHash(hash string) (APIKey, error) {
+	var k APIKey
+	err := scanAPIKey(DB.QueryRow("SELECT "+apiKeyColumns+" FROM api_keys WHERE token_hash=?", hash), &k)
+	return k, err
+}
+
+// ListAPIKeys 列出用户的全部令牌（含已吊销、已过期的）
+func ListAPIKeys(userID int) ([]APIKey, error) {
+	rows, err := DB.Query("SELECT "+apiKeyColumns+" FROM api_keys WHERE user_id=? ORDER BY id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []APIKey
+	for rows.Next() {
+		var k APIKey
+		if err := scanAPIKey(rows, &k); err != nil {
+			continue
+		}
+		list = append(list, k)
+	}
+	return list, nil
+}
+
+// TouchAPIKey 记录令牌最近一次使用时间
+func TouchAPIKey(id int) error {
+	_, err := DB.Exec("UPDATE api_keys SET last_used_at=? WHERE id=?", time.Now(), id)
+	return err
+}
+
+// RevokeAPIKey 吊销用户的令牌，返回是否存在且此前未吊销
+func RevokeAPIKey(userID, id int) (bool, error) {
+	res, err := DB.Exec(
+		"UPDATE api_keys SET revoked_at=? WHERE id=? AND user_id=? AND revoked_at IS NULL",
+		time.Now(), id, userID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// GetUserRole 查询用户当前的系统角色
+func GetUserRole(userID int) (string, error) {
+	var role string
+	err := DB.QueryRow("SELECT role FROM users WHERE id=?", userID).Scan(&role)
+	return role, err
+}
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// CapacityConfig 容量探测参数：从 StartUsers 开始，每阶段增加 StepUsers，
+// 直到 p95 或错误率超出阈值，或达到 MaxUsers
+type CapacityConfig struct {
+	TestID       int     `json:"test_id"`
+	StartUsers   int     `json:"start_users"`
+	StepUsers    int     `json:"step_users"`
+	StepDuration int     `json:"step_duration"` // 每阶段持续秒数
+	MaxUsers     int     `json:"max_users"`
+	MaxP95       float64 `json:"max_p95"`        // p95 上限（毫秒），0 表示不限制
+	MaxErrorRate float64 `json:"max_error_rate"` // 错误率上限（0~1），0 表示不限制
+}
+
+// CapacityStep 容量曲线上的一个点
+type CapacityStep struct {
+	Users     int     `json:"users"`
+	RPS       float64 `json:"rps"`
+	P95       float64 `json:"p95"`
+	ErrorRate float64 `json:"error_rate"`
+	Passed    bool    `json:"passed"`
+}
+
+// CapacityResult 容量探测结论：最大可持续负载及得出结论的曲线
+type CapacityResult struct {
+	ID            int            `json:"id"`
+	TestID        int            `json:"test_id"`
+	MaxUsers      int            `json:"max_users"`      // 最后一个满足 SLO 的并发数
+	MaxRPS        float64        `json:"max_rps"`        // 对应的 RPS
+	BreakingUsers int            `json:"breaking_users"` // 首次违反 SLO 的并发数，0 表示未违反
+	StopReason    string         `json:"stop_reason"`
+	Curve         []CapacityStep `json:"curve"`
+	CreatedAt     time.Time      `json:"created_at"`
+}
+
+var capacityTables = []string{
+	`CREATE TABLE IF NOT EXISTS capacity_configs (
+		test_id INT PRIMARY KEY,
+		start_users INT NOT NULL,
+		step_users INT NOT NULL,
+		step_duration INT NOT NULL,
+		max_users INT NOT NULL,
+		max_p95 DOUBLE NOT NULL DEFAULT 0,
+		max_error_rate DOUBLE NOT NULL DEFAULT 0,
+		FOREIGN KEY (test_id) REFERENCES load_tests(id)
+	);`,
+	`CREATE TABLE IF NOT EXISTS capacity_results (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		test_id INT NOT NULL,
+		max_users INT NOT NULL,
+		max_rps DOUBLE NOT NULL,
+		breaking_users INT NOT NULL DEFAULT 0,
+		stop_reason VARCHAR(255) NOT NULL DEFAULT '',
+		curve TEXT,
+		created_at DATETIME NOT NULL,
+		FOREIGN KEY (test_id) REFERENCES load_tests(id)
+	);`,
+}
+
+func CreateCapacityConfig(c *CapacityConfig) error {
+	_, err := DB.Exec(
+		"INSERT INTO capacity_configs(test_id, start_users, step_users, step_duration, max_users, max_p95, max_error_rate) VALUES(?,?,?,?,?,?,?)",
+		c.TestID, c.StartUsers, c.StepUsers, c.StepDuration, c.MaxUsers, c.MaxP95, c.MaxErrorRate,
+	)
+	return err
+}
+
+func GetCapacityConfig(testID int) (CapacityConfig, error) {
+	var c CapacityConfig
+	err := DB.QueryRow(
+		"SELECT test_id, start_users, step_users, step_duration, max_users, max_p95, max_error_rate FROM capacity_configs WHERE test_id=?", testID,
+	).Scan(&c.TestID, &c.StartUsers, &c.StepUsers, &c.StepDuration, &c.MaxUsers, &c.MaxP95, &c.MaxErrorRate)
+	return c, err
+}
+
+func CreateCapacityResult(r *CapacityResult) error {
+	curve, err := json.Marshal(r.Curve)
+	if err != nil {
+		return err
+	}
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = time.Now()
+	}
+	res, err := DB.Exec(
+		"INSERT INTO capacity_results(test_id, max_users, max_rps, breaking_users, stop_reason, curve, created_at) VALUES(?,?,?,?,?,?,?)",
+		r.TestID, r.MaxUsers, r.MaxRPS, r.BreakingUsers, r.StopReason, string(curve), r.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if id, err := res.LastInsertId(); err == nil {
+		r.ID = int(id)
+	}
+	return nil
+}
+
+// GetCapacityResult 返回任务最近一次容量探测结论
+func GetCapacityResult(testID int) (CapacityResult, error) {
+	var (
+		r     CapacityResult
+		curve string
+	)
+	err := DB.QueryRow(
+		`SELECT id, test_id, max_users, max_rps, breaking_users, stop_reason, curve, created_at
+		   FROM capacity_results WHERE test_id=? ORDER BY id DESC LIMIT 1`, testID,
+	).Scan(&r.ID, &r.TestID, &r.MaxUsers, &r.MaxRPS, &r.BreakingUsers, &r.StopReason, &curve, &r.CreatedAt)
+	if err != nil {
+		return r, err
+	}
+	if curve != "" {
+		if err := json.Unmarshal([]byte(curve), &r.Curve); err != nil {
+			return r, err
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,60 @@
+package models
+
+// CheckResult 一次运行中某个步骤断言的通过 / 失败次数
+type CheckResult struct {
+	ID       int    `json:"id"`
+	TestID   int    `json:"test_id"`
+	ResultID int    `json:"result_id"`
+	Step     string `json:"step"`
+	Check    string `json:"check"`
+	Passes   int    `json:"passes"`
+	Fails    int    `json:"fails"`
+}
+
+var checkTables = []string{
+	`CREATE TABLE IF NOT EXISTS check_results (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		test_id INT NOT NULL,
+		result_id INT NOT NULL,
+		step VARCHAR(255) NOT NULL,
+		check_desc VARCHAR(512) NOT NULL,
+		passes INT NOT NULL,
+		fails INT NOT NULL,
+		FOREIGN KEY (test_id) REFERENCES load_tests(id)
+	);`,
+}
+
+func CreateCheckResult(r *CheckResult) error {
+	res, err := DB.Exec(
+		"INSERT INTO check_results(test_id, result_id, step, check_desc, passes, fails) VALUES(?,?,?,?,?,?)",
+		r.TestID, r.ResultID, r.Step, r.Check, r.Passes, r.Fails,
+	)
+	if err != nil {
+		return err
+	}
+	if id, err := res.LastInsertId(); err == nil {
+		r.ID = int(id)
+	}
+	return nil
+}
+
+// GetCheckResults 返回某次结果的全部断言统计
+func GetCheckResults(resultID int) ([]CheckResult, error) {
+	rows, err := DB.Query(
+		"SELECT id, test_id, result_id, step, check_desc, passes, fails FROM check_results WHERE result_id=? ORDER BY id", resultID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []CheckResult
+	for rows.Next() {
+		var r CheckResult
+		if err := rows.Scan(&r.ID, &r.TestID, &r.ResultID, &r.Step, &r.Check, &r.Passes, &r.Fails); err != nil {
+			continue
+		}
+		list = append(list, r)
+	}
+	return list, nil
+}
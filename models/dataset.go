@@ -0,0 +1,128 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 数据集分配方式
+const (
+	DatasetSequential = "sequential" // 按顺序循环取行
+	DatasetRandom     = "random"     // 每次迭代随机取行
+	DatasetUnique     = "unique"     // 每个虚拟用户独占一行，行数不足时多余用户停止
+)
+
+// Dataset 用户上传的 CSV 数据集
+type Dataset struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	Name      string    `json:"name"`
+	FilePath  string    `json:"-"`
+	Columns   []string  `json:"columns"`
+	RowCount  int       `json:"row_count"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TestDataset 任务与数据集的关联
+type TestDataset struct {
+	TestID    int    `json:"test_id"`
+	DatasetID int    `json:"dataset_id"`
+	Mode      string `json:"mode"`
+}
+
+var datasetTables = []string{
+	`CREATE TABLE IF NOT EXISTS datasets (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		user_id INT NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		file_path VARCHAR(512) NOT NULL,
+		columns TEXT NOT NULL,
+		row_count INT NOT NULL,
+		created_at DATETIME NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	);`,
+	`CREATE TABLE IF NOT EXISTS test_datasets (
+		test_id INT PRIMARY KEY,
+		dataset_id INT NOT NULL,
+		mode VARCHAR(20) NOT NULL DEFAULT 'sequential',
+		FOREIGN KEY (test_id) REFERENCES load_tests(id),
+		FOREIGN KEY (dataset_id) REFERENCES datasets(id)
+	);`,
+}
+
+func CreateDataset(d *Dataset) error {
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = time.Now()
+	}
+	columns, err := json.Marshal(d.Columns)
+	if err != nil {
+		return err
+	}
+	res, err := DB.Exec(
+		"INSERT INTO datasets(user_id, name, file_path, columns, row_count, created_at) VALUES(?,?,?,?,?,?)",
+		d.UserID, d.Name, d.FilePath, string(columns), d.RowCount, d.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if id, err := res.LastInsertId(); err == nil {
+		d.ID = int(id)
+	}
+	return nil
+}
+
+func GetDatasetByID(id int) (Dataset, error) {
+	var (
+		d       Dataset
+		columns string
+	)
+	err := DB.QueryRow(
+		"SELECT id, user_id, name, file_path, columns, row_count, created_at FROM datasets WHERE id=?", id,
+	).Scan(&d.ID, &d.UserID, &d.Name, &d.FilePath, &columns, &d.RowCount, &d.CreatedAt)
+	if err != nil {
+		return d, err
+	}
+	err = json.Unmarshal([]byte(columns), &d.Columns)
+	return d, err
+}
+
+// ListDatasets 列出用户上传的数据集
+func ListDatasets(userID int) ([]Dataset, error) {
+	rows, err := DB.Query(
+		"SELECT id, user_id, name, columns, row_count, created_at FROM datasets WHERE user_id=? ORDER BY id DESC", userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Dataset
+	for rows.Next() {
+		var (
+			d       Dataset
+			columns string
+		)
+		if err := rows.Scan(&d.ID, &d.UserID, &d.Name, &columns, &d.RowCount, &d.CreatedAt); err != nil {
+			continue
+		}
+		json.Unmarshal([]byte(columns), &d.Columns)
+		list = append(list, d)
+	}
+	return list, nil
+}
+
+func CreateTestDataset(t *TestDataset) error {
+	_, err := DB.Exec(
+		"INSERT INTO test_datasets(test_id, dataset_id, mode) VALUES(?,?,?)",
+		t.TestID, t.DatasetID, t.Mode,
+	)
+	return err
+}
+
+func GetTestDataset(testID int) (TestDataset, error) {
+	var t TestDataset
+	err := DB.QueryRow(
+		"SELECT test_id, dataset_id, mode FROM test_datasets WHERE test_id=?", testID,
+	).Scan(&t.TestID, &t.DatasetID, &t.Mode)
+	return t, err
+}
@@ -0,0 +1,201 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// GRPCMethod .proto 中定义的一个 RPC 方法
+type GRPCMethod struct {
+	Name            string `json:"name"` // /package.Service/Method
+	ClientStreaming bool   `json:"client_streaming"`
+	ServerStreaming bool   `json:"server_streaming"`
+}
+
+// ProtoDescriptor 用户上传的 .proto 文件编译后的 FileDescriptorSet
+type ProtoDescriptor struct {
+	ID        int          `json:"id"`
+	UserID    int          `json:"user_id"`
+	Name      string       `json:"name"`
+	FilePath  string       `json:"-"`
+	Methods   []GRPCMethod `json:"methods"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// GRPCOptions 任务的 gRPC 消息描述来源，DescriptorID 为 0 时通过服务端反射获取
+type GRPCOptions struct {
+	TestID       int `json:"test_id"`
+	DescriptorID int `json:"descriptor_id"`
+}
+
+// GRPCMethodResult 一次运行中单个 gRPC 方法的延迟与状态码分布
+type GRPCMethodResult struct {
+	ID              int            `json:"id"`
+	TestID          int            `json:"test_id"`
+	ResultID        int            `json:"result_id"`
+	Method          string         `json:"method"`
+	Requests        int            `json:"requests"`
+	Failures        int            `json:"failures"`
+	AvgResponseTime float64        `json:"avg_response_time"`
+	P50ResponseTime float64        `json:"p50_response_time"`
+	P95ResponseTime float64        `json:"p95_response_time"`
+	P99ResponseTime float64        `json:"p99_response_time"`
+	StatusCodes     map[string]int `json:"status_codes"` // 状态码名称（OK、UNAVAILABLE 等）-> 次数
+}
+
+var grpcTables = []string{
+	`CREATE TABLE IF NOT EXISTS proto_descriptors (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		user_id INT NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		file_path VARCHAR(512) NOT NULL,
+		methods TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	);`,
+	`CREATE TABLE IF NOT EXISTS grpc_options (
+		test_id INT PRIMARY KEY,
+		descriptor_id INT NOT NULL DEFAULT 0,
+		FOREIGN KEY (test_id) REFERENCES load_tests(id)
+	);`,
+	`CREATE TABLE IF NOT EXISTS grpc_method_results (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		test_id INT NOT NULL,
+		result_id INT NOT NULL,
+		method VARCHAR(255) NOT NULL,
+		requests INT NOT NULL,
+		failures INT NOT NULL,
+		avg_response_time DOUBLE NOT NULL,
+		p50_response_time DOUBLE NOT NULL,
+		p95_response_time DOUBLE NOT NULL,
+		p99_response_time DOUBLE NOT NULL,
+		status_codes TEXT NOT NULL,
+		FOREIGN KEY (test_id) REFERENCES load_tests(id)
+	);`,
+}
+
+func CreateProtoDescriptor(d *ProtoDescriptor) error {
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = time.Now()
+	}
+	methods, err := json.Marshal(d.Methods)
+	if err != nil {
+		return err
+	}
+	res, err := DB.Exec(
+		"INSERT INTO proto_descriptors(user_id, name, file_path, methods, created_at) VALUES(?,?,?,?,?)",
+		d.UserID, d.Name, d.FilePath, string(methods), d.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if id, err := res.LastInsertId(); err == nil {
+		d.ID = int(id)
+	}
+	return nil
+}
+
+func GetProtoDescriptorByID(id int) (ProtoDescriptor, error) {
+	var (
+		d       ProtoDescriptor
+		methods string
+	)
+	err := DB.QueryRow(
+		"SELECT id, user_id, name, file_path, methods, created_at FROM proto_descriptors WHERE id=?", id,
+	).Scan(&d.ID, &d.UserID, &d.Name, &d.FilePath, &methods, &d.CreatedAt)
+	if err != nil {
+		return d, err
+	}
+	err = json.Unmarshal([]byte(methods), &d.Methods)
+	return d, err
+}
+
+// ListProtoDescriptors 列出用户上传的 .proto 描述
+func ListProtoDescriptors(userID int) ([]ProtoDescriptor, error) {
+	rows, err := DB.Query(
+		"SELECT id, user_id, name, methods, created_at FROM proto_descriptors WHERE user_id=? ORDER BY id DESC", userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []ProtoDescriptor
+	for rows.Next() {
+		var (
+			d       ProtoDescriptor
+			methods string
+		)
+		if err := rows.Scan(&d.ID, &d.UserID, &d.Name, &methods, &d.CreatedAt); err != nil {
+			continue
+		}
+		json.Unmarshal([]byte(methods), &d.Methods)
+		list = append(list, d)
+	}
+	return list, nil
+}
+
+func CreateGRPCOptions(o *GRPCOptions) error {
+	_, err := DB.Exec(
+		"INSERT INTO grpc_options(test_id, descriptor_id) VALUES(?,?)",
+		o.TestID, o.DescriptorID,
+	)
+	return err
+}
+
+func GetGRPCOptions(testID int) (GRPCOptions, error) {
+	var o GRPCOptions
+	err := DB.QueryRow(
+		"SELECT test_id, descriptor_id FROM grpc_options WHERE test_id=?", testID,
+	).Scan(&o.TestID, &o.DescriptorID)
+	return o, err
+}
+
+func CreateGRPCMethodResult(r *GRPCMethodResult) error {
+	codes, err := json.Marshal(r.StatusCodes)
+	if err != nil {
+		return err
+	}
+	res, err := DB.Exec(
+		`INSERT INTO grpc_method_results(test_id, result_id, method, requests, failures,
+			avg_response_time, p50_response_time, p95_response_time, p99_response_time, status_codes)
+		 VALUES(?,?,?,?,?,?,?,?,?,?)`,
+		r.TestID, r.ResultID, r.Method, r.Requests, r.Failures,
+		r.AvgResponseTime, r.P50ResponseTime, r.P95ResponseTime, r.P99ResponseTime, string(codes),
+	)
+	if err != nil {
+		return err
+	}
+	if id, err := res.LastInsertId(); err == nil {
+		r.ID = int(id)
+	}
+	return nil
+}
+
+// GetGRPCMethodResults 返回某次结果的各 gRPC 方法统计
+func GetGRPCMethodResults(resultID int) ([]GRPCMethodResult, error) {
+	rows, err := DB.Query(
+		`SELECT id, test_id, result_id, method, requests, failures,
+			avg_response_time, p50_response_time, p95_response_time, p99_response_time, status_codes
+		   FROM grpc_method_results WHERE result_id=? ORDER BY id`, resultID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []GRPCMethodResult
+	for rows.Next() {
+		var (
+			r     GRPCMethodResult
+			codes string
+		)
+		if err := rows.Scan(&r.ID, &r.TestID, &r.ResultID, &r.Method, &r.Requests, &r.Failures,
+			&r.AvgResponseTime, &r.P50ResponseTime, &r.P95ResponseTime, &r.P99ResponseTime, &codes); err != nil {
+			continue
+		}
+		json.Unmarshal([]byte(codes), &r.StatusCodes)
+		list = append(list, r)
+	}
+	return list, nil
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"encoding/json"
+
+	"loadtest_project/utils"
+)
+
+// HistogramAggregated 整次运行汇总直方图使用的端点名称
+const HistogramAggregated = "Aggregated"
+
+// LatencyHistogram 一次运行中某个端点（或整体）的延迟直方图
+type LatencyHistogram struct {
+	ID        int              `json:"id"`
+	TestID    int              `json:"test_id"`
+	ResultID  int              `json:"result_id"`
+	Type      string           `json:"type"` // 请求方法或 WS / GRPC，汇总行为空
+	Name      string           `json:"name"`
+	Histogram *utils.Histogram `json:"histogram"`
+}
+
+var histogramTables = []string{
+	`CREATE TABLE IF NOT EXISTS latency_histograms (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		test_id INT NOT NULL,
+		result_id INT NOT NULL,
+		endpoint_type VARCHAR(20) NOT NULL DEFAULT '',
+		endpoint_name VARCHAR(512) NOT NULL,
+		histogram MEDIUMTEXT NOT NULL,
+		INDEX idx_latency_histograms_result (result_id),
+		FOREIGN KEY (test_id) REFERENCES load_tests(id)
+	);`,
+}
+
+func CreateLatencyHistogram(h *LatencyHistogram) error {
+	data, err := json.Marshal(h.Histogram)
+	if err != nil {
+		return err
+	}
+	res, err := DB.Exec(
+		"INSERT INTO latency_histograms(test_id, result_id, endpoint_type, endpoint_name, histogram) VALUES(?,?,?,?,?)",
+		h.TestID, h.ResultID, h.Type, h.Name, string(data),
+	)
+	if err != nil {
+		return err
+	}
+	if id, err := res.LastInsertId(); err == nil {
+		h.ID = int(id)
+	}
+	return nil
+}
+
+// GetLatencyHistograms 返回某次结果的全部直方图，汇总行在前
+func GetLatencyHistograms(resultID int) ([]LatencyHistogram, error) {
+	rows, err := DB.Query(
+		`SELECT id, test_id, result_id, endpoint_type, endpoint_name, histogram
+		   FROM latency_histograms WHERE result_id=? ORDER BY endpoint_name <> ?, id`,
+		resultID, HistogramAggregated,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []LatencyHistogram
+	for rows.Next() {
+		var (
+			h    LatencyHistogram
+			data string
+		)
+		if err := rows.Scan(&h.ID, &h.TestID, &h.ResultID, &h.Type, &h.Name, &data); err != nil {
+			continue
+		}
+		h.Histogram = utils.NewHistogram()
+		if err := json.Unmarshal([]byte(data), h.Histogram); err != nil {
+			continue
+		}
+		list = append(list, h)
+	}
+	return list, nil
+}
+
+// GetResultTestID 返回结果所属的任务
+func GetResultTestID(resultID int) (int, error) {
+	var testID int
+	err := DB.QueryRow("SELECT test_id FROM test_results WHERE id=?", resultID).Scan(&testID)
+	return testID, err
+}
@@ -0,0 +1,166 @@
+package models
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// externalPassword 外部身份源（OIDC、LDAP）用户的占位密码，不是合法的 bcrypt 哈希，无法用本地密码登录
+const externalPassword = "!"
+
+var identityTables = []string{
+	// 外部身份（provider + subject）与本地用户的对应关系；provisioned 表示账号由该身份源创建，只有这样的账号跟随身份源同步角色
+	`CREATE TABLE IF NOT EXISTS user_identities (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		user_id INT NOT NULL,
+		provider VARCHAR(255) NOT NULL,
+		subject VARCHAR(255) NOT NULL,
+		provisioned BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at DATETIME NOT NULL,
+		UNIQUE KEY uk_identity (provider, subject),
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	);`,
+	// 进行中的 OIDC 登录：state 只能使用一次，保存 PKCE code_verifier 与 nonce
+	`CREATE TABLE IF NOT EXISTS login_states (
+		state VARCHAR(64) PRIMARY KEY,
+		code_verifier VARCHAR(128) NOT NULL,
+		nonce VARCHAR(64) NOT NULL,
+		expires_at DATETIME NOT NULL
+	);`,
+}
+
+// LoginState 一次进行中的 OIDC 登录
+type LoginState struct {
+	State        string
+	CodeVerifier string
+	Nonce        string
+	ExpiresAt    time.Time
+}
+
+// CreateLoginState 保存登录状态，顺带清理过期的
+func CreateLoginState(s LoginState) error {
+	if _, err := DB.Exec("DELETE FROM login_states WHERE expires_at < ?", time.Now()); err != nil {
+		return err
+	}
+	_, err := DB.Exec(
+		"INSERT INTO login_states(state, code_verifier, nonce, expires_at) VALUES(?,?,?,?)",
+		s.State, s.CodeVerifier, s.Nonce, s.ExpiresAt,
+	)
+	return err
+}
+
+// ConsumeLoginState 取出并删除登录状态；不存在、已被使用或已过期时返回 sql.ErrNoRows
+func ConsumeLoginState(state string) (LoginState, error) {
+	var s LoginState
+	err := DB.QueryRow(
+		"SELECT state, code_verifier, nonce, expires_at FROM login_states WHERE state=?", state,
+	).Scan(&s.State, &s.CodeVerifier, &s.Nonce, &s.ExpiresAt)
+	if err != nil {
+		return s, err
+	}
+	res, err := DB.Exec("DELETE FROM login_states WHERE state=?", state)
+	if err != nil {
+		return s, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 || time.Now().After(s.ExpiresAt) {
+		return s, sql.ErrNoRows
+	}
+	return s, nil
+}
+
+// ProvisionExternalUser 返回外部身份对应的本地用户，首次登录时自动创建；由该身份源创建的账号每次登录按其组更新系统角色。
+// linkByUsername 为 true 时（身份源验证的就是该用户名，如 LDAP），首次登录关联到用户名完全一致、没有可用本地密码
+// 且尚未关联该身份源的已有账号（如先经 OIDC 创建的账号），保留其任务与项目，但不改动其角色；
+// 有本地密码的账号（引导管理员等）绝不会被关联。否则用户名已被占用时追加由 subject 派生的后缀，不会并入同名账号
+func ProvisionExternalUser(provider, subject, username, role string, linkByUsername bool) (int, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var userID int
+	var provisioned bool
+	err = tx.QueryRow(
+		"SELECT user_id, provisioned FROM user_identities WHERE provider=? AND subject=?", provider, subject,
+	).Scan(&userID, &provisioned)
+	switch {
+	case err == nil:
+		if provisioned {
+			if _, err := tx.Exec("UPDATE users SET role=? WHERE id=?", role, userID); err != nil {
+				return 0, err
+			}
+		}
+		return userID, tx.Commit()
+	case !errors.Is(err, sql.ErrNoRows):
+		return 0, err
+	}
+
+	if linkByUsername {
+		// MySQL 默认排序规则比较字符串不区分大小写，用 BINARY 精确匹配
+		err := tx.QueryRow(
+			`SELECT u.id FROM users u
+			  WHERE BINARY u.username = ? AND u.password = ?
+			    AND NOT EXISTS (SELECT 1 FROM user_identities i WHERE i.user_id = u.id AND i.provider = ?)`,
+			username, externalPassword, provider,
+		).Scan(&userID)
+		switch {
+		case err == nil:
+			if err := linkIdentity(tx, userID, provider, subject, false); err != nil {
+				return 0, err
+			}
+			return userID, tx.Commit()
+		case !errors.Is(err, sql.ErrNoRows):
+			return 0, err
+		}
+	}
+
+	sum := sha256.Sum256([]byte(provider + "|" + subject))
+	name := username
+	for _, candidate := range []string{username, username + "_" + hex.EncodeToString(sum[:4])} {
+		var exists bool
+		if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE username=?)", candidate).Scan(&exists); err != nil {
+			return 0, err
+		}
+		if name = candidate; !exists {
+			break
+		}
+	}
+	res, err := tx.Exec("INSERT INTO users(username, password, role) VALUES(?,?,?)", name, externalPassword, role)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	userID = int(id)
+	if err := linkIdentity(tx, userID, provider, subject, true); err != nil {
+		return 0, err
+	}
+	return userID, tx.Commit()
+}
+
+func linkIdentity(tx *sql.Tx, userID int, provider, subject string, provisioned bool) error {
+	_, err := tx.Exec(
+		"INSERT INTO user_identities(user_id, provider, subject, provisioned, created_at) VALUES(?,?,?,?,?)",
+		userID, provider, subject, provisioned, time.Now(),
+	)
+	return err
+}
+
+// markProvisionedIdentities 早期版本没有 provisioned 列：没有本地密码的账号视为由其最早关联的身份源创建
+func markProvisionedIdentities() error {
+	_, err := DB.Exec(
+		`UPDATE user_identities i
+		   JOIN users u ON u.id = i.user_id
+		   JOIN (SELECT user_id, MIN(id) AS first_id FROM user_identities GROUP BY user_id) f ON f.first_id = i.id
+		    SET i.provisioned = TRUE
+		  WHERE u.password = ? AND NOT i.provisioned`,
+		externalPassword,
+	)
+	return err
+}
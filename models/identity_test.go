@@ -0,0 +1,162 @@
+package models
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"loadtest_project/models/testdb"
+)
+
+type fakeUser struct {
+	id                       int
+	username, password, role string
+}
+
+type fakeIdentity struct {
+	userID            int
+	provider, subject string
+	provisioned       bool
+}
+
+// identityStore 只实现 ProvisionExternalUser 用到的语句；username 的比较与 MySQL 默认排序规则一样不区分大小写，BINARY 时区分
+type identityStore struct {
+	users      []*fakeUser
+	identities []fakeIdentity
+}
+
+func (s *identityStore) handle(query string, args []driver.Value) (testdb.Result, error) {
+	switch {
+	case strings.HasPrefix(query, "SELECT user_id, provisioned FROM user_identities"):
+		for _, i := range s.identities {
+			if i.provider == args[0] && i.subject == args[1] {
+				return testdb.Row([]string{"user_id", "provisioned"}, int64(i.userID), i.provisioned), nil
+			}
+		}
+	case strings.Contains(query, "BINARY u.username = ?"):
+		for _, u := range s.users {
+			if u.username == args[0] && u.password == args[1] && !s.linked(u.id, args[2].(string)) {
+				return testdb.Row([]string{"id"}, int64(u.id)), nil
+			}
+		}
+	case strings.HasPrefix(query, "SELECT EXISTS(SELECT 1 FROM users"):
+		exists := false
+		for _, u := range s.users {
+			exists = exists || strings.EqualFold(u.username, args[0].(string))
+		}
+		return testdb.Row([]string{"exists"}, exists), nil
+	case strings.HasPrefix(query, "UPDATE users SET role=?"):
+		for _, u := range s.users {
+			if int64(u.id) == args[1] {
+				u.role = args[0].(string)
+			}
+		}
+		return testdb.Result{RowsAffected: 1}, nil
+	case strings.HasPrefix(query, "INSERT INTO users"):
+		u := &fakeUser{id: len(s.users) + 1, username: args[0].(string), password: args[1].(string), role: args[2].(string)}
+		s.users = append(s.users, u)
+		return testdb.Result{LastInsertID: int64(u.id), RowsAffected: 1}, nil
+	case strings.HasPrefix(query, "INSERT INTO user_identities"):
+		s.identities = append(s.identities, fakeIdentity{
+			userID: int(args[0].(int64)), provider: args[1].(string), subject: args[2].(string), provisioned: args[3].(bool),
+		})
+		return testdb.Result{RowsAffected: 1}, nil
+	default:
+		return testdb.Result{}, unexpectedQuery(query)
+	}
+	return testdb.Result{}, nil
+}
+
+func (s *identityStore) linked(userID int, provider string) bool {
+	for _, i := range s.identities {
+		if i.userID == userID && i.provider == provider {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *identityStore) user(id int) *fakeUser {
+	for _, u := range s.users {
+		if u.id == id {
+			return u
+		}
+	}
+	return nil
+}
+
+type unexpectedQuery string
+
+func (q unexpectedQuery) Error() string { return "未预期的 SQL: " + string(q) }
+
+func useStore(t *testing.T, s *identityStore) {
+	orig := DB
+	DB = testdb.Open(t, s.handle)
+	t.Cleanup(func() { DB = orig })
+}
+
+const ldapProvider = "ldap:ldap://dir.example.com"
+
+func TestProvisionExternalUserDoesNotTakeOverLocalAccount(t *testing.T) {
+	s := &identityStore{users: []*fakeUser{{id: 1, username: "admin", password: "$2a$10$bootstrap", role: "admin"}}}
+	useStore(t, s)
+
+	for _, name := range []string{"Admin", "admin"} {
+		id, err := ProvisionExternalUser(ldapProvider, "uid="+strings.ToLower(name)+name, name, RoleViewer, true)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if id == 1 {
+			t.Errorf("目录用户 %q 不应关联到有本地密码的引导管理员", name)
+		}
+		if u := s.user(id); u == nil || u.password != externalPassword {
+			t.Errorf("目录用户 %q 应得到新建的外部账号，got %+v", name, u)
+		}
+	}
+	if admin := s.user(1); admin.role != "admin" {
+		t.Errorf("引导管理员的角色被改写为 %q", admin.role)
+	}
+}
+
+func TestProvisionExternalUserLinksAccountWithoutPassword(t *testing.T) {
+	// bob 先经 OIDC 登录创建，管理员随后把他设为 approver
+	s := &identityStore{
+		users:      []*fakeUser{{id: 1, username: "bob", password: externalPassword, role: "approver"}},
+		identities: []fakeIdentity{{userID: 1, provider: "oidc:https://idp", subject: "sub-bob", provisioned: true}},
+	}
+	useStore(t, s)
+
+	if id, err := ProvisionExternalUser(ldapProvider, "uid=bob", "Bob", RoleViewer, true); err != nil || id == 1 {
+		t.Errorf("用户名大小写不一致时不应关联，got id=%d err=%v", id, err)
+	}
+	id, err := ProvisionExternalUser(ldapProvider, "uid=bob,dc=example", "bob", RoleViewer, true)
+	if err != nil || id != 1 {
+		t.Fatalf("应关联到同名且没有本地密码的账号，got id=%d err=%v", id, err)
+	}
+	if role := s.user(1).role; role != "approver" {
+		t.Errorf("关联已有账号不应改动其角色，got %q", role)
+	}
+	// 再次登录：身份已关联但账号不是 LDAP 创建的，角色仍不变
+	if _, err := ProvisionExternalUser(ldapProvider, "uid=bob,dc=example", "bob", RoleViewer, true); err != nil {
+		t.Fatal(err)
+	}
+	if role := s.user(1).role; role != "approver" {
+		t.Errorf("非本身份源创建的账号不应跟随其组更新角色，got %q", role)
+	}
+}
+
+func TestProvisionExternalUserSyncsRoleOfProvisionedAccount(t *testing.T) {
+	s := &identityStore{}
+	useStore(t, s)
+
+	id, err := ProvisionExternalUser(ldapProvider, "uid=carol", "carol", RoleViewer, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ProvisionExternalUser(ldapProvider, "uid=carol", "carol", RoleApprover, true); err != nil {
+		t.Fatal(err)
+	}
+	if role := s.user(id).role; role != RoleApprover {
+		t.Errorf("由身份源创建的账号应按组更新角色，got %q", role)
+	}
+}
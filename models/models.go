@@ -2,12 +2,18 @@ package models
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 )
 
 var DB *sql.DB
 
+// execer DB 或事务，供需要在事务中复用的写入函数使用
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
 type User struct {
 	ID       int
 	Username string
@@ -15,15 +21,26 @@ type User struct {
 	Role     string
 }
 
+// 压测类型
+const (
+	TestTypeLoad     = "load"     // 固定并发压测
+	TestTypeCapacity = "capacity" // 容量探测（阶梯加压直到违反 SLO）
+)
+
 type LoadTest struct {
-	ID        int       `json:"id"`
-	UserID    int       `json:"user_id"`
-	NumUsers  int       `json:"num_users"`
-	RampUp    int       `json:"ramp_up"`
-	TargetURL string    `json:"target_url"`
-	StartTime time.Time `json:"start_time"`
-	EndTime   time.Time `json:"end_time"`
-	Status    string    `json:"status"`
+	ID          int       `json:"id"`
+	UserID      int       `json:"user_id"`
+	NumUsers    int       `json:"num_users"`
+	RampUp      int       `json:"ramp_up"`
+	TargetURL   string    `json:"target_url"`
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+	Status      string    `json:"status"`
+	TestType    string    `json:"test_type"`
+	ProjectID   int       `json:"project_id"`
+	AbortReason string    `json:"abort_reason"`
+	// FailureReason 运行失败时的原因摘要，详细日志见对应的运行记录
+	FailureReason string `json:"failure_reason"`
 }
 
 type TestResult struct {
@@ -45,6 +62,20 @@ type TestResult struct {
 	TTFB                float64 `json:"ttfb"`
 	ContentDownloadTime float64 `json:"content_download_time"`
 	Availability        float64 `json:"availability"`
+	P50ResponseTime     float64 `json:"p50_response_time"`
+	P95ResponseTime     float64 `json:"p95_response_time"`
+	P99ResponseTime     float64 `json:"p99_response_time"`
+	CheckPassRate       float64 `json:"check_pass_rate"`
+	WSConnectTime       float64 `json:"ws_connect_time"`
+	WSRoundTripTime     float64 `json:"ws_round_trip_time"`
+	WSSendRate          float64 `json:"ws_send_rate"`
+	WSReceiveRate       float64 `json:"ws_receive_rate"`
+	// WSErrorRate WebSocket 连接、收发与期望消息失败的比例，不计入 HTTP 错误率
+	WSErrorRate float64 `json:"ws_error_rate"`
+	// Transport 运行时生效的传输层选项，便于复现
+	Transport *TransportOptions `json:"transport,omitempty"`
+	// GeneratorWarnings 运行期间负载生成器自身资源饱和的告警，存在时结果可能失真
+	GeneratorWarnings []string `json:"generator_warnings,omitempty"`
 }
 
 func CreateTables() error {
@@ -64,6 +95,10 @@ func CreateTables() error {
 			start_time DATETIME NOT NULL,
 			end_time DATETIME NOT NULL,
 			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			test_type VARCHAR(20) NOT NULL DEFAULT 'load',
+			project_id INT NOT NULL DEFAULT 0,
+			abort_reason VARCHAR(255) NOT NULL DEFAULT '',
+			failure_reason VARCHAR(1024) NOT NULL DEFAULT '',
 			FOREIGN KEY (user_id) REFERENCES users(id)
 		);`,
 		`CREATE TABLE IF NOT EXISTS test_results (
@@ -85,21 +120,122 @@ func CreateTables() error {
 			ttfb DOUBLE,
 			content_download_time DOUBLE,
 			availability DOUBLE,
+			p50_response_time DOUBLE,
+			p95_response_time DOUBLE,
+			p99_response_time DOUBLE,
+			check_pass_rate DOUBLE,
+			ws_connect_time DOUBLE,
+			ws_round_trip_time DOUBLE,
+			ws_send_rate DOUBLE,
+			ws_receive_rate DOUBLE,
+			ws_error_rate DOUBLE,
+			transport TEXT,
+			generator_warnings TEXT,
 			FOREIGN KEY (test_id) REFERENCES load_tests(id)
 		);`,
 	}
+	queries = append(queries, capacityTables...)
+	queries = append(queries, thresholdTables...)
+	queries = append(queries, abortTables...)
+	queries = append(queries, targetTables...)
+	queries = append(queries, secretTables...)
+	queries = append(queries, scenarioTables...)
+	queries = append(queries, datasetTables...)
+	queries = append(queries, checkTables...)
+	queries = append(queries, grpcTables...)
+	queries = append(queries, transportTables...)
+	queries = append(queries, agentTables...)
+	queries = append(queries, histogramTables...)
+	queries = append(queries, runTables...)
+	queries = append(queries, projectTables...)
+	queries = append(queries, apiKeyTables...)
+	queries = append(queries, sessionTables...)
+	queries = append(queries, identityTables...)
 	for _, q := range queries {
 		if _, err := DB.Exec(q); err != nil {
 			return fmt.Errorf("建表失败: %v", err)
 		}
 	}
+	// 旧库补列：CREATE TABLE IF NOT EXISTS 不会给已存在的表加新列
+	for _, col := range addedColumns {
+		if err := ensureColumn(col.table, col.column, col.definition); err != nil {
+			return fmt.Errorf("补充列 %s.%s 失败: %v", col.table, col.column, err)
+		}
+	}
+	if err := assignUnscopedTests(); err != nil {
+		return fmt.Errorf("归入个人项目失败: %v", err)
+	}
+	if err := demotePersonalSpaceOwners(); err != nil {
+		return fmt.Errorf("调整个人空间角色失败: %v", err)
+	}
+	if err := markProvisionedIdentities(); err != nil {
+		return fmt.Errorf("标记外部身份来源失败: %v", err)
+	}
 	return nil
 }
 
+// columnDef 描述一个在建表之后新增的列
+type columnDef struct {
+	table      string
+	column     string
+	definition string
+}
+
+// addedColumns 列出后续版本新增的列，启动时按需补齐
+var addedColumns = []columnDef{
+	{"load_tests", "test_type", "VARCHAR(20) NOT NULL DEFAULT 'load'"},
+	{"load_tests", "abort_reason", "VARCHAR(255) NOT NULL DEFAULT ''"},
+	{"test_results", "p50_response_time", "DOUBLE"},
+	{"test_results", "p95_response_time", "DOUBLE"},
+	{"test_results", "p99_response_time", "DOUBLE"},
+	{"test_results", "check_pass_rate", "DOUBLE"},
+	{"scenario_steps", "checks", "TEXT"},
+	{"scenario_steps", "extractors", "TEXT"},
+	{"scenario_steps", "type", "VARCHAR(20) NOT NULL DEFAULT 'http'"},
+	{"scenario_steps", "timeout", "INT NOT NULL DEFAULT 0"},
+	{"test_results", "ws_connect_time", "DOUBLE"},
+	{"test_results", "ws_round_trip_time", "DOUBLE"},
+	{"test_results", "ws_send_rate", "DOUBLE"},
+	{"test_results", "ws_receive_rate", "DOUBLE"},
+	{"test_results", "transport", "TEXT"},
+	{"test_results", "ws_error_rate", "DOUBLE"},
+	{"agents", "cpu_cores", "INT NOT NULL DEFAULT 0"},
+	{"agents", "cpu_percent", "DOUBLE NOT NULL DEFAULT 0"},
+	{"agents", "mem_percent", "DOUBLE NOT NULL DEFAULT 0"},
+	{"agents", "credential_hash", "VARCHAR(64) NOT NULL DEFAULT ''"},
+	{"agents", "max_users", "INT NOT NULL DEFAULT 0"},
+	{"test_results", "generator_warnings", "TEXT"},
+	{"load_tests", "failure_reason", "VARCHAR(1024) NOT NULL DEFAULT ''"},
+	{"load_tests", "project_id", "INT NOT NULL DEFAULT 0"},
+	{"runs", "result_uploaded", "BOOLEAN NOT NULL DEFAULT FALSE"},
+	{"user_identities", "provisioned", "BOOLEAN NOT NULL DEFAULT FALSE"},
+}
+
+// ensureColumn 若列不存在则执行 ALTER TABLE 添加
+func ensureColumn(table, column, definition string) error {
+	var count int
+	err := DB.QueryRow(
+		`SELECT COUNT(*) FROM information_schema.COLUMNS
+		  WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?`,
+		table, column,
+	).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	_, err = DB.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
 func CreateLoadTest(t *LoadTest) error {
+	if t.TestType == "" {
+		t.TestType = TestTypeLoad
+	}
 	res, err := DB.Exec(
-		"INSERT INTO load_tests(user_id, num_users, ramp_up, target_url, start_time, end_time, status) VALUES(?,?,?,?,?,?,?)",
-		t.UserID, t.NumUsers, t.RampUp, t.TargetURL, t.StartTime, t.EndTime, t.Status,
+		"INSERT INTO load_tests(user_id, project_id, num_users, ramp_up, target_url, start_time, end_time, status, test_type) VALUES(?,?,?,?,?,?,?,?,?)",
+		t.UserID, t.ProjectID, t.NumUsers, t.RampUp, t.TargetURL, t.StartTime, t.EndTime, t.Status, t.TestType,
 	)
 	if err != nil {
 		return err
@@ -116,10 +252,19 @@ func UpdateLoadTestStatus(id int, status string) error {
 	return err
 }
 
+// GetLoadTestByID 按 ID 查询单个任务
+func GetLoadTestByID(id int) (LoadTest, error) {
+	var t LoadTest
+	err := DB.QueryRow(
+		"SELECT id, user_id, project_id, num_users, ramp_up, target_url, start_time, end_time, status, test_type, abort_reason, failure_reason FROM load_tests WHERE id=?", id,
+	).Scan(&t.ID, &t.UserID, &t.ProjectID, &t.NumUsers, &t.RampUp, &t.TargetURL, &t.StartTime, &t.EndTime, &t.Status, &t.TestType, &t.AbortReason, &t.FailureReason)
+	return t, err
+}
+
 func GetApprovedTasksReadyToRun() ([]LoadTest, error) {
 	now := time.Now()
 	rows, err := DB.Query(
-		"SELECT id, user_id, num_users, ramp_up, target_url, start_time, end_time FROM load_tests WHERE status='approved' AND start_time <= ?", now,
+		"SELECT id, user_id, project_id, num_users, ramp_up, target_url, start_time, end_time, test_type FROM load_tests WHERE status='approved' AND start_time <= ?", now,
 	)
 	if err != nil {
 		return nil, err
@@ -129,7 +274,7 @@ func GetApprovedTasksReadyToRun() ([]LoadTest, error) {
 	var tasks []LoadTest
 	for rows.Next() {
 		var t LoadTest
-		if err := rows.Scan(&t.ID, &t.UserID, &t.NumUsers, &t.RampUp, &t.TargetURL, &t.StartTime, &t.EndTime); err != nil {
+		if err := rows.Scan(&t.ID, &t.UserID, &t.ProjectID, &t.NumUsers, &t.RampUp, &t.TargetURL, &t.StartTime, &t.EndTime, &t.TestType); err != nil {
 			continue
 		}
 		tasks = append(tasks, t)
@@ -137,20 +282,47 @@ func GetApprovedTasksReadyToRun() ([]LoadTest, error) {
 	return tasks, nil
 }
 
-func CreateTestResult(r *TestResult) error {
-	_, err := DB.Exec(`
+// insertTestResult 写入一条测试结果，db 可以是 DB 或事务
+func insertTestResult(db execer, r *TestResult) error {
+	var transport string
+	if r.Transport != nil {
+		data, err := json.Marshal(r.Transport)
+		if err != nil {
+			return err
+		}
+		transport = string(data)
+	}
+	var warnings string
+	if len(r.GeneratorWarnings) > 0 {
+		data, err := json.Marshal(r.GeneratorWarnings)
+		if err != nil {
+			return err
+		}
+		warnings = string(data)
+	}
+	res, err := db.Exec(`
 		INSERT INTO test_results (
 			test_id, tps, avg_response_time, success_count, failure_count,
 			error_rate, max_response_time, min_response_time, rps, download_speed,
 			download_size, download_duration, dns_time, connect_time, ttfb,
-			content_download_time, availability
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			content_download_time, availability, p50_response_time, p95_response_time,
+			p99_response_time, check_pass_rate, ws_connect_time, ws_round_trip_time,
+			ws_send_rate, ws_receive_rate, ws_error_rate, transport, generator_warnings
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		r.TestID, r.TPS, r.AvgResponseTime, r.SuccessCount, r.FailureCount,
 		r.ErrorRate, r.MaxResponseTime, r.MinResponseTime, r.RPS, r.DownloadSpeed,
 		r.DownloadSize, r.DownloadDuration, r.DNSTime, r.ConnectTime, r.TTFB,
-		r.ContentDownloadTime, r.Availability,
+		r.ContentDownloadTime, r.Availability, r.P50ResponseTime, r.P95ResponseTime,
+		r.P99ResponseTime, r.CheckPassRate, r.WSConnectTime, r.WSRoundTripTime,
+		r.WSSendRate, r.WSReceiveRate, r.WSErrorRate, transport, warnings,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	if id, err := res.LastInsertId(); err == nil {
+		r.ID = int(id)
+	}
+	return nil
 }
 
 func GetTestResultsByTestID(testID int) ([]TestResult, error) {
@@ -158,7 +330,11 @@ func GetTestResultsByTestID(testID int) ([]TestResult, error) {
 		SELECT id, test_id, tps, avg_response_time, success_count, failure_count,
 		       error_rate, max_response_time, min_response_time, rps, download_speed,
 		       download_size, download_duration, dns_time, connect_time, ttfb,
-		       content_download_time, availability
+		       content_download_time, availability, COALESCE(p50_response_time, 0),
+		       COALESCE(p95_response_time, 0), COALESCE(p99_response_time, 0),
+		       COALESCE(check_pass_rate, 1), COALESCE(ws_connect_time, 0),
+		       COALESCE(ws_round_trip_time, 0), COALESCE(ws_send_rate, 0), COALESCE(ws_receive_rate, 0),
+		       COALESCE(ws_error_rate, 0), COALESCE(transport, ''), COALESCE(generator_warnings, '')
 		FROM test_results WHERE test_id = ?`, testID,
 	)
 	if err != nil {
@@ -168,15 +344,27 @@ func GetTestResultsByTestID(testID int) ([]TestResult, error) {
 
 	var results []TestResult
 	for rows.Next() {
-		var r TestResult
+		var (
+			r         TestResult
+			transport string
+			warnings  string
+		)
 		if err := rows.Scan(
 			&r.ID, &r.TestID, &r.TPS, &r.AvgResponseTime, &r.SuccessCount, &r.FailureCount,
 			&r.ErrorRate, &r.MaxResponseTime, &r.MinResponseTime, &r.RPS, &r.DownloadSpeed,
 			&r.DownloadSize, &r.DownloadDuration, &r.DNSTime, &r.ConnectTime, &r.TTFB,
-			&r.ContentDownloadTime, &r.Availability,
+			&r.ContentDownloadTime, &r.Availability, &r.P50ResponseTime, &r.P95ResponseTime,
+			&r.P99ResponseTime, &r.CheckPassRate, &r.WSConnectTime,
+			&r.WSRoundTripTime, &r.WSSendRate, &r.WSReceiveRate, &r.WSErrorRate, &transport, &warnings,
 		); err != nil {
 			continue
 		}
+		if transport != "" {
+			json.Unmarshal([]byte(transport), &r.Transport)
+		}
+		if warnings != "" {
+			json.Unmarshal([]byte(warnings), &r.GeneratorWarnings)
+		}
 		results = append(results, r)
 	}
 	return results, nil
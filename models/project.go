@@ -0,0 +1,409 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// 组织 / 项目成员角色，权限依次递增
+const (
+	RoleViewer   = "viewer"   // 查看任务、结果与报告
+	RoleTester   = "tester"   // 另可在项目中提交压测
+	RoleApprover = "approver" // 另可审批 / 拒绝项目中的任务
+	RoleAdmin    = "admin"    // 另可管理项目与成员
+)
+
+var roleRanks = map[string]int{RoleViewer: 1, RoleTester: 2, RoleApprover: 3, RoleAdmin: 4}
+
+// ValidRole 是否为合法的成员角色
+func ValidRole(role string) bool {
+	return roleRanks[role] > 0
+}
+
+// RoleAtLeast role 是否不低于 min；空角色（非成员）总是 false
+func RoleAtLeast(role, min string) bool {
+	return roleRanks[role] > 0 && roleRanks[role] >= roleRanks[min]
+}
+
+// higherRole 返回两个角色中权限较高的一个
+func higherRole(a, b string) string {
+	if roleRanks[b] > roleRanks[a] {
+		return b
+	}
+	return a
+}
+
+// Organization 组织，成员角色对其下全部项目生效；PersonalUserID 非空时为该用户的个人空间
+type Organization struct {
+	ID             int       `json:"id"`
+	Name           string    `json:"name"`
+	PersonalUserID *int      `json:"personal_user_id,omitempty"`
+	Role           string    `json:"role,omitempty"` // 查询时当前用户在组织中的角色
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Project 项目，压测任务归属于项目
+type Project struct {
+	ID        int       `json:"id"`
+	OrgID     int       `json:"org_id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role,omitempty"` // 查询时当前用户的有效角色
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Member 组织或项目成员
+type Member struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+var projectTables = []string{
+	`CREATE TABLE IF NOT EXISTS organizations (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		personal_user_id INT NULL UNIQUE,
+		created_at DATETIME NOT NULL
+	);`,
+	`CREATE TABLE IF NOT EXISTS projects (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		org_id INT NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		created_at DATETIME NOT NULL,
+		UNIQUE KEY uk_projects_org_name (org_id, name),
+		FOREIGN KEY (org_id) REFERENCES organizations(id)
+	);`,
+	`CREATE TABLE IF NOT EXISTS org_members (
+		org_id INT NOT NULL,
+		user_id INT NOT NULL,
+		role VARCHAR(20) NOT NULL,
+		PRIMARY KEY (org_id, user_id),
+		FOREIGN KEY (org_id) REFERENCES organizations(id),
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	);`,
+	`CREATE TABLE IF NOT EXISTS project_members (
+		project_id INT NOT NULL,
+		user_id INT NOT NULL,
+		role VARCHAR(20) NOT NULL,
+		PRIMARY KEY (project_id, user_id),
+		FOREIGN KEY (project_id) REFERENCES projects(id),
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	);`,
+}
+
+// CreateOrganization 创建组织，创建者成为组织管理员；个人空间的所有者只是 tester
+func CreateOrganization(o *Organization, creatorID int) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	o.CreatedAt = time.Now()
+	res, err := tx.Exec(
+		"INSERT INTO organizations(name, personal_user_id, created_at) VALUES(?,?,?)",
+		o.Name, o.PersonalUserID, o.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	o.ID = int(id)
+	o.Role = RoleAdmin
+	if o.PersonalUserID != nil {
+		o.Role = personalSpaceRole
+	}
+	if _, err := tx.Exec("INSERT INTO org_members(org_id, user_id, role) VALUES(?,?,?)", o.ID, creatorID, o.Role); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListOrganizations 列出用户所属的组织
+func ListOrganizations(userID int) ([]Organization, error) {
+	rows, err := DB.Query(
+		`SELECT o.id, o.name, o.personal_user_id, m.role, o.created_at
+		   FROM organizations o JOIN org_members m ON m.org_id = o.id
+		  WHERE m.user_id = ? ORDER BY o.id`, userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Organization
+	for rows.Next() {
+		var (
+			o        Organization
+			personal sql.NullInt64
+		)
+		if err := rows.Scan(&o.ID, &o.Name, &personal, &o.Role, &o.CreatedAt); err != nil {
+			continue
+		}
+		if personal.Valid {
+			id := int(personal.Int64)
+			o.PersonalUserID = &id
+		}
+		list = append(list, o)
+	}
+	return list, nil
+}
+
+// OrgRole 用户在组织中的角色，非成员时为空
+func OrgRole(orgID, userID int) (string, error) {
+	var role string
+	err := DB.QueryRow("SELECT role FROM org_members WHERE org_id=? AND user_id=?", orgID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return role, err
+}
+
+func CreateProject(p *Project) error {
+	p.CreatedAt = time.Now()
+	res, err := DB.Exec(
+		"INSERT INTO projects(org_id, name, created_at) VALUES(?,?,?)",
+		p.OrgID, p.Name, p.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if id, err := res.LastInsertId(); err == nil {
+		p.ID = int(id)
+	}
+	return nil
+}
+
+func GetProjectByID(id int) (Project, error) {
+	var p Project
+	err := DB.QueryRow(
+		"SELECT id, org_id, name, created_at FROM projects WHERE id=?", id,
+	).Scan(&p.ID, &p.OrgID, &p.Name, &p.CreatedAt)
+	return p, err
+}
+
+// ListUserProjects 列出用户可访问的项目及其有效角色（项目角色与组织角色取较高者）
+func ListUserProjects(userID int) ([]Project, error) {
+	rows, err := DB.Query(
+		`SELECT p.id, p.org_id, p.name, m.role, p.created_at
+		   FROM projects p JOIN project_members m ON m.project_id = p.id
+		  WHERE m.user_id = ?
+		 UNION ALL
+		 SELECT p.id, p.org_id, p.name, m.role, p.created_at
+		   FROM projects p JOIN org_members m ON m.org_id = p.org_id
+		  WHERE m.user_id = ?`, userID, userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		list  []Project
+		index = make(map[int]int)
+	)
+	for rows.Next() {
+		var p Project
+		if err := rows.Scan(&p.ID, &p.OrgID, &p.Name, &p.Role, &p.CreatedAt); err != nil {
+			continue
+		}
+		if i, ok := index[p.ID]; ok {
+			list[i].Role = higherRole(list[i].Role, p.Role)
+			continue
+		}
+		index[p.ID] = len(list)
+		list = append(list, p)
+	}
+	return list, nil
+}
+
+// ProjectRole 用户在项目中的有效角色（项目角色与所属组织角色取较高者），非成员时为空
+func ProjectRole(projectID, userID int) (string, error) {
+	rows, err := DB.Query(
+		`SELECT role FROM project_members WHERE project_id = ? AND user_id = ?
+		 UNION ALL
+		 SELECT m.role FROM org_members m JOIN projects p ON p.org_id = m.org_id
+		  WHERE p.id = ? AND m.user_id = ?`,
+		projectID, userID, projectID, userID,
+	)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var role string
+	for rows.Next() {
+		var r string
+		if err := rows.Scan(&r); err != nil {
+			return "", err
+		}
+		role = higherRole(role, r)
+	}
+	return role, rows.Err()
+}
+
+// SetOrgMember 添加组织成员或修改其角色
+func SetOrgMember(orgID, userID int, role string) error {
+	_, err := DB.Exec(
+		"INSERT INTO org_members(org_id, user_id, role) VALUES(?,?,?) ON DUPLICATE KEY UPDATE role=VALUES(role)",
+		orgID, userID, role,
+	)
+	return err
+}
+
+// SetProjectMember 添加项目成员或修改其角色
+func SetProjectMember(projectID, userID int, role string) error {
+	_, err := DB.Exec(
+		"INSERT INTO project_members(project_id, user_id, role) VALUES(?,?,?) ON DUPLICATE KEY UPDATE role=VALUES(role)",
+		projectID, userID, role,
+	)
+	return err
+}
+
+// RemoveOrgMember 移除组织成员，返回是否存在
+func RemoveOrgMember(orgID, userID int) (bool, error) {
+	res, err := DB.Exec("DELETE FROM org_members WHERE org_id=? AND user_id=?", orgID, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// RemoveProjectMember 移除项目成员，返回是否存在
+func RemoveProjectMember(projectID, userID int) (bool, error) {
+	res, err := DB.Exec("DELETE FROM project_members WHERE project_id=? AND user_id=?", projectID, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// CountOrgAdmins 组织管理员人数，用于防止移除最后一个管理员
+func CountOrgAdmins(orgID int) (int, error) {
+	var n int
+	err := DB.QueryRow("SELECT COUNT(*) FROM org_members WHERE org_id=? AND role=?", orgID, RoleAdmin).Scan(&n)
+	return n, err
+}
+
+func listMembers(query string, id int) ([]Member, error) {
+	rows, err := DB.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Member
+	for rows.Next() {
+		var m Member
+		if err := rows.Scan(&m.UserID, &m.Username, &m.Role); err != nil {
+			continue
+		}
+		list = append(list, m)
+	}
+	return list, nil
+}
+
+func ListOrgMembers(orgID int) ([]Member, error) {
+	return listMembers(
+		`SELECT u.id, u.username, m.role FROM org_members m JOIN users u ON u.id = m.user_id
+		  WHERE m.org_id = ? ORDER BY u.username`, orgID,
+	)
+}
+
+func ListProjectMembers(projectID int) ([]Member, error) {
+	return listMembers(
+		`SELECT u.id, u.username, m.role FROM project_members m JOIN users u ON u.id = m.user_id
+		  WHERE m.project_id = ? ORDER BY u.username`, projectID,
+	)
+}
+
+// GetUserIDByUsername 按用户名查找用户
+func GetUserIDByUsername(username string) (int, error) {
+	var id int
+	err := DB.QueryRow("SELECT id FROM users WHERE username=?", username).Scan(&id)
+	return id, err
+}
+
+// personalProjectName 个人空间中默认项目的名称
+const personalProjectName = "默认项目"
+
+// personalSpaceRole 用户在自己个人空间中的角色：可以提交任务，但不能审批，个人项目的任务仍由系统管理员审批
+const personalSpaceRole = RoleTester
+
+// EnsurePersonalProject 返回用户个人空间的默认项目，不存在时创建。
+// 未指定项目提交的任务归入该项目，用户在其个人空间中是 tester
+func EnsurePersonalProject(userID int) (int, error) {
+	var projectID int
+	err := DB.QueryRow(
+		`SELECT p.id FROM projects p JOIN organizations o ON o.id = p.org_id
+		  WHERE o.personal_user_id = ? AND p.name = ?`, userID, personalProjectName,
+	).Scan(&projectID)
+	if err != sql.ErrNoRows {
+		return projectID, err
+	}
+
+	var orgID int
+	err = DB.QueryRow("SELECT id FROM organizations WHERE personal_user_id=?", userID).Scan(&orgID)
+	if err == sql.ErrNoRows {
+		var username string
+		if err := DB.QueryRow("SELECT username FROM users WHERE id=?", userID).Scan(&username); err != nil {
+			return 0, err
+		}
+		org := Organization{Name: username + " 的个人空间", PersonalUserID: &userID}
+		if err := CreateOrganization(&org, userID); err != nil {
+			return 0, err
+		}
+		orgID = org.ID
+	} else if err != nil {
+		return 0, err
+	}
+
+	p := Project{OrgID: orgID, Name: personalProjectName}
+	if err := CreateProject(&p); err != nil {
+		return 0, err
+	}
+	return p.ID, nil
+}
+
+// assignUnscopedTests 把引入项目之前提交的任务归入提交者的个人项目
+func assignUnscopedTests() error {
+	rows, err := DB.Query("SELECT DISTINCT user_id FROM load_tests WHERE project_id = 0")
+	if err != nil {
+		return err
+	}
+	var userIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err == nil {
+			userIDs = append(userIDs, id)
+		}
+	}
+	rows.Close()
+
+	for _, userID := range userIDs {
+		projectID, err := EnsurePersonalProject(userID)
+		if err != nil {
+			return err
+		}
+		if _, err := DB.Exec("UPDATE load_tests SET project_id=? WHERE user_id=? AND project_id = 0", projectID, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// demotePersonalSpaceOwners 早期版本中个人空间的所有者是 admin，可审批自己的任务，启动时降为 tester
+func demotePersonalSpaceOwners() error {
+	_, err := DB.Exec(
+		`UPDATE org_members m JOIN organizations o ON o.id = m.org_id
+		    SET m.role = ?
+		  WHERE o.personal_user_id = m.user_id AND m.role <> ?`,
+		personalSpaceRole, personalSpaceRole,
+	)
+	return err
+}
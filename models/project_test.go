@@ -0,0 +1,63 @@
+package models
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"loadtest_project/models/testdb"
+)
+
+func TestRoleAtLeast(t *testing.T) {
+	roles := []string{RoleViewer, RoleTester, RoleApprover, RoleAdmin}
+	for i, role := range roles {
+		for j, min := range roles {
+			if got, want := RoleAtLeast(role, min), i >= j; got != want {
+				t.Errorf("RoleAtLeast(%q, %q) = %v, want %v", role, min, got, want)
+			}
+		}
+	}
+
+	tests := []struct {
+		name      string
+		role, min string
+		want      bool
+	}{
+		{"非成员没有任何权限", "", RoleViewer, false},
+		{"未知角色没有任何权限", "owner", RoleViewer, false},
+		{"系统角色 user 不是项目角色", "user", RoleViewer, false},
+		{"未知的最低要求按最低处理", RoleViewer, "guest", true},
+	}
+	for _, tt := range tests {
+		if got := RoleAtLeast(tt.role, tt.min); got != tt.want {
+			t.Errorf("%s: RoleAtLeast(%q, %q) = %v, want %v", tt.name, tt.role, tt.min, got, tt.want)
+		}
+	}
+}
+
+func TestProjectRole(t *testing.T) {
+	tests := []struct {
+		name  string
+		roles []string // 项目成员角色与组织成员角色查询返回的行
+		want  string
+	}{
+		{"非成员", nil, ""},
+		{"只有项目角色", []string{RoleTester}, RoleTester},
+		{"组织角色高于项目角色时取组织角色", []string{RoleViewer, RoleApprover}, RoleApprover},
+		{"项目角色高于组织角色时取项目角色", []string{RoleAdmin, RoleTester}, RoleAdmin},
+	}
+	for _, tt := range tests {
+		orig := DB
+		DB = testdb.Open(t, func(query string, args []driver.Value) (testdb.Result, error) {
+			rows := &testdb.Rows{Columns: []string{"role"}}
+			for _, r := range tt.roles {
+				rows.Values = append(rows.Values, []driver.Value{r})
+			}
+			return testdb.Result{Rows: rows}, nil
+		})
+		got, err := ProjectRole(1, 2)
+		DB = orig
+		if err != nil || got != tt.want {
+			t.Errorf("%s: ProjectRole = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// 单次 runner 运行的状态
+const (
+	RunStatusRunning   = "running"
+	RunStatusSucceeded = "succeeded"
+	RunStatusFailed    = "failed"
+	RunStatusAborted   = "aborted"
+	RunStatusTimeout   = "timeout" // 超过硬性期限被强制停止
+)
+
+// Run 一次 runner 进程的运行记录，日志保存在 LogPath；
+// 普通任务每次执行一条，容量探测每个阶段一条
+type Run struct {
+	ID            int        `json:"id"`
+	TestID        int        `json:"test_id"`
+	Prefix        string     `json:"prefix"` // 结果文件前缀
+	Status        string     `json:"status"`
+	FailureReason string     `json:"failure_reason"`
+	LogPath       string     `json:"-"`
+	LogSize       int64      `json:"log_size"`
+	LogTruncated  bool       `json:"log_truncated"`
+	StartedAt     time.Time  `json:"started_at"`
+	FinishedAt    *time.Time `json:"finished_at"`
+}
+
+var runTables = []string{
+	`CREATE TABLE IF NOT EXISTS runs (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		test_id INT NOT NULL,
+		prefix VARCHAR(255) NOT NULL,
+		status VARCHAR(20) NOT NULL,
+		failure_reason VARCHAR(1024) NOT NULL DEFAULT '',
+		log_path VARCHAR(512) NOT NULL,
+		log_size BIGINT NOT NULL DEFAULT 0,
+		log_truncated BOOLEAN NOT NULL DEFAULT FALSE,
+		result_uploaded BOOLEAN NOT NULL DEFAULT FALSE,
+		started_at DATETIME NOT NULL,
+		finished_at DATETIME NULL,
+		INDEX idx_runs_test (test_id),
+		FOREIGN KEY (test_id) REFERENCES load_tests(id)
+	);`,
+}
+
+const runColumns = "id, test_id, prefix, status, failure_reason, log_path, log_size, log_truncated, started_at, finished_at"
+
+func scanRun(row interface{ Scan(...interface{}) error }, r *Run) error {
+	var finished sql.NullTime
+	err := row.Scan(&r.ID, &r.TestID, &r.Prefix, &r.Status, &r.FailureReason, &r.LogPath, &r.LogSize, &r.LogTruncated, &r.StartedAt, &finished)
+	if finished.Valid {
+		r.FinishedAt = &finished.Time
+	}
+	return err
+}
+
+// CreateRun 记录一次开始的运行
+func CreateRun(r *Run) error {
+	r.Status = RunStatusRunning
+	r.StartedAt = time.Now()
+	res, err := DB.Exec(
+		"INSERT INTO runs(test_id, prefix, status, log_path, started_at) VALUES(?,?,?,?,?)",
+		r.TestID, r.Prefix, r.Status, r.LogPath, r.StartedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if id, err := res.LastInsertId(); err == nil {
+		r.ID = int(id)
+	}
+	return nil
+}
+
+// FinishRun 记录运行结束时的状态、失败原因与日志大小
+func FinishRun(r *Run) error {
+	now := time.Now()
+	r.FinishedAt = &now
+	_, err := DB.Exec(
+		"UPDATE runs SET status=?, failure_reason=?, log_size=?, log_truncated=?, finished_at=? WHERE id=?",
+		r.Status, r.FailureReason, r.LogSize, r.LogTruncated, now, r.ID,
+	)
+	return err
+}
+
+func GetRunByID(id int) (Run, error) {
+	var r Run
+	err := scanRun(DB.QueryRow("SELECT "+runColumns+" FROM runs WHERE id=?", id), &r)
+	return r, err
+}
+
+// ListRuns 按开始时间列出任务的全部运行
+func ListRuns(testID int) ([]Run, error) {
+	rows, err := DB.Query("SELECT "+runColumns+" FROM runs WHERE test_id=? ORDER BY id", testID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Run
+	for rows.Next() {
+		var r Run
+		if err := scanRun(rows, &r); err != nil {
+			continue
+		}
+		list = append(list, r)
+	}
+	return list, nil
+}
+
+// GetRunByPrefix 按结果文件前缀查找运行
+func GetRunByPrefix(prefix string) (Run, error) {
+	var r Run
+	err := scanRun(DB.QueryRow("SELECT "+runColumns+" FROM runs WHERE prefix=?", prefix), &r)
+	return r, err
+}
+
+// SaveRunResult 保存运行的测试结果，并在同一事务中标记该运行已有结果：每次运行只保存一条
+// （runner 自行解析保存的或经 /api/upload_result 上传的），已有结果时返回 false；写入失败不占用名额
+func SaveRunResult(runID int, r *TestResult) (bool, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec("UPDATE runs SET result_uploaded=TRUE WHERE id=? AND result_uploaded=FALSE", runID)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	if err := insertTestResult(tx, r); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// FailLoadTest 将任务标记为 failed 并记录失败原因
+func FailLoadTest(id int, reason string) error {
+	_, err := DB.Exec("UPDATE load_tests SET status='failed', failure_reason=? WHERE id=?", reason, id)
+	return err
+}
+
+// TimeoutLoadTest 将任务标记为 timeout：runner 超过硬性期限被强制停止
+func TimeoutLoadTest(id int, reason string) error {
+	_, err := DB.Exec("UPDATE load_tests SET status='timeout', failure_reason=? WHERE id=?", reason, id)
+	return err
+}
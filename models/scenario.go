@@ -0,0 +1,173 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// 步骤类型
+const (
+	StepHTTP      = "http"       // HTTP 请求（默认）
+	StepWSConnect = "ws_connect" // 建立 WebSocket 连接，Path 为 ws:// 地址或相对路径
+	StepWSSend    = "ws_send"    // 发送 Body 作为消息
+	StepWSExpect  = "ws_expect"  // 等待内容包含 Body 的消息，超时 Timeout 毫秒
+	StepWSHold    = "ws_hold"    // 保持连接并持续接收 Timeout 毫秒
+	StepWSClose   = "ws_close"   // 关闭连接
+	StepGRPC      = "grpc"       // gRPC 调用，Path 为 /package.Service/Method，Body 为 JSON 请求消息
+)
+
+// 断言类型
+const (
+	CheckStatus       = "status"        // 状态码等于 Value
+	CheckBodyContains = "body_contains" // 响应体包含 Value
+	CheckBodyRegex    = "body_regex"    // 响应体匹配正则 Value
+	CheckJSONPath     = "json_path"     // JSON 路径 Path 的值等于 Value
+	CheckMaxLatency   = "max_latency"   // 响应时间（毫秒）不超过 Value
+)
+
+// StepCheck 步骤上的响应断言，任一断言失败即该请求记为失败
+type StepCheck struct {
+	Type  string `json:"type"`
+	Path  string `json:"path,omitempty"`
+	Value string `json:"value"`
+}
+
+// 提取器类型
+const (
+	ExtractJSONPath = "json_path" // 按 JSON 路径取值
+	ExtractRegex    = "regex"     // 正则匹配响应体，有分组时取第 1 组
+	ExtractHeader   = "header"    // 取响应头
+)
+
+// StepExtractor 从响应中提取值存入虚拟用户变量，供后续步骤以 ${Variable} 引用
+type StepExtractor struct {
+	Type       string `json:"type"`
+	Expression string `json:"expression"`
+	Variable   string `json:"variable"`
+}
+
+// ScenarioStep 场景中的一个请求步骤。Path、Headers、Body 中可使用 ${变量} 引用
+// 数据集列或前序步骤提取的变量
+type ScenarioStep struct {
+	ID         int               `json:"id"`
+	TestID     int               `json:"test_id"`
+	StepOrder  int               `json:"step_order"`
+	Type       string            `json:"type"`
+	Name       string            `json:"name"`
+	Method     string            `json:"method"`
+	Path       string            `json:"path"`
+	Headers    map[string]string `json:"headers"`
+	Body       string            `json:"body"`
+	ThinkTime  int               `json:"think_time"` // 步骤完成后等待的毫秒数
+	Timeout    int               `json:"timeout"`    // ws_expect / grpc 超时，ws_hold 时长（毫秒）
+	Checks     []StepCheck       `json:"checks"`
+	Extractors []StepExtractor   `json:"extractors"`
+}
+
+var scenarioTables = []string{
+	`CREATE TABLE IF NOT EXISTS scenario_steps (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		test_id INT NOT NULL,
+		step_order INT NOT NULL,
+		type VARCHAR(20) NOT NULL DEFAULT 'http',
+		name VARCHAR(255) NOT NULL DEFAULT '',
+		method VARCHAR(10) NOT NULL,
+		path TEXT NOT NULL,
+		headers TEXT,
+		body MEDIUMTEXT,
+		think_time INT NOT NULL DEFAULT 0,
+		timeout INT NOT NULL DEFAULT 0,
+		checks TEXT,
+		extractors TEXT,
+		FOREIGN KEY (test_id) REFERENCES load_tests(id)
+	);`,
+}
+
+func CreateScenarioStep(s *ScenarioStep) error {
+	return insertScenarioStep(DB, s)
+}
+
+func insertScenarioStep(db execer, s *ScenarioStep) error {
+	if s.Type == "" {
+		s.Type = StepHTTP
+	}
+	headers, err := json.Marshal(s.Headers)
+	if err != nil {
+		return err
+	}
+	checks, err := json.Marshal(s.Checks)
+	if err != nil {
+		return err
+	}
+	extractors, err := json.Marshal(s.Extractors)
+	if err != nil {
+		return err
+	}
+	res, err := db.Exec(
+		"INSERT INTO scenario_steps(test_id, step_order, type, name, method, path, headers, body, think_time, timeout, checks, extractors) VALUES(?,?,?,?,?,?,?,?,?,?,?,?)",
+		s.TestID, s.StepOrder, s.Type, s.Name, s.Method, s.Path, string(headers), s.Body, s.ThinkTime, s.Timeout, string(checks), string(extractors),
+	)
+	if err != nil {
+		return err
+	}
+	if id, err := res.LastInsertId(); err == nil {
+		s.ID = int(id)
+	}
+	return nil
+}
+
+// GetScenarioSteps 按顺序返回任务的场景步骤
+func GetScenarioSteps(testID int) ([]ScenarioStep, error) {
+	rows, err := DB.Query(`
+		SELECT id, test_id, step_order, type, name, method, path, COALESCE(headers, ''), COALESCE(body, ''),
+		       think_time, timeout, COALESCE(checks, ''), COALESCE(extractors, '')
+		  FROM scenario_steps WHERE test_id = ? ORDER BY step_order, id`, testID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var steps []ScenarioStep
+	for rows.Next() {
+		var (
+			s                           ScenarioStep
+			headers, checks, extractors string
+		)
+		if err := rows.Scan(&s.ID, &s.TestID, &s.StepOrder, &s.Type, &s.Name, &s.Method, &s.Path, &headers, &s.Body,
+			&s.ThinkTime, &s.Timeout, &checks, &extractors); err != nil {
+			continue
+		}
+		if headers != "" {
+			json.Unmarshal([]byte(headers), &s.Headers)
+		}
+		if checks != "" {
+			json.Unmarshal([]byte(checks), &s.Checks)
+		}
+		if extractors != "" {
+			json.Unmarshal([]byte(extractors), &s.Extractors)
+		}
+		steps = append(steps, s)
+	}
+	return steps, nil
+}
+
+// ReplaceScenarioSteps 在同一事务中删除任务原有的场景步骤并写入新的（重新导入时使用），
+// 任一步骤写入失败时保留原场景
+func ReplaceScenarioSteps(testID int, steps []ScenarioStep) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("DELETE FROM scenario_steps WHERE test_id=?", testID); err != nil {
+		return err
+	}
+	for i := range steps {
+		steps[i].TestID = testID
+		if err := insertScenarioStep(tx, &steps[i]); err != nil {
+			return fmt.Errorf("场景步骤保存失败: %w", err)
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,148 @@
+package models
+
+import (
+	"time"
+)
+
+// Secret 加密保存的凭据，属于用户或项目（ProjectID 非 0），API 只返回元数据，永不返回明文或密文
+type Secret struct {
+	ID         int       `json:"id"`
+	UserID     int       `json:"user_id"` // 用户 secret 的所有者，项目 secret 的最近一次保存者
+	ProjectID  int       `json:"project_id,omitempty"`
+	Name       string    `json:"name"`
+	Ciphertext string    `json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+var secretTables = []string{
+	`CREATE TABLE IF NOT EXISTS secrets (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		user_id INT NOT NULL,
+		name VARCHAR(128) NOT NULL,
+		ciphertext TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL,
+		UNIQUE KEY uniq_user_secret (user_id, name),
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	);`,
+	// 项目共享的 secret，项目成员提交的任务都可引用
+	`CREATE TABLE IF NOT EXISTS project_secrets (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		project_id INT NOT NULL,
+		user_id INT NOT NULL,
+		name VARCHAR(128) NOT NULL,
+		ciphertext TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL,
+		UNIQUE KEY uniq_project_secret (project_id, name),
+		FOREIGN KEY (project_id) REFERENCES projects(id),
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	);`,
+}
+
+// SaveSecret 新建或覆盖同名 secret（用于轮换）
+func SaveSecret(s *Secret) error {
+	now := time.Now()
+	_, err := DB.Exec(`
+		INSERT INTO secrets (user_id, name, ciphertext, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE ciphertext = VALUES(ciphertext), updated_at = VALUES(updated_at)`,
+		s.UserID, s.Name, s.Ciphertext, now, now,
+	)
+	return err
+}
+
+// GetSecret 按名称查询用户的 secret（含密文，仅供 Runner 解密使用）
+func GetSecret(userID int, name string) (Secret, error) {
+	var s Secret
+	err := DB.QueryRow(
+		"SELECT id, user_id, name, ciphertext, created_at, updated_at FROM secrets WHERE user_id=? AND name=?",
+		userID, name,
+	).Scan(&s.ID, &s.UserID, &s.Name, &s.Ciphertext, &s.CreatedAt, &s.UpdatedAt)
+	return s, err
+}
+
+// ListSecrets 列出用户的 secret 元数据
+func ListSecrets(userID int) ([]Secret, error) {
+	rows, err := DB.Query(
+		"SELECT id, user_id, name, created_at, updated_at FROM secrets WHERE user_id=? ORDER BY name", userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Secret
+	for rows.Next() {
+		var s Secret
+		if err := rows.Scan(&s.ID, &s.UserID, &s.Name, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			continue
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+// DeleteSecret 删除用户的 secret，返回是否存在
+func DeleteSecret(userID int, name string) (bool, error) {
+	res, err := DB.Exec("DELETE FROM secrets WHERE user_id=? AND name=?", userID, name)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// SaveProjectSecret 新建或覆盖项目中的同名 secret
+func SaveProjectSecret(s *Secret) error {
+	now := time.Now()
+	_, err := DB.Exec(`
+		INSERT INTO project_secrets (project_id, user_id, name, ciphertext, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE user_id = VALUES(user_id), ciphertext = VALUES(ciphertext), updated_at = VALUES(updated_at)`,
+		s.ProjectID, s.UserID, s.Name, s.Ciphertext, now, now,
+	)
+	return err
+}
+
+// GetProjectSecret 按名称查询项目的 secret（含密文，仅供 Runner 解密使用）
+func GetProjectSecret(projectID int, name string) (Secret, error) {
+	var s Secret
+	err := DB.QueryRow(
+		"SELECT id, user_id, project_id, name, ciphertext, created_at, updated_at FROM project_secrets WHERE project_id=? AND name=?",
+		projectID, name,
+	).Scan(&s.ID, &s.UserID, &s.ProjectID, &s.Name, &s.Ciphertext, &s.CreatedAt, &s.UpdatedAt)
+	return s, err
+}
+
+// ListProjectSecrets 列出项目的 secret 元数据
+func ListProjectSecrets(projectID int) ([]Secret, error) {
+	rows, err := DB.Query(
+		"SELECT id, user_id, project_id, name, created_at, updated_at FROM project_secrets WHERE project_id=? ORDER BY name", projectID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Secret
+	for rows.Next() {
+		var s Secret
+		if err := rows.Scan(&s.ID, &s.UserID, &s.ProjectID, &s.Name, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			continue
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+// DeleteProjectSecret 删除项目的 secret，返回是否存在
+func DeleteProjectSecret(projectID int, name string) (bool, error) {
+	res, err := DB.Exec("DELETE FROM project_secrets WHERE project_id=? AND name=?", projectID, name)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
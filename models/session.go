@@ -0,0 +1,156 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// RefreshToken 服务端保存的 refresh token，只保存哈希。每次使用后轮换：旧令牌吊销并记录替代者，
+// 同一次登录轮换出的令牌共享 FamilyID；已吊销的令牌被再次使用说明可能泄露，整个家族随即吊销
+type RefreshToken struct {
+	ID        int
+	UserID    int
+	TokenHash string
+	FamilyID  string
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+var sessionTables = []string{
+	`CREATE TABLE IF NOT EXISTS refresh_tokens (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		user_id INT NOT NULL,
+		token_hash CHAR(64) NOT NULL UNIQUE,
+		family_id VARCHAR(64) NOT NULL,
+		expires_at DATETIME NOT NULL,
+		revoked_at DATETIME NULL,
+		replaced_by INT NULL,
+		created_at DATETIME NOT NULL,
+		INDEX idx_refresh_family (family_id),
+		INDEX idx_refresh_user (user_id),
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	);`,
+	// 登出的 access token（按 jti），过期后即可清理
+	`CREATE TABLE IF NOT EXISTS token_denylist (
+		jti VARCHAR(64) PRIMARY KEY,
+		expires_at DATETIME NOT NULL,
+		INDEX idx_denylist_expires (expires_at)
+	);`,
+	// 用户在 revoked_before 之前签发的 access token 全部失效（离职、重置权限等）
+	`CREATE TABLE IF NOT EXISTS token_revocations (
+		user_id INT PRIMARY KEY,
+		revoked_before DATETIME NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	);`,
+}
+
+const refreshTokenColumns = "id, user_id, token_hash, family_id, expires_at, revoked_at, created_at"
+
+func scanRefreshToken(row interface{ Scan(...interface{}) error }, t *RefreshToken) error {
+	var revoked sql.NullTime
+	if err := row.Scan(&t.ID, &t.UserID, &t.TokenHash, &t.FamilyID, &t.ExpiresAt, &revoked, &t.CreatedAt); err != nil {
+		return err
+	}
+	if revoked.Valid {
+		t.RevokedAt = &revoked.Time
+	}
+	return nil
+}
+
+func CreateRefreshToken(t *RefreshToken) error {
+	t.CreatedAt = time.Now()
+	res, err := DB.Exec(
+		"INSERT INTO refresh_tokens(user_id, token_hash, family_id, expires_at, created_at) VALUES(?,?,?,?,?)",
+		t.UserID, t.TokenHash, t.FamilyID, t.ExpiresAt, t.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if id, err := res.LastInsertId(); err == nil {
+		t.ID = int(id)
+	}
+	return nil
+}
+
+// GetRefreshTokenByHash 按哈希查找，不检查是否过期或吊销
+func GetRefreshTokenByHash(hash string) (RefreshToken, error) {
+	var t RefreshToken
+	err := scanRefreshToken(DB.QueryRow("SELECT "+refreshTokenColumns+" FROM refresh_tokens WHERE token_hash=?", hash), &t)
+	return t, err
+}
+
+// RotateRefreshToken 吊销旧令牌并保存替代它的新令牌；旧令牌已被吊销（并发或重放）时返回 false，不保存新令牌
+func RotateRefreshToken(oldID int, next *RefreshToken) (bool, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	res, err := tx.Exec("UPDATE refresh_tokens SET revoked_at=? WHERE id=? AND revoked_at IS NULL", now, oldID)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	next.CreatedAt = now
+	res, err = tx.Exec(
+		"INSERT INTO refresh_tokens(user_id, token_hash, family_id, expires_at, created_at) VALUES(?,?,?,?,?)",
+		next.UserID, next.TokenHash, next.FamilyID, next.ExpiresAt, next.CreatedAt,
+	)
+	if err != nil {
+		return false, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return false, err
+	}
+	next.ID = int(id)
+	if _, err := tx.Exec("UPDATE refresh_tokens SET replaced_by=? WHERE id=?", next.ID, oldID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// RevokeRefreshFamily 吊销同一次登录轮换出的全部 refresh token
+func RevokeRefreshFamily(familyID string) error {
+	_, err := DB.Exec("UPDATE refresh_tokens SET revoked_at=? WHERE family_id=? AND revoked_at IS NULL", time.Now(), familyID)
+	return err
+}
+
+// RevokeUserSessions 吊销用户全部 refresh token，并使此前签发的 access token 立即失效
+func RevokeUserSessions(userID int) error {
+	now := time.Now()
+	if _, err := DB.Exec("UPDATE refresh_tokens SET revoked_at=? WHERE user_id=? AND revoked_at IS NULL", now, userID); err != nil {
+		return err
+	}
+	_, err := DB.Exec(
+		"INSERT INTO token_revocations(user_id, revoked_before) VALUES(?,?) ON DUPLICATE KEY UPDATE revoked_before=VALUES(revoked_before)",
+		userID, now,
+	)
+	return err
+}
+
+// DenyAccessToken 将 access token 加入黑名单直到其过期，顺带清理已过期的条目
+func DenyAccessToken(jti string, expiresAt time.Time) error {
+	if _, err := DB.Exec("DELETE FROM token_denylist WHERE expires_at < ?", time.Now()); err != nil {
+		return err
+	}
+	_, err := DB.Exec("INSERT IGNORE INTO token_denylist(jti, expires_at) VALUES(?,?)", jti, expiresAt)
+	return err
+}
+
+// IsAccessTokenRevoked access token 是否已登出，或签发于用户令牌被整体吊销之前。
+// 签发时间只精确到秒，同一秒内签发的令牌按已吊销处理
+func IsAccessTokenRevoked(jti string, userID int, issuedAt time.Time) (bool, error) {
+	var revoked bool
+	err := DB.QueryRow(
+		`SELECT EXISTS(SELECT 1 FROM token_denylist WHERE jti=?)
+			OR EXISTS(SELECT 1 FROM token_revocations WHERE user_id=? AND revoked_before >= ?)`,
+		jti, userID, issuedAt,
+	).Scan(&revoked)
+	return revoked, err
+}
@@ -0,0 +1,165 @@
+package models
+
+import (
+	"encoding/json"
+)
+
+// 目标认证方式
+const (
+	AuthNone   = ""
+	AuthBasic  = "basic"
+	AuthBearer = "bearer"
+)
+
+// TargetOptions 访问压测目标所需的请求头、认证与 TLS 选项；
+// 凭据（password、token、client_key、凭据类请求头与 Cookie）只保存 secret 引用，明文加密存放在 secrets 中
+type TargetOptions struct {
+	TestID        int               `json:"test_id"`
+	Headers       map[string]string `json:"headers"`
+	Cookies       map[string]string `json:"cookies"`
+	AuthType      string            `json:"auth_type"`
+	Username      string            `json:"username"`
+	Password      string            `json:"password"`
+	Token         string            `json:"token"`
+	TLSSkipVerify bool              `json:"tls_skip_verify"`
+	CABundle      string            `json:"ca_bundle"`   // PEM
+	ClientCert    string            `json:"client_cert"` // PEM
+	ClientKey     string            `json:"client_key"`  // PEM
+}
+
+// redactedValue 替代敏感字段的占位符
+const redactedValue = "******"
+
+// Redacted 返回可安全输出给前端的副本：凭据、Cookie、请求头值和私钥均被遮蔽
+func (o TargetOptions) Redacted() TargetOptions {
+	mask := func(v string) string {
+		if v == "" {
+			return ""
+		}
+		return redactedValue
+	}
+	maskMap := func(m map[string]string) map[string]string {
+		if m == nil {
+			return nil
+		}
+		out := make(map[string]string, len(m))
+		for k, v := range m {
+			out[k] = mask(v)
+		}
+		return out
+	}
+	o.Headers = maskMap(o.Headers)
+	o.Cookies = maskMap(o.Cookies)
+	o.Password = mask(o.Password)
+	o.Token = mask(o.Token)
+	o.ClientKey = mask(o.ClientKey)
+	return o
+}
+
+var targetTables = []string{
+	`CREATE TABLE IF NOT EXISTS target_options (
+		test_id INT PRIMARY KEY,
+		headers TEXT,
+		cookies TEXT,
+		auth_type VARCHAR(20) NOT NULL DEFAULT '',
+		username VARCHAR(255) NOT NULL DEFAULT '',
+		password TEXT,
+		token TEXT,
+		tls_skip_verify BOOLEAN NOT NULL DEFAULT FALSE,
+		ca_bundle TEXT,
+		client_cert TEXT,
+		client_key TEXT,
+		FOREIGN KEY (test_id) REFERENCES load_tests(id)
+	);`,
+}
+
+func CreateTargetOptions(o *TargetOptions) error {
+	headers, err := json.Marshal(o.Headers)
+	if err != nil {
+		return err
+	}
+	cookies, err := json.Marshal(o.Cookies)
+	if err != nil {
+		return err
+	}
+	_, err = DB.Exec(`
+		INSERT INTO target_options (
+			test_id, headers, cookies, auth_type, username, password, token,
+			tls_skip_verify, ca_bundle, client_cert, client_key
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		o.TestID, string(headers), string(cookies), o.AuthType, o.Username, o.Password, o.Token,
+		o.TLSSkipVerify, o.CABundle, o.ClientCert, o.ClientKey,
+	)
+	return err
+}
+
+func GetTargetOptions(testID int) (TargetOptions, error) {
+	var (
+		o                TargetOptions
+		headers, cookies string
+	)
+	err := DB.QueryRow(`
+		SELECT test_id, COALESCE(headers, ''), COALESCE(cookies, ''), auth_type, username,
+		       COALESCE(password, ''), COALESCE(token, ''), tls_skip_verify,
+		       COALESCE(ca_bundle, ''), COALESCE(client_cert, ''), COALESCE(client_key, '')
+		  FROM target_options WHERE test_id = ?`, testID,
+	).Scan(&o.TestID, &headers, &cookies, &o.AuthType, &o.Username,
+		&o.Password, &o.Token, &o.TLSSkipVerify,
+		&o.CABundle, &o.ClientCert, &o.ClientKey)
+	if err != nil {
+		return o, err
+	}
+	if headers != "" {
+		if err := json.Unmarshal([]byte(headers), &o.Headers); err != nil {
+			return o, err
+		}
+	}
+	if cookies != "" {
+		if err := json.Unmarshal([]byte(cookies), &o.Cookies); err != nil {
+			return o, err
+		}
+	}
+	return o, nil
+}
+
+// TargetOwner 有目标选项的任务及其提交者
+type TargetOwner struct {
+	TestID int
+	UserID int
+}
+
+// ListTargetOwners 列出全部有目标选项的任务，用于迁移早期保存的明文凭据
+func ListTargetOwners() ([]TargetOwner, error) {
+	rows, err := DB.Query("SELECT o.test_id, t.user_id FROM target_options o JOIN load_tests t ON t.id = o.test_id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []TargetOwner
+	for rows.Next() {
+		var o TargetOwner
+		if err := rows.Scan(&o.TestID, &o.UserID); err != nil {
+			return nil, err
+		}
+		list = append(list, o)
+	}
+	return list, rows.Err()
+}
+
+// UpdateTargetCredentials 更新目标选项中的凭据字段
+func UpdateTargetCredentials(o *TargetOptions) error {
+	headers, err := json.Marshal(o.Headers)
+	if err != nil {
+		return err
+	}
+	cookies, err := json.Marshal(o.Cookies)
+	if err != nil {
+		return err
+	}
+	_, err = DB.Exec(
+		"UPDATE target_options SET headers=?, cookies=?, password=?, token=?, client_key=? WHERE test_id=?",
+		string(headers), string(cookies), o.Password, o.Token, o.ClientKey, o.TestID,
+	)
+	return err
+}
@@ -0,0 +1,149 @@
+// Package testdb 供测试使用的 database/sql 驱动：每条 SQL 交给测试提供的处理函数应答，不需要真实的 MySQL。
+// 处理函数按 SQL 片段判断是哪条语句，返回查询结果或影响的行数；事务的提交与回滚不做任何事
+package testdb
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+)
+
+// Rows 查询返回的结果集
+type Rows struct {
+	Columns []string
+	Values  [][]driver.Value
+}
+
+// Result 一条语句的应答：查询语句用 Rows（nil 表示没有结果行），修改语句用 LastInsertID 与 RowsAffected
+type Result struct {
+	Rows         *Rows
+	LastInsertID int64
+	RowsAffected int64
+}
+
+// Handler 应答一条 SQL，args 为绑定参数
+type Handler func(query string, args []driver.Value) (Result, error)
+
+// Row 构造单行结果集的便捷函数
+func Row(columns []string, values ...driver.Value) Result {
+	return Result{Rows: &Rows{Columns: columns, Values: [][]driver.Value{values}}}
+}
+
+var (
+	registerOnce sync.Once
+	mu           sync.Mutex
+	handlers     = map[string]Handler{}
+	seq          int
+)
+
+// Open 打开由 h 应答的数据库，测试结束时自动关闭
+func Open(t testing.TB, h Handler) *sql.DB {
+	t.Helper()
+	registerOnce.Do(func() { sql.Register("testdb", fakeDriver{}) })
+
+	mu.Lock()
+	seq++
+	dsn := fmt.Sprintf("%s#%d", t.Name(), seq)
+	handlers[dsn] = h
+	mu.Unlock()
+
+	db, err := sql.Open("testdb", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		mu.Lock()
+		delete(handlers, dsn)
+		mu.Unlock()
+	})
+	return db
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(dsn string) (driver.Conn, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	h, ok := handlers[dsn]
+	if !ok {
+		return nil, fmt.Errorf("testdb: 未知的连接 %q", dsn)
+	}
+	return &conn{handle: h}, nil
+}
+
+type conn struct {
+	handle Handler
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{conn: c, query: query}, nil
+}
+
+func (c *conn) Close() error              { return nil }
+func (c *conn) Begin() (driver.Tx, error) { return tx{}, nil }
+
+type tx struct{}
+
+func (tx) Commit() error   { return nil }
+func (tx) Rollback() error { return nil }
+
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+func (s *stmt) Close() error  { return nil }
+func (s *stmt) NumInput() int { return -1 }
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	res, err := s.conn.handle(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	return result{res}, nil
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	res, err := s.conn.handle(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	if res.Rows == nil {
+		return &rows{}, nil
+	}
+	return &rows{Rows: res.Rows}, nil
+}
+
+type result struct {
+	res Result
+}
+
+func (r result) LastInsertId() (int64, error) { return r.res.LastInsertID, nil }
+func (r result) RowsAffected() (int64, error) { return r.res.RowsAffected, nil }
+
+type rows struct {
+	*Rows
+	next int
+}
+
+func (r *rows) Columns() []string {
+	if r.Rows == nil {
+		return nil
+	}
+	return r.Rows.Columns
+}
+
+func (r *rows) Close() error { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.Rows == nil || r.next >= len(r.Values) {
+		return io.EOF
+	}
+	copy(dest, r.Values[r.next])
+	r.next++
+	return nil
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Threshold 附加在任务上的 SLO 阈值，如 p95_response_time < 300
+type Threshold struct {
+	ID       int     `json:"id"`
+	TestID   int     `json:"test_id"`
+	Metric   string  `json:"metric"`
+	Operator string  `json:"operator"` // <、<=、>、>=
+	Value    float64 `json:"value"`
+}
+
+// ThresholdOutcome 单个阈值的评估结果
+type ThresholdOutcome struct {
+	Metric   string  `json:"metric"`
+	Operator string  `json:"operator"`
+	Value    float64 `json:"value"`
+	Actual   float64 `json:"actual"`
+	Passed   bool    `json:"passed"`
+}
+
+// Verdict 一次运行的 SLO 结论
+type Verdict struct {
+	ID        int                `json:"id"`
+	TestID    int                `json:"test_id"`
+	ResultID  int                `json:"result_id"`
+	Passed    bool               `json:"passed"`
+	Details   []ThresholdOutcome `json:"details"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+var thresholdTables = []string{
+	`CREATE TABLE IF NOT EXISTS test_thresholds (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		test_id INT NOT NULL,
+		metric VARCHAR(64) NOT NULL,
+		operator VARCHAR(4) NOT NULL,
+		value DOUBLE NOT NULL,
+		FOREIGN KEY (test_id) REFERENCES load_tests(id)
+	);`,
+	`CREATE TABLE IF NOT EXISTS test_verdicts (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		test_id INT NOT NULL,
+		result_id INT NOT NULL,
+		passed BOOLEAN NOT NULL,
+		details TEXT,
+		created_at DATETIME NOT NULL,
+		FOREIGN KEY (test_id) REFERENCES load_tests(id)
+	);`,
+}
+
+func CreateThreshold(t *Threshold) error {
+	res, err := DB.Exec(
+		"INSERT INTO test_thresholds(test_id, metric, operator, value) VALUES(?,?,?,?)",
+		t.TestID, t.Metric, t.Operator, t.Value,
+	)
+	if err != nil {
+		return err
+	}
+	if id, err := res.LastInsertId(); err == nil {
+		t.ID = int(id)
+	}
+	return nil
+}
+
+func GetThresholdsByTestID(testID int) ([]Threshold, error) {
+	rows, err := DB.Query(
+		"SELECT id, test_id, metric, operator, value FROM test_thresholds WHERE test_id=? ORDER BY id", testID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Threshold
+	for rows.Next() {
+		var t Threshold
+		if err := rows.Scan(&t.ID, &t.TestID, &t.Metric, &t.Operator, &t.Value); err != nil {
+			continue
+		}
+		list = append(list, t)
+	}
+	return list, nil
+}
+
+func CreateVerdict(v *Verdict) error {
+	details, err := json.Marshal(v.Details)
+	if err != nil {
+		return err
+	}
+	if v.CreatedAt.IsZero() {
+		v.CreatedAt = time.Now()
+	}
+	res, err := DB.Exec(
+		"INSERT INTO test_verdicts(test_id, result_id, passed, details, created_at) VALUES(?,?,?,?,?)",
+		v.TestID, v.ResultID, v.Passed, string(details), v.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if id, err := res.LastInsertId(); err == nil {
+		v.ID = int(id)
+	}
+	return nil
+}
+
+// GetLatestVerdict 返回任务最近一次运行的结论
+func GetLatestVerdict(testID int) (Verdict, error) {
+	var (
+		v       Verdict
+		details string
+	)
+	err := DB.QueryRow(
+		`SELECT id, test_id, result_id, passed, details, created_at
+		   FROM test_verdicts WHERE test_id=? ORDER BY id DESC LIMIT 1`, testID,
+	).Scan(&v.ID, &v.TestID, &v.ResultID, &v.Passed, &details, &v.CreatedAt)
+	if err != nil {
+		return v, err
+	}
+	if details != "" {
+		if err := json.Unmarshal([]byte(details), &v.Details); err != nil {
+			return v, err
+		}
+	}
+	return v, nil
+}
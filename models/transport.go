@@ -0,0 +1,46 @@
+package models
+
+// HTTP 协议版本
+const (
+	HTTPVersion11 = "1.1"
+	HTTPVersion2  = "2"
+)
+
+// TransportOptions 任务的传输层选项，零值即 Runner 默认行为：
+// HTTP/1.1、保持连接、每个虚拟用户独立连接、不限超时、跟随重定向
+type TransportOptions struct {
+	TestID           int    `json:"test_id,omitempty"`
+	HTTPVersion      string `json:"http_version"`
+	DisableKeepAlive bool   `json:"disable_keep_alive"`
+	MaxConnsPerHost  int    `json:"max_conns_per_host"` // 大于 0 时所有虚拟用户共享一个该大小的连接池
+	RequestTimeout   int    `json:"request_timeout"`    // 单个请求超时（毫秒），0 表示不限制
+	DisableRedirects bool   `json:"disable_redirects"`
+}
+
+var transportTables = []string{
+	`CREATE TABLE IF NOT EXISTS transport_options (
+		test_id INT PRIMARY KEY,
+		http_version VARCHAR(10) NOT NULL DEFAULT '1.1',
+		disable_keep_alive BOOLEAN NOT NULL DEFAULT FALSE,
+		max_conns_per_host INT NOT NULL DEFAULT 0,
+		request_timeout INT NOT NULL DEFAULT 0,
+		disable_redirects BOOLEAN NOT NULL DEFAULT FALSE,
+		FOREIGN KEY (test_id) REFERENCES load_tests(id)
+	);`,
+}
+
+func CreateTransportOptions(o *TransportOptions) error {
+	_, err := DB.Exec(
+		"INSERT INTO transport_options(test_id, http_version, disable_keep_alive, max_conns_per_host, request_timeout, disable_redirects) VALUES(?,?,?,?,?,?)",
+		o.TestID, o.HTTPVersion, o.DisableKeepAlive, o.MaxConnsPerHost, o.RequestTimeout, o.DisableRedirects,
+	)
+	return err
+}
+
+func GetTransportOptions(testID int) (TransportOptions, error) {
+	var o TransportOptions
+	err := DB.QueryRow(
+		"SELECT test_id, http_version, disable_keep_alive, max_conns_per_host, request_timeout, disable_redirects FROM transport_options WHERE test_id=?", testID,
+	).Scan(&o.TestID, &o.HTTPVersion, &o.DisableKeepAlive, &o.MaxConnsPerHost, &o.RequestTimeout, &o.DisableRedirects)
+	return o, err
+}
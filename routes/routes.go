@@ -9,9 +9,64 @@ func SetupRoutes(r *gin.Engine) {
 	// 注册 / 登录
 	r.POST("/api/register", controllers.Register)
 	r.POST("/api/login", controllers.Login)
+	r.POST("/api/token/refresh", controllers.RefreshToken)
+	r.POST("/api/logout", controllers.Logout)
+	// OIDC 单点登录（授权码 + PKCE）
+	r.GET("/api/oidc/login", controllers.OIDCLogin)
+	r.GET("/api/oidc/callback", controllers.OIDCCallback)
 	// 用户提交任务
 	r.POST("/api/submit", controllers.SubmitLoadTest)
 	r.GET("/api/tasks", controllers.GetUserTasks)
+	// API 令牌（CI 等非交互场景使用，哈希保存，可吊销）
+	r.POST("/api/tokens", controllers.CreateAPIKey)
+	r.GET("/api/tokens", controllers.ListAPIKeys)
+	r.DELETE("/api/tokens", controllers.RevokeAPIKey)
+	// 组织 / 项目与成员角色（viewer、tester、approver、admin）
+	r.POST("/api/orgs", controllers.CreateOrganization)
+	r.GET("/api/orgs", controllers.ListOrganizations)
+	r.GET("/api/orgs/members", controllers.ListOrgMembers)
+	r.POST("/api/orgs/members", controllers.SetOrgMember)
+	r.DELETE("/api/orgs/members", controllers.RemoveOrgMember)
+	r.POST("/api/projects", controllers.CreateProject)
+	r.GET("/api/projects", controllers.ListProjects)
+	r.GET("/api/projects/members", controllers.ListProjectMembers)
+	r.POST("/api/projects/members", controllers.SetProjectMember)
+	r.DELETE("/api/projects/members", controllers.RemoveProjectMember)
+	// 容量探测结论
+	r.GET("/api/capacity_result", controllers.GetCapacityResult)
+	// SLO 阈值与结论
+	r.GET("/api/verdict", controllers.GetVerdict)
+	// 目标请求头 / 认证 / TLS 选项（敏感字段遮蔽）
+	r.GET("/api/target_options", controllers.GetTargetOptions)
+	// 加密 secrets，在目标选项中以 {{secret:name}} 引用
+	r.POST("/api/secrets", controllers.SaveSecret)
+	r.GET("/api/secrets", controllers.ListSecrets)
+	r.DELETE("/api/secrets", controllers.DeleteSecret)
+	// CSV 参数化数据集
+	r.POST("/api/datasets", controllers.UploadDataset)
+	r.GET("/api/datasets", controllers.ListDatasets)
+	// gRPC .proto 描述（未上传时使用服务端反射）与方法统计
+	r.POST("/api/protos", controllers.UploadProto)
+	r.GET("/api/protos", controllers.ListProtos)
+	r.GET("/api/grpc_results", controllers.GetGRPCResults)
+	// 延迟直方图：单次结果各端点，及多次结果合并后重新计算百分位
+	r.GET("/api/histograms", controllers.GetHistograms)
+	r.GET("/api/histograms/merge", controllers.MergeHistograms)
+	// 运行记录与 runner 日志（查看、下载、实时跟踪）
+	r.GET("/api/runs", controllers.ListRuns)
+	r.GET("/api/runs/log", controllers.GetRunLog)
+	// 场景断言统计
+	r.GET("/api/check_results", controllers.GetCheckResults)
+	// 场景导入（?test_id=xxx 时写入任务，否则仅预览）
+	r.POST("/api/import/har", controllers.ImportHAR)
+	r.POST("/api/import/openapi", controllers.ImportOpenAPI)
+	r.POST("/api/import/postman", controllers.ImportPostman)
+	r.POST("/api/import/curl", controllers.ImportCurl)
+	// worker agent 注册、心跳与拉取分配（X-Agent-Token 共享令牌 + 注册时签发的 X-Agent-Credential 认证）
+	r.POST("/api/agents/register", controllers.RegisterAgent)
+	r.POST("/api/agents/heartbeat", controllers.AgentHeartbeat)
+	r.POST("/api/agents/assignment", controllers.GetAgentAssignment)
+	r.POST("/api/agents/secrets", controllers.GetAgentSecrets)
 	// Locust 回调存结果
 	r.POST("/api/upload_result", controllers.SaveTestResult)
 	// 用户下载报告
@@ -21,8 +76,11 @@ func SetupRoutes(r *gin.Engine) {
 func SetupAdminRoutes(r *gin.Engine) {
 	admin := r.Group("/admin")
 	{
-		admin.GET("/tasks", controllers.AdminOnlyMiddleware(), controllers.GetTasksByStatus)
-		admin.POST("/approve", controllers.AdminOnlyMiddleware(), controllers.ApproveLoadTest)
-		admin.POST("/reject", controllers.AdminOnlyMiddleware(), controllers.RejectLoadTest)
+		// 审批相关接口按项目角色（approver 及以上）在处理函数内校验
+		admin.GET("/tasks", controllers.GetTasksByStatus)
+		admin.POST("/approve", controllers.ApproveLoadTest)
+		admin.POST("/reject", controllers.RejectLoadTest)
+		admin.GET("/agents", controllers.AdminOnlyMiddleware(), controllers.ListAgents)
+		admin.POST("/users/revoke_sessions", controllers.AdminOnlyMiddleware(), controllers.RevokeUserSessions)
 	}
 }
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"loadtest_project/models"
+)
+
+// abortPollInterval 读取 stats_history CSV 的间隔，与 Locust 默认写入间隔一致
+const abortPollInterval = time.Second
+
+// abortMonitor 跟踪 stats_history CSV 的新行，判断是否触发中止条件
+type abortMonitor struct {
+	cond models.AbortCondition
+	// 已处理的数据行数（不含表头）
+	seen int
+	// 当前连续超标的起始时间戳，0 表示未超标
+	errorSince int64
+	p99Since   int64
+}
+
+// watchStatsHistory 轮询 Locust 实时写入的 stats_history CSV，触发中止条件时返回原因；
+// done 关闭（进程已结束）时返回空串
+func watchStatsHistory(path string, cond models.AbortCondition, done <-chan struct{}) string {
+	m := &abortMonitor{cond: cond}
+	ticker := time.NewTicker(abortPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return ""
+		case <-ticker.C:
+			if reason := m.poll(path); reason != "" {
+				return reason
+			}
+		}
+	}
+}
+
+// poll 读取文件中尚未处理的完整行并逐行评估
+func (m *abortMonitor) poll(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		// 文件尚未创建
+		return ""
+	}
+	// 只处理完整的行，最后一行可能正在写入
+	if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+		data = data[:i+1]
+	} else {
+		return ""
+	}
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil || len(records) < 2 {
+		return ""
+	}
+	col := make(map[string]int, len(records[0]))
+	for i, name := range records[0] {
+		col[name] = i
+	}
+
+	rows := records[1:]
+	for ; m.seen < len(rows); m.seen++ {
+		if reason := m.evaluate(rows[m.seen], col); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+// evaluate 评估一行 Aggregated 实时数据
+func (m *abortMonitor) evaluate(record []string, col map[string]int) string {
+	field := func(name string) string {
+		if i, ok := col[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+	if field("Name") != "Aggregated" {
+		return ""
+	}
+	ts, err := strconv.ParseInt(field("Timestamp"), 10, 64)
+	if err != nil {
+		return ""
+	}
+	window := int64(m.cond.WindowSeconds)
+
+	if m.cond.MaxErrorRate > 0 {
+		rps, _ := strconv.ParseFloat(field("Requests/s"), 64)
+		fps, _ := strconv.ParseFloat(field("Failures/s"), 64)
+		rate := 0.0
+		if rps > 0 {
+			rate = fps / rps
+		}
+		if rate > m.cond.MaxErrorRate {
+			if m.errorSince == 0 {
+				m.errorSince = ts
+			}
+			if ts-m.errorSince >= window {
+				return fmt.Sprintf("错误率 %.4f 超过 %.4f 持续 %d 秒", rate, m.cond.MaxErrorRate, ts-m.errorSince)
+			}
+		} else {
+			m.errorSince = 0
+		}
+	}
+
+	if m.cond.MaxP99 > 0 {
+		// 尚无请求时该列为 N/A，解析失败视为未超标
+		p99, err := strconv.ParseFloat(field("99%"), 64)
+		if err == nil && p99 > m.cond.MaxP99 {
+			if m.p99Since == 0 {
+				m.p99Since = ts
+			}
+			if ts-m.p99Since >= window {
+				return fmt.Sprintf("p99 %.0fms 超过 %.0fms 持续 %d 秒", p99, m.cond.MaxP99, ts-m.p99Since)
+			}
+		} else {
+			m.p99Since = 0
+		}
+	}
+	return ""
+}
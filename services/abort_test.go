@@ -0,0 +1,167 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"loadtest_project/models"
+)
+
+const abortHistoryHeader = "Timestamp,User Count,Type,Name,Requests/s,Failures/s,99%\n"
+
+var abortHistoryColumns = map[string]int{"Timestamp": 0, "User Count": 1, "Type": 2, "Name": 3, "Requests/s": 4, "Failures/s": 5, "99%": 6}
+
+// historyRow 构造一行 stats_history 数据：时间戳、每秒请求数、每秒失败数与 p99
+func historyRow(ts, rps, fps, p99 string) []string {
+	return []string{ts, "10", "", "Aggregated", rps, fps, p99}
+}
+
+func TestAbortMonitorEvaluate(t *testing.T) {
+	errorCond := models.AbortCondition{MaxErrorRate: 0.1, WindowSeconds: 3}
+	p99Cond := models.AbortCondition{MaxP99: 1000, WindowSeconds: 2}
+	tests := []struct {
+		name       string
+		cond       models.AbortCondition
+		rows       [][]string
+		wantRow    int    // 触发中止的行，-1 表示不触发
+		wantReason string // 原因中应包含的片段
+	}{
+		{
+			name: "错误率持续超标满窗口后中止",
+			cond: errorCond,
+			rows: [][]string{
+				historyRow("1000", "10", "2", "100"),
+				historyRow("1001", "10", "2", "100"),
+				historyRow("1002", "10", "2", "100"),
+				historyRow("1003", "10", "2", "100"),
+			},
+			wantRow:    3,
+			wantReason: "持续 3 秒",
+		},
+		{
+			name: "未满窗口不中止",
+			cond: errorCond,
+			rows: [][]string{
+				historyRow("1000", "10", "2", "100"),
+				historyRow("1002", "10", "2", "100"),
+			},
+			wantRow: -1,
+		},
+		{
+			name: "回落到阈值内后重新计时",
+			cond: errorCond,
+			rows: [][]string{
+				historyRow("1000", "10", "2", "100"),
+				historyRow("1002", "10", "2", "100"),
+				historyRow("1003", "10", "1", "100"), // 错误率恰好等于阈值，不算超标
+				historyRow("1004", "10", "2", "100"),
+				historyRow("1006", "10", "2", "100"),
+				historyRow("1007", "10", "2", "100"),
+			},
+			wantRow:    5,
+			wantReason: "持续 3 秒",
+		},
+		{
+			name:       "窗口为 0 时立即中止",
+			cond:       models.AbortCondition{MaxErrorRate: 0.1},
+			rows:       [][]string{historyRow("1000", "10", "5", "100")},
+			wantRow:    0,
+			wantReason: "错误率 0.5000",
+		},
+		{
+			name:    "没有请求时错误率为 0",
+			cond:    models.AbortCondition{MaxErrorRate: 0.1},
+			rows:    [][]string{historyRow("1000", "0", "0", "N/A")},
+			wantRow: -1,
+		},
+		{
+			name: "p99 持续超标后中止",
+			cond: p99Cond,
+			rows: [][]string{
+				historyRow("1000", "10", "0", "1500"),
+				historyRow("1001", "10", "0", "1200"),
+				historyRow("1002", "10", "0", "1100"),
+			},
+			wantRow:    2,
+			wantReason: "p99 1100ms",
+		},
+		{
+			name: "p99 为 N/A 视为未超标并重新计时",
+			cond: p99Cond,
+			rows: [][]string{
+				historyRow("1000", "10", "0", "1500"),
+				historyRow("1001", "0", "0", "N/A"),
+				historyRow("1002", "10", "0", "1500"),
+				historyRow("1003", "10", "0", "1500"),
+			},
+			wantRow: -1,
+		},
+		{
+			name: "只评估 Aggregated 行",
+			cond: models.AbortCondition{MaxP99: 1000},
+			rows: [][]string{
+				{"1000", "10", "GET", "/slow", "10", "0", "5000"},
+				historyRow("1000", "10", "0", "200"),
+			},
+			wantRow: -1,
+		},
+		{
+			name:    "时间戳无法解析的行被跳过",
+			cond:    models.AbortCondition{MaxP99: 1000},
+			rows:    [][]string{historyRow("", "10", "0", "5000")},
+			wantRow: -1,
+		},
+	}
+	for _, tt := range tests {
+		m := &abortMonitor{cond: tt.cond}
+		got, reason := -1, ""
+		for i, row := range tt.rows {
+			if reason = m.evaluate(row, abortHistoryColumns); reason != "" {
+				got = i
+				break
+			}
+		}
+		if got != tt.wantRow || !strings.Contains(reason, tt.wantReason) {
+			t.Errorf("%s: 在第 %d 行触发（%q），want 第 %d 行（包含 %q）", tt.name, got, reason, tt.wantRow, tt.wantReason)
+		}
+	}
+}
+
+func TestAbortMonitorPollPartialLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats_history.csv")
+	m := &abortMonitor{cond: models.AbortCondition{MaxP99: 1000}}
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if reason := m.poll(path); reason != "" {
+		t.Fatalf("文件尚未创建时不应中止，got %q", reason)
+	}
+	// Locust 正在写入第二行：已写出的 "10" 不是完整的 p99
+	content := abortHistoryHeader + "1000,10,,Aggregated,10,0,200\n" + "1001,10,,Aggregated,10,0,10"
+	write(content)
+	if reason := m.poll(path); reason != "" || m.seen != 1 {
+		t.Fatalf("只应处理完整的行，got reason=%q seen=%d", reason, m.seen)
+	}
+	write(content + "00\n")
+	if reason := m.poll(path); reason != "" || m.seen != 2 {
+		t.Fatalf("补全的行 p99 为 1000，不应中止，got reason=%q seen=%d", reason, m.seen)
+	}
+	write(content + "00\n" + "1002,10,,Aggregated,10,0,2500\n")
+	if reason := m.poll(path); !strings.Contains(reason, "p99 2500ms") {
+		t.Errorf("新的完整行超标应中止，got %q", reason)
+	}
+
+	// 只有表头或表头尚未写完时不做评估
+	m = &abortMonitor{cond: models.AbortCondition{MaxP99: 1}}
+	for _, content := range []string{"Timestamp,Na", abortHistoryHeader} {
+		write(content)
+		if reason := m.poll(path); reason != "" || m.seen != 0 {
+			t.Errorf("%q: 不应评估任何行，got reason=%q seen=%d", content, reason, m.seen)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"loadtest_project/models"
+)
+
+// RunCapacitySearch 阶梯加压：每阶段以固定并发运行 StepDuration 秒，
+// 直到 p95 或错误率违反阈值，最后记录最大可持续负载与整条曲线
+func RunCapacitySearch(task models.LoadTest) {
+	cfg, err := models.GetCapacityConfig(task.ID)
+	if err != nil {
+		fmt.Println("读取容量探测配置失败:", err)
+		models.FailLoadTest(task.ID, "读取容量探测配置失败")
+		return
+	}
+	if cfg.StepUsers <= 0 || cfg.StepDuration <= 0 || cfg.MaxUsers < cfg.StartUsers {
+		fmt.Printf("任务 %d 容量探测配置无效: %+v\n", task.ID, cfg)
+		models.FailLoadTest(task.ID, "容量探测配置无效")
+		return
+	}
+	_ = os.MkdirAll(resultsDir, 0755)
+
+	var (
+		result    = models.CapacityResult{TestID: task.ID}
+		lastGood  locustStats
+		lastStep  string // 最后一个合格阶段的 CSV 前缀
+		aborted   string
+		stepTime  = time.Duration(cfg.StepDuration) * time.Second
+		timestamp = time.Now().Unix()
+	)
+	for users := cfg.StartUsers; users <= cfg.MaxUsers; users += cfg.StepUsers {
+		// 整个探测仍受任务结束时间约束
+		if time.Now().Add(stepTime).After(task.EndTime) {
+			result.StopReason = "已到任务结束时间"
+			break
+		}
+
+		prefix := fmt.Sprintf("task_%d_%d_step_%d", task.ID, timestamp, users)
+		// spawn rate 取并发数本身，使每阶段尽快达到目标并发
+		abortReason, err := runLocust(task, users, users, stepTime, prefix)
+		if err != nil {
+			fmt.Printf("容量探测阶段 %d 运行失败: %v\n", users, err)
+			if errors.Is(err, errRunTimeout) {
+				models.TimeoutLoadTest(task.ID, fmt.Sprintf("阶段 %d: %v", users, err))
+			} else {
+				models.FailLoadTest(task.ID, fmt.Sprintf("阶段 %d: %v", users, err))
+			}
+			return
+		}
+		if abortReason != "" {
+			// 中止条件优先于 SLO 判定：目标已不可用，直接结束整个探测
+			aborted = abortReason
+			result.BreakingUsers = users
+			result.StopReason = "已中止: " + abortReason
+			break
+		}
+		stats, err := parseLocustStats(filepath.Join(resultsDir, prefix+"_stats.csv"))
+		if err != nil {
+			fmt.Println("解析 CSV 失败:", err)
+			models.FailLoadTest(task.ID, fmt.Sprintf("阶段 %d: 解析结果 CSV 失败", users))
+			return
+		}
+
+		step := models.CapacityStep{
+			Users:     users,
+			RPS:       round4(stats.RPS),
+			P95:       round4(stats.P95),
+			ErrorRate: round4(stats.ErrorRate()),
+		}
+		reason := capacityViolation(cfg, stats)
+		step.Passed = reason == ""
+		result.Curve = append(result.Curve, step)
+
+		if !step.Passed {
+			result.BreakingUsers = users
+			result.StopReason = reason
+			break
+		}
+		result.MaxUsers = users
+		result.MaxRPS = step.RPS
+		lastGood = stats
+		lastStep = prefix
+	}
+	if len(result.Curve) == 0 && aborted == "" {
+		// 审批或调度延迟后剩余时间不足一个阶段，没有任何数据，不能记为完成
+		fmt.Printf("任务 %d 容量探测未运行任何阶段: %s\n", task.ID, result.StopReason)
+		models.FailLoadTest(task.ID, "剩余时间不足一个阶段，未运行任何阶段")
+		return
+	}
+	if result.StopReason == "" {
+		result.StopReason = "已达到最大并发数"
+	}
+
+	if err := models.CreateCapacityResult(&result); err != nil {
+		fmt.Println("写入容量探测结果失败:", err)
+		models.FailLoadTest(task.ID, "写入容量探测结果失败")
+		return
+	}
+	// 同时以最后一个合格阶段写入常规结果，便于报告下载
+	if result.MaxUsers > 0 {
+		checks, err := readCheckResults(lastStep)
+		if err != nil {
+			fmt.Println(err)
+		}
+		grpcMethods, err := grpcMethodResults(lastGood, lastStep)
+		if err != nil {
+			fmt.Println(err)
+		}
+		histograms, err := readHistograms(lastStep)
+		if err != nil {
+			fmt.Println(err)
+		}
+		warnings, err := readGeneratorWarnings(lastStep)
+		if err != nil {
+			fmt.Println(err)
+		}
+		ws, err := readWebSocketMetrics(lastStep, stepTime)
+		if err != nil {
+			fmt.Println(err)
+		}
+		tr := buildTestResult(task, lastGood, ws, stepTime)
+		tr.CheckPassRate = checkPassRate(checks)
+		tr.GeneratorWarnings = warnings
+		if err := saveRunResult(lastStep, &tr); err != nil {
+			fmt.Println("写入测试结果失败:", err)
+		} else {
+			saveCheckResults(tr, checks)
+			saveGRPCResults(tr, grpcMethods)
+			saveHistograms(tr, histograms)
+			RecordVerdict(tr)
+		}
+	}
+
+	if aborted != "" {
+		models.AbortLoadTest(task.ID, aborted)
+		fmt.Printf("任务 %d 容量探测已中止: %s\n", task.ID, aborted)
+		return
+	}
+	models.UpdateLoadTestStatus(task.ID, "completed")
+	fmt.Printf("任务 %d 容量探测完成: 最大并发 %d, RPS %.2f (%s)\n",
+		task.ID, result.MaxUsers, result.MaxRPS, result.StopReason)
+}
+
+// capacityViolation 返回违反 SLO 的原因，未违反时返回空串
+func capacityViolation(cfg models.CapacityConfig, stats locustStats) string {
+	if cfg.MaxP95 > 0 && stats.P95 > cfg.MaxP95 {
+		return fmt.Sprintf("p95 %.0fms 超过阈值 %.0fms", stats.P95, cfg.MaxP95)
+	}
+	if cfg.MaxErrorRate > 0 && stats.ErrorRate() > cfg.MaxErrorRate {
+		return fmt.Sprintf("错误率 %.4f 超过阈值 %.4f", stats.ErrorRate(), cfg.MaxErrorRate)
+	}
+	return ""
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"loadtest_project/models"
+	"loadtest_project/models/testdb"
+)
+
+func TestCapacityViolation(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   models.CapacityConfig
+		stats locustStats
+		want  string // 原因中应包含的片段，空表示未违反
+	}{
+		{"p95 等于阈值不算违反", models.CapacityConfig{MaxP95: 500}, locustStats{P95: 500, TotalRequests: 100}, ""},
+		{"p95 超过阈值", models.CapacityConfig{MaxP95: 500}, locustStats{P95: 500.5, TotalRequests: 100}, "p95"},
+		{"错误率等于阈值不算违反", models.CapacityConfig{MaxErrorRate: 0.01}, locustStats{TotalRequests: 100, Failures: 1}, ""},
+		{"错误率超过阈值", models.CapacityConfig{MaxErrorRate: 0.01}, locustStats{TotalRequests: 100, Failures: 2}, "错误率"},
+		{"p95 阈值为 0 表示不限制", models.CapacityConfig{MaxErrorRate: 0.5}, locustStats{P95: 1e6, TotalRequests: 100}, ""},
+		{"错误率阈值为 0 表示不限制", models.CapacityConfig{MaxP95: 500}, locustStats{TotalRequests: 100, Failures: 100}, ""},
+		{"两者都违反时报告 p95", models.CapacityConfig{MaxP95: 500, MaxErrorRate: 0.01}, locustStats{P95: 900, TotalRequests: 10, Failures: 5}, "p95"},
+		{"没有请求时错误率为 0", models.CapacityConfig{MaxErrorRate: 0.01}, locustStats{}, ""},
+	}
+	for _, tt := range tests {
+		got := capacityViolation(tt.cfg, tt.stats)
+		if (tt.want == "") != (got == "") || !strings.Contains(got, tt.want) {
+			t.Errorf("%s: capacityViolation = %q, want 包含 %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// capacityStore 返回固定的容量配置，记录任务状态的变更
+type capacityStore struct {
+	cfg      models.CapacityConfig
+	statuses []string
+	results  int
+}
+
+func (s *capacityStore) handle(query string, args []driver.Value) (testdb.Result, error) {
+	switch {
+	case strings.Contains(query, "FROM capacity_configs WHERE test_id=?"):
+		c := s.cfg
+		return testdb.Row(
+			[]string{"test_id", "start_users", "step_users", "step_duration", "max_users", "max_p95", "max_error_rate"},
+			int64(c.TestID), int64(c.StartUsers), int64(c.StepUsers), int64(c.StepDuration), int64(c.MaxUsers), c.MaxP95, c.MaxErrorRate,
+		), nil
+	case strings.HasPrefix(query, "UPDATE load_tests SET status="):
+		s.statuses = append(s.statuses, query+" "+args[0].(string))
+	case strings.HasPrefix(query, "INSERT INTO capacity_results"):
+		s.results++
+	}
+	return testdb.Result{RowsAffected: 1}, nil
+}
+
+func TestRunCapacitySearchWithoutSteps(t *testing.T) {
+	t.Chdir(t.TempDir())
+	tests := []struct {
+		name       string
+		cfg        models.CapacityConfig
+		endTime    time.Time
+		wantReason string
+	}{
+		{
+			name:       "剩余时间不足一个阶段，没有运行任何阶段",
+			cfg:        models.CapacityConfig{TestID: 1, StartUsers: 10, StepUsers: 10, StepDuration: 60, MaxUsers: 50, MaxP95: 500},
+			endTime:    time.Now().Add(30 * time.Second),
+			wantReason: "未运行任何阶段",
+		},
+		{
+			name:       "结束时间已过",
+			cfg:        models.CapacityConfig{TestID: 1, StartUsers: 10, StepUsers: 10, StepDuration: 60, MaxUsers: 50, MaxP95: 500},
+			endTime:    time.Now().Add(-time.Minute),
+			wantReason: "未运行任何阶段",
+		},
+		{
+			name:       "配置无效",
+			cfg:        models.CapacityConfig{TestID: 1, StartUsers: 10, StepUsers: 0, StepDuration: 60, MaxUsers: 50},
+			endTime:    time.Now().Add(time.Hour),
+			wantReason: "容量探测配置无效",
+		},
+	}
+	for _, tt := range tests {
+		store := &capacityStore{cfg: tt.cfg}
+		orig := models.DB
+		models.DB = testdb.Open(t, store.handle)
+		RunCapacitySearch(models.LoadTest{ID: 1, TestType: models.TestTypeCapacity, EndTime: tt.endTime})
+		models.DB = orig
+
+		if len(store.statuses) != 1 || !strings.Contains(store.statuses[0], "'failed'") || !strings.Contains(store.statuses[0], tt.wantReason) {
+			t.Errorf("%s: 任务应标记为失败并说明 %q，got %v", tt.name, tt.wantReason, store.statuses)
+		}
+		if store.results != 0 {
+			t.Errorf("%s: 没有运行任何阶段时不应记录容量结果", tt.name)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"loadtest_project/models"
+)
+
+// checksFile 本次运行的断言统计文件
+func checksFile(prefix string) string {
+	return filepath.Join(resultsDir, prefix+"_checks.json")
+}
+
+func hasChecks(steps []RunnerStep) bool {
+	for _, s := range steps {
+		if len(s.Checks) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// readCheckResults 读取 Runner 写出的断言统计，文件不存在时返回 nil
+func readCheckResults(prefix string) ([]models.CheckResult, error) {
+	data, err := os.ReadFile(checksFile(prefix))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var checks []models.CheckResult
+	if err := json.Unmarshal(data, &checks); err != nil {
+		return nil, fmt.Errorf("解析断言统计失败: %w", err)
+	}
+	return checks, nil
+}
+
+// checkPassRate 全部断言的总体通过率，没有断言时为 1
+func checkPassRate(checks []models.CheckResult) float64 {
+	var passes, total int
+	for _, c := range checks {
+		passes += c.Passes
+		total += c.Passes + c.Fails
+	}
+	if total == 0 {
+		return 1
+	}
+	return round4(float64(passes) / float64(total))
+}
+
+// saveCheckResults 将断言统计关联到已保存的结果
+func saveCheckResults(result models.TestResult, checks []models.CheckResult) {
+	for i := range checks {
+		checks[i].TestID = result.TestID
+		checks[i].ResultID = result.ID
+		if err := models.CreateCheckResult(&checks[i]); err != nil {
+			fmt.Println("写入断言统计失败:", err)
+		}
+	}
+}
@@ -0,0 +1,330 @@
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"loadtest_project/models"
+)
+
+// curlIgnoredFlags 对压测无意义、且不带参数的 curl 选项
+var curlIgnoredFlags = map[string]bool{
+	"-s": true, "--silent": true, "-S": true, "--show-error": true, "-k": true, "--insecure": true,
+	"-L": true, "--location": true, "-v": true, "--verbose": true, "-i": true, "--include": true,
+	"--compressed": true, "-f": true, "--fail": true, "-#": true, "--progress-bar": true,
+}
+
+// curlIgnoredArgFlags 对压测无意义、且带一个参数的 curl 选项
+var curlIgnoredArgFlags = map[string]bool{
+	"-o": true, "--output": true, "-m": true, "--max-time": true, "--connect-timeout": true,
+	"-w": true, "--write-out": true, "--retry": true, "-A": true, "--user-agent": true,
+}
+
+// curlShortArgFlags 带一个参数的单字母选项，合并写法中其后的字符即为参数（如 -XPOST）
+var curlShortArgFlags = map[byte]bool{
+	'X': true, 'H': true, 'd': true, 'u': true, 'b': true, 'e': true, 'o': true, 'm': true, 'w': true, 'A': true,
+}
+
+// ImportCurl 将多条 curl 命令（支持 \ 续行）转换为场景步骤
+func ImportCurl(text, baseURL string) ([]models.ScenarioStep, error) {
+	var steps []models.ScenarioStep
+	for i, command := range splitCurlCommands(text) {
+		step, err := parseCurl(command, baseURL)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 条 curl: %w", i+1, err)
+		}
+		steps = append(steps, step)
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("没有 curl 命令")
+	}
+	return steps, nil
+}
+
+// splitCurlCommands 合并续行后按以 curl 开头的行拆分命令
+func splitCurlCommands(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\\\n", " ")
+
+	var (
+		commands []string
+		current  strings.Builder
+	)
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "curl ") || trimmed == "curl" {
+			if current.Len() > 0 {
+				commands = append(commands, current.String())
+				current.Reset()
+			}
+		}
+		if trimmed != "" {
+			current.WriteString(trimmed)
+			current.WriteByte(' ')
+		}
+	}
+	if current.Len() > 0 {
+		commands = append(commands, current.String())
+	}
+	return commands
+}
+
+// parseCurl 解析单条 curl 命令
+func parseCurl(command, baseURL string) (models.ScenarioStep, error) {
+	var step models.ScenarioStep
+	args, err := shellSplit(command)
+	if err != nil {
+		return step, err
+	}
+	if len(args) == 0 || args[0] != "curl" {
+		return step, fmt.Errorf("不是 curl 命令")
+	}
+
+	var (
+		rawURL   string
+		data     []string
+		getMode  bool
+		headMode bool
+	)
+	next := func(i *int) (string, error) {
+		if *i+1 >= len(args) {
+			return "", fmt.Errorf("选项 %s 缺少参数", args[*i])
+		}
+		*i++
+		return args[*i], nil
+	}
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		// 支持 --data=xxx 形式
+		if strings.HasPrefix(arg, "--") && strings.Contains(arg, "=") {
+			kv := strings.SplitN(arg, "=", 2)
+			args = append(args[:i], append([]string{kv[0], kv[1]}, args[i+1:]...)...)
+			arg = kv[0]
+		}
+		// 合并的单字母选项：-sS、-XPOST、-sSXPOST
+		if len(arg) > 2 && arg[0] == '-' && arg[1] != '-' {
+			args = append(args[:i], append(splitShortFlags(arg), args[i+1:]...)...)
+			arg = args[i]
+		}
+		switch {
+		case arg == "-X" || arg == "--request":
+			v, err := next(&i)
+			if err != nil {
+				return step, err
+			}
+			step.Method = strings.ToUpper(v)
+		case arg == "-H" || arg == "--header":
+			v, err := next(&i)
+			if err != nil {
+				return step, err
+			}
+			name, value, ok := strings.Cut(v, ":")
+			if ok && !skippedDefinedHeaders[strings.ToLower(strings.TrimSpace(name))] {
+				setStepHeader(&step, strings.TrimSpace(name), strings.TrimSpace(value))
+			}
+		case arg == "-d" || arg == "--data" || arg == "--data-raw" || arg == "--data-binary" || arg == "--data-ascii":
+			v, err := next(&i)
+			if err != nil {
+				return step, err
+			}
+			// 除 --data-raw 外，@ 开头表示从文件读取
+			if strings.HasPrefix(v, "@") && arg != "--data-raw" {
+				return step, fmt.Errorf("不支持从文件读取请求体（%s %s），请直接填入内容", arg, v)
+			}
+			data = append(data, v)
+		case arg == "--data-urlencode":
+			v, err := next(&i)
+			if err != nil {
+				return step, err
+			}
+			if name, value, ok := strings.Cut(v, "="); ok {
+				data = append(data, name+"="+url.QueryEscape(value))
+			} else if strings.Contains(v, "@") {
+				return step, fmt.Errorf("不支持从文件读取请求体（%s %s），请直接填入内容", arg, v)
+			} else {
+				data = append(data, url.QueryEscape(v))
+			}
+		case arg == "--json":
+			v, err := next(&i)
+			if err != nil {
+				return step, err
+			}
+			if strings.HasPrefix(v, "@") {
+				return step, fmt.Errorf("不支持从文件读取请求体（%s %s），请直接填入内容", arg, v)
+			}
+			data = append(data, v)
+			setStepHeader(&step, "Content-Type", "application/json")
+			setStepHeader(&step, "Accept", "application/json")
+		case arg == "-u" || arg == "--user":
+			v, err := next(&i)
+			if err != nil {
+				return step, err
+			}
+			setStepHeader(&step, "Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(v)))
+		case arg == "-b" || arg == "--cookie":
+			v, err := next(&i)
+			if err != nil {
+				return step, err
+			}
+			setStepHeader(&step, "Cookie", v)
+		case arg == "-e" || arg == "--referer":
+			v, err := next(&i)
+			if err != nil {
+				return step, err
+			}
+			setStepHeader(&step, "Referer", v)
+		case arg == "--url":
+			v, err := next(&i)
+			if err != nil {
+				return step, err
+			}
+			rawURL = v
+		case arg == "-G" || arg == "--get":
+			getMode = true
+		case arg == "-I" || arg == "--head":
+			headMode = true
+		case curlIgnoredFlags[arg]:
+		case curlIgnoredArgFlags[arg]:
+			if _, err := next(&i); err != nil {
+				return step, err
+			}
+		case strings.HasPrefix(arg, "-"):
+			return step, fmt.Errorf("不支持的选项 %s", arg)
+		default:
+			rawURL = arg
+		}
+	}
+	if rawURL == "" {
+		return step, fmt.Errorf("缺少 URL")
+	}
+
+	body := strings.Join(data, "&")
+	if getMode && body != "" {
+		// -G 把数据拼到查询串
+		sep := "?"
+		if strings.Contains(rawURL, "?") {
+			sep = "&"
+		}
+		rawURL += sep + body
+		body = ""
+	}
+	switch {
+	case step.Method != "":
+	case headMode:
+		step.Method = "HEAD"
+	case body != "":
+		step.Method = "POST"
+	default:
+		step.Method = "GET"
+	}
+	if body != "" {
+		step.Body = body
+		if step.Headers["Content-Type"] == "" {
+			setStepHeader(&step, "Content-Type", "application/x-www-form-urlencoded")
+		}
+	}
+	if !strings.Contains(rawURL, "://") && !strings.HasPrefix(rawURL, "/") {
+		rawURL = "http://" + rawURL
+	}
+	step.Path = importedURL(rawURL, baseURL)
+	if u, err := url.Parse(rawURL); err == nil {
+		step.Name = step.Method + " " + u.Path
+	} else {
+		step.Name = step.Method + " " + rawURL
+	}
+	return step, nil
+}
+
+// splitShortFlags 拆开合并的单字母选项：-sS → -s -S；遇到带参数的选项时，
+// 其后的字符作为参数：-XPOST → -X POST
+func splitShortFlags(arg string) []string {
+	var out []string
+	for j := 1; j < len(arg); j++ {
+		out = append(out, "-"+arg[j:j+1])
+		if curlShortArgFlags[arg[j]] {
+			if j+1 < len(arg) {
+				out = append(out, arg[j+1:])
+			}
+			break
+		}
+	}
+	return out
+}
+
+// shellSplit 按 POSIX shell 规则拆分参数，支持单引号、双引号、$'...' 和反斜杠转义
+func shellSplit(s string) ([]string, error) {
+	var (
+		args    []string
+		cur     strings.Builder
+		inArg   bool
+		runes   = []rune(s)
+		escapes = map[rune]rune{'n': '\n', 't': '\t', 'r': '\r', '\\': '\\', '\'': '\'', '"': '"'}
+	)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		case r == '\\':
+			inArg = true
+			if i+1 < len(runes) {
+				i++
+				cur.WriteRune(runes[i])
+			}
+		case r == '$' && i+1 < len(runes) && runes[i+1] == '\'':
+			// ANSI-C 引号 $'...'
+			inArg = true
+			i += 2
+			for ; i < len(runes) && runes[i] != '\''; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					if e, ok := escapes[runes[i]]; ok {
+						cur.WriteRune(e)
+					} else {
+						cur.WriteRune('\\')
+						cur.WriteRune(runes[i])
+					}
+					continue
+				}
+				cur.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("引号未闭合")
+			}
+		case r == '\'':
+			inArg = true
+			i++
+			for ; i < len(runes) && runes[i] != '\''; i++ {
+				cur.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("引号未闭合")
+			}
+		case r == '"':
+			inArg = true
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("\"\\$`\n", runes[i+1]) {
+					i++
+				}
+				cur.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("引号未闭合")
+			}
+		default:
+			inArg = true
+			cur.WriteRune(r)
+		}
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
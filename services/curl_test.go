@@ -0,0 +1,122 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCurl(t *testing.T) {
+	tests := []struct {
+		name        string
+		command     string
+		wantMethod  string
+		wantPath    string
+		wantBody    string
+		wantHeaders map[string]string
+		wantErr     string
+	}{
+		{
+			name:       "GET 与同源地址",
+			command:    `curl https://api.example.com/users?page=1`,
+			wantMethod: "GET", wantPath: "/users?page=1",
+		},
+		{
+			name:       "-d 默认 POST 表单",
+			command:    `curl -H 'X-Trace: 1' -d 'a=1' -d 'b=2' https://api.example.com/form`,
+			wantMethod: "POST", wantPath: "/form", wantBody: "a=1&b=2",
+			wantHeaders: map[string]string{"X-Trace": "1", "Content-Type": "application/x-www-form-urlencoded"},
+		},
+		{
+			name:       "-XPOST 合写",
+			command:    `curl -XPUT https://api.example.com/items/1`,
+			wantMethod: "PUT", wantPath: "/items/1",
+		},
+		{
+			name:       "合并的单字母选项",
+			command:    `curl -sSL -k https://api.example.com/health`,
+			wantMethod: "GET", wantPath: "/health",
+		},
+		{
+			name:       "合并选项末尾带参数",
+			command:    `curl -sSXDELETE https://api.example.com/items/1`,
+			wantMethod: "DELETE", wantPath: "/items/1",
+		},
+		{
+			name:       "--json",
+			command:    `curl --json '{"a":1}' https://api.example.com/j`,
+			wantMethod: "POST", wantPath: "/j", wantBody: `{"a":1}`,
+			wantHeaders: map[string]string{"Content-Type": "application/json", "Accept": "application/json"},
+		},
+		{
+			name:       "-G 数据拼入查询串",
+			command:    `curl -G --data-urlencode 'q=a b' https://api.example.com/search`,
+			wantMethod: "GET", wantPath: "/search?q=a+b",
+		},
+		{
+			name:       "-u 生成 Basic 认证",
+			command:    `curl -u admin:secret https://api.example.com/`,
+			wantMethod: "GET", wantPath: "/",
+			wantHeaders: map[string]string{"Authorization": "Basic YWRtaW46c2VjcmV0"},
+		},
+		{
+			name:       "--data-raw 的 @ 不是文件",
+			command:    `curl --data-raw '@me' https://api.example.com/m`,
+			wantMethod: "POST", wantPath: "/m", wantBody: "@me",
+			wantHeaders: map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+		},
+		{name: "-d @file", command: `curl -d @body.json https://api.example.com/`, wantErr: "不支持从文件读取请求体"},
+		{name: "--data-urlencode name@file", command: `curl --data-urlencode 'q@q.txt' https://api.example.com/`, wantErr: "不支持从文件读取请求体"},
+		{name: "不支持的选项", command: `curl -Z https://api.example.com/`, wantErr: "不支持的选项 -Z"},
+		{name: "缺少 URL", command: `curl -X POST`, wantErr: "缺少 URL"},
+		{name: "选项缺少参数", command: `curl https://api.example.com/ -H`, wantErr: "缺少参数"},
+		{name: "引号未闭合", command: `curl 'https://api.example.com/`, wantErr: "引号未闭合"},
+	}
+	for _, tt := range tests {
+		step, err := parseCurl(tt.command, "https://api.example.com")
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: 意外错误 %v", tt.name, err)
+			continue
+		}
+		if step.Method != tt.wantMethod || step.Path != tt.wantPath || step.Body != tt.wantBody {
+			t.Errorf("%s: got %s %s body=%q, want %s %s body=%q",
+				tt.name, step.Method, step.Path, step.Body, tt.wantMethod, tt.wantPath, tt.wantBody)
+		}
+		if tt.wantHeaders != nil && !reflect.DeepEqual(step.Headers, tt.wantHeaders) {
+			t.Errorf("%s: headers = %v, want %v", tt.name, step.Headers, tt.wantHeaders)
+		}
+	}
+}
+
+func TestSplitCurlCommands(t *testing.T) {
+	text := "curl https://a.example.com/1 \\\n  -H 'X: 1'\r\n\ncurl https://a.example.com/2\n"
+	got := splitCurlCommands(text)
+	want := []string{"curl https://a.example.com/1    -H 'X: 1' ", "curl https://a.example.com/2 "}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("splitCurlCommands = %q, want %q", got, want)
+	}
+}
+
+func TestShellSplit(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{`curl -H 'a b' "c d"`, []string{"curl", "-H", "a b", "c d"}},
+		{`a\ b "x\"y" 'it'\''s'`, []string{"a b", `x"y`, "it's"}},
+		{`$'line\nbreak'`, []string{"line\nbreak"}},
+		{`  `, nil},
+	}
+	for _, tt := range tests {
+		got, err := shellSplit(tt.in)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("shellSplit(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
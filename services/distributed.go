@@ -0,0 +1,315 @@
+package services
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"loadtest_project/models"
+)
+
+// AgentOnlineWindow 超过该时间没有心跳的 agent 视为离线
+const AgentOnlineWindow = 30 * time.Second
+
+// expectWorkersWait master 等待全部 worker 连接的最长秒数，超时则本次运行失败
+const expectWorkersWait = 60
+
+// WorkerLocustfile 分配中 locustfile 的文件名，worker 在工作目录下以此名称运行
+const WorkerLocustfile = "locustfile.py"
+
+// WorkerAssignment 下发给 agent 的一次运行分配。Config 中的文件路径均为
+// Files 中的文件名，worker 在同一工作目录下运行即可。Config 与 Files 中的 secret
+// 保留 {{secret:名称}} 引用，明文由 agent 通过 AgentSecrets 单独拉取
+type WorkerAssignment struct {
+	RunID      string            `json:"run_id"`
+	TestID     int               `json:"test_id"`
+	Users      int               `json:"users"` // 该 worker 预计承担的虚拟用户数
+	MasterPort int               `json:"master_port"`
+	Config     []byte            `json:"config"`
+	Files      map[string][]byte `json:"files"`
+
+	secrets map[string]string // 配置引用的 secret 明文，不随分配下发
+}
+
+// assignments 各 agent 当前的分配（agent -> 运行 -> 分配），仅在运行期间保存在内存中。
+// 一个 agent 可同时承担多个运行，只要虚拟用户总数不超过其上限
+var assignments = struct {
+	sync.Mutex
+	byAgent map[int]map[string]*WorkerAssignment
+}{byAgent: make(map[int]map[string]*WorkerAssignment)}
+
+// AgentRunIDs 返回分配给 agent 的全部运行
+func AgentRunIDs(agentID int) []string {
+	assignments.Lock()
+	defer assignments.Unlock()
+	var ids []string
+	for id := range assignments.byAgent[agentID] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// AgentAssignment 返回 agent 上指定运行的分配，没有时返回 nil
+func AgentAssignment(agentID int, runID string) *WorkerAssignment {
+	assignments.Lock()
+	defer assignments.Unlock()
+	return assignments.byAgent[agentID][runID]
+}
+
+// AgentSecrets 返回 agent 上指定运行引用的 secret 明文，没有该分配时返回 false
+func AgentSecrets(agentID int, runID string) (map[string]string, bool) {
+	assignments.Lock()
+	defer assignments.Unlock()
+	as := assignments.byAgent[agentID][runID]
+	if as == nil {
+		return nil, false
+	}
+	return as.secrets, true
+}
+
+// AgentAssignedUsers 返回 agent 上已分配的虚拟用户总数
+func AgentAssignedUsers(agentID int) int {
+	assignments.Lock()
+	defer assignments.Unlock()
+	return assignedUsers(agentID)
+}
+
+func assignedUsers(agentID int) int {
+	total := 0
+	for _, as := range assignments.byAgent[agentID] {
+		total += as.Users
+	}
+	return total
+}
+
+// AgentSaturated agent 上报的 CPU 或内存使用率已达到饱和阈值
+func AgentSaturated(a models.Agent) bool {
+	return a.CPUPercent >= saturationCPU || a.MemPercent >= saturationMem
+}
+
+// startDistributed 为配置了 worker 的任务挑选有空闲容量的 agent 并下发分配，
+// 返回 master 模式的附加参数与参与运行的 agent；release 在运行结束后收回分配
+func startDistributed(task models.LoadTest, users int, prefix, locustPath string, secrets *secretResolver) (args []string, agents []models.Agent, release func(), err error) {
+	release = func() {}
+	opts, err := models.GetDistributedOptions(task.ID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && opts.Workers == 0) {
+		return nil, nil, release, nil
+	}
+	if err != nil {
+		return nil, nil, release, err
+	}
+
+	// Locust master 将虚拟用户平均分给各 worker
+	perWorker := (users + opts.Workers - 1) / opts.Workers
+	assignment := &WorkerAssignment{RunID: prefix, TestID: task.ID, Users: perWorker}
+	// worker 的配置单独生成，secret 只以引用形式出现
+	refs := secrets.references()
+	configPath, cleanup, err := writeRunnerConfig(task, prefix+"_worker", refs)
+	defer cleanup()
+	if err != nil {
+		return nil, nil, release, err
+	}
+	if assignment.Config, assignment.Files, err = workerBundle(locustPath, configPath); err != nil {
+		return nil, nil, release, err
+	}
+	assignment.secrets = refs.referencedValues()
+	if assignment.MasterPort, err = freePort(); err != nil {
+		return nil, nil, release, err
+	}
+	workers := make([]*WorkerAssignment, opts.Workers)
+	for i := range workers {
+		if workers[i], err = shardAssignment(assignment, i, opts.Workers); err != nil {
+			return nil, nil, release, err
+		}
+	}
+	online, err := models.ListAgents(time.Now().Add(-AgentOnlineWindow))
+	if err != nil {
+		return nil, nil, release, err
+	}
+
+	assignments.Lock()
+	defer assignments.Unlock()
+	agents = placeWorkers(online, opts.Workers, perWorker)
+	if len(agents) < opts.Workers {
+		return nil, nil, release, fmt.Errorf("有空闲容量的 agent 不足：需要 %d 个、每个承担 %d 个虚拟用户，满足条件的 %d 个",
+			opts.Workers, perWorker, len(agents))
+	}
+	for i, a := range agents {
+		if assignments.byAgent[a.ID] == nil {
+			assignments.byAgent[a.ID] = make(map[string]*WorkerAssignment)
+		}
+		assignments.byAgent[a.ID][prefix] = workers[i]
+	}
+	release = func() {
+		assignments.Lock()
+		defer assignments.Unlock()
+		for _, a := range agents {
+			delete(assignments.byAgent[a.ID], prefix)
+			if len(assignments.byAgent[a.ID]) == 0 {
+				delete(assignments.byAgent, a.ID)
+			}
+		}
+	}
+
+	args = []string{
+		"--master",
+		"--master-bind-port", strconv.Itoa(assignment.MasterPort),
+		"--expect-workers", strconv.Itoa(opts.Workers),
+		"--expect-workers-max-wait", strconv.Itoa(expectWorkersWait),
+	}
+	return args, agents, release, nil
+}
+
+// placeWorkers 在未饱和且剩余容量不少于 perWorker 的 agent 中，按剩余容量从大到小
+// 挑选 n 个；调用方需持有 assignments 锁。MaxUsers 为 0 的 agent 视为容量不限
+func placeWorkers(online []models.Agent, n, perWorker int) []models.Agent {
+	type candidate struct {
+		agent models.Agent
+		free  int
+	}
+	var candidates []candidate
+	for _, a := range online {
+		if AgentSaturated(a) {
+			continue
+		}
+		free := math.MaxInt32
+		if a.MaxUsers > 0 {
+			free = a.MaxUsers - assignedUsers(a.ID)
+		}
+		if free >= perWorker {
+			candidates = append(candidates, candidate{a, free})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].free > candidates[j].free })
+
+	var picked []models.Agent
+	for _, c := range candidates {
+		if len(picked) == n {
+			break
+		}
+		picked = append(picked, c.agent)
+	}
+	return picked
+}
+
+// workerBundle 打包 worker 运行所需的 locustfile、配置及其引用的文件。
+// 断言、gRPC 与响应时间统计由 worker 上报给 master 汇总，worker 配置中不再输出文件
+func workerBundle(locustPath, configPath string) ([]byte, map[string][]byte, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	var cfg RunnerConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, nil, err
+	}
+
+	files := make(map[string][]byte)
+	attach := func(path *string) error {
+		if *path == "" {
+			return nil
+		}
+		content, err := os.ReadFile(*path)
+		if err != nil {
+			return err
+		}
+		name := filepath.Base(*path)
+		files[name] = content
+		*path = name
+		return nil
+	}
+	for _, p := range []*string{&cfg.TLS.CAFile, &cfg.TLS.CertFile, &cfg.TLS.KeyFile} {
+		if err := attach(p); err != nil {
+			return nil, nil, err
+		}
+	}
+	if cfg.Dataset != nil {
+		if err := attach(&cfg.Dataset.File); err != nil {
+			return nil, nil, err
+		}
+	}
+	if cfg.GRPC != nil {
+		if err := attach(&cfg.GRPC.DescriptorFile); err != nil {
+			return nil, nil, err
+		}
+		cfg.GRPC.StatusOutput = ""
+	}
+	cfg.ChecksOutput = ""
+	cfg.HistogramOutput = ""
+	cfg.WSOutput = ""
+	// 结果只由 master 所在的本机 runner 上传
+	cfg.ResultToken = ""
+	cfg.CPUOutput = ""
+
+	if files[WorkerLocustfile], err = os.ReadFile(locustPath); err != nil {
+		return nil, nil, err
+	}
+	data, err = json.Marshal(cfg)
+	return data, files, err
+}
+
+// shardAssignment 第 index 个 worker（共 total 个）的分配。sequential 与 unique 模式的数据集按行轮流分给各 worker，
+// 每行只由一个 worker 使用，避免 unique 模式下多个 worker 重复使用同一行；random 模式各 worker 使用完整数据集
+func shardAssignment(base *WorkerAssignment, index, total int) (*WorkerAssignment, error) {
+	var cfg RunnerConfig
+	if err := json.Unmarshal(base.Config, &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Dataset == nil || cfg.Dataset.Mode == models.DatasetRandom || total <= 1 {
+		return base, nil
+	}
+	shard, err := shardCSV(base.Files[cfg.Dataset.File], index, total)
+	if err != nil {
+		return nil, fmt.Errorf("拆分数据集失败: %v", err)
+	}
+	a := *base
+	a.Files = make(map[string][]byte, len(base.Files))
+	for name, content := range base.Files {
+		a.Files[name] = content
+	}
+	a.Files[cfg.Dataset.File] = shard
+	return &a, nil
+}
+
+// shardCSV 保留表头，取第 index、index+total、index+2*total… 个数据行；数据行少于 total 时报错，避免有 worker 分不到数据
+func shardCSV(content []byte, index, total int) ([]byte, error) {
+	records, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records)-1 < total {
+		return nil, fmt.Errorf("数据集只有 %d 行，少于 worker 数 %d", max(len(records)-1, 0), total)
+	}
+	shard := [][]string{records[0]}
+	for i := 1 + index; i < len(records); i += total {
+		shard = append(shard, records[i])
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.WriteAll(shard); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// freePort 取一个当前空闲的端口供 master 监听
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
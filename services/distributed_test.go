@@ -0,0 +1,73 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"loadtest_project/models"
+)
+
+func datasetAssignment(t *testing.T, mode, content string) *WorkerAssignment {
+	t.Helper()
+	cfg, err := json.Marshal(RunnerConfig{Dataset: &RunnerDataset{File: "users.csv", Mode: mode}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &WorkerAssignment{
+		Config: cfg,
+		Files:  map[string][]byte{"users.csv": []byte(content), WorkerLocustfile: []byte("# locustfile")},
+	}
+}
+
+func TestShardAssignment(t *testing.T) {
+	const dataset = "user,password\nu1,p1\nu2,p2\nu3,p3\nu4,p4\n\"u,5\",\"p\n5\"\n"
+	tests := []struct {
+		name    string
+		mode    string
+		workers int
+		want    [][]string // 各 worker 分到的用户名
+	}{
+		{"unique 模式每行只分给一个 worker", models.DatasetUnique, 2, [][]string{{"u1", "u3", "u,5"}, {"u2", "u4"}}},
+		{"sequential 模式同样按行拆分", models.DatasetSequential, 3, [][]string{{"u1", "u4"}, {"u2", "u,5"}, {"u3"}}},
+		{"random 模式使用完整数据集", models.DatasetRandom, 2, [][]string{{"u1", "u2", "u3", "u4", "u,5"}, {"u1", "u2", "u3", "u4", "u,5"}}},
+		{"单个 worker 使用完整数据集", models.DatasetUnique, 1, [][]string{{"u1", "u2", "u3", "u4", "u,5"}}},
+	}
+	for _, tt := range tests {
+		base := datasetAssignment(t, tt.mode, dataset)
+		for i, want := range tt.want {
+			a, err := shardAssignment(base, i, tt.workers)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			records, err := csv.NewReader(strings.NewReader(string(a.Files["users.csv"]))).ReadAll()
+			if err != nil {
+				t.Fatalf("%s: worker %d 的数据集无法解析: %v", tt.name, i, err)
+			}
+			if strings.Join(records[0], ",") != "user,password" {
+				t.Errorf("%s: worker %d 的表头 = %v", tt.name, i, records[0])
+			}
+			var got []string
+			for _, r := range records[1:] {
+				got = append(got, r[0])
+			}
+			if strings.Join(got, "|") != strings.Join(want, "|") {
+				t.Errorf("%s: worker %d 分到 %v，want %v", tt.name, i, got, want)
+			}
+			if string(a.Files[WorkerLocustfile]) != "# locustfile" {
+				t.Errorf("%s: worker %d 的其他文件不应改变", tt.name, i)
+			}
+		}
+		if string(base.Files["users.csv"]) != dataset {
+			t.Errorf("%s: 拆分不应修改共享的分配", tt.name)
+		}
+	}
+}
+
+func TestShardAssignmentTooFewRows(t *testing.T) {
+	base := datasetAssignment(t, models.DatasetUnique, "user\nu1\nu2\n")
+	if _, err := shardAssignment(base, 0, 3); err == nil {
+		t.Error("数据行少于 worker 数时应报错")
+	}
+}
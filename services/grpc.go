@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"loadtest_project/models"
+)
+
+// grpcRequestType Runner 上报 gRPC 调用时使用的请求类型，名称为方法全名（见 locustfile.py）
+const grpcRequestType = "GRPC"
+
+// CompileProto 编译上传的 .proto 文件（文件名 -> 内容，可相互 import，并可引用
+// google/protobuf 下的标准文件），返回序列化的 FileDescriptorSet 及其中定义的方法
+func CompileProto(files map[string]string) ([]byte, []models.GRPCMethod, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(files),
+		}),
+	}
+	compiled, err := compiler.Compile(context.Background(), names...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 依赖在前，Runner 按顺序加入描述池
+	var (
+		set  descriptorpb.FileDescriptorSet
+		seen = map[string]bool{}
+		add  func(fd protoreflect.FileDescriptor)
+	)
+	add = func(fd protoreflect.FileDescriptor) {
+		if seen[fd.Path()] {
+			return
+		}
+		seen[fd.Path()] = true
+		imports := fd.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			add(imports.Get(i).FileDescriptor)
+		}
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(fd))
+	}
+
+	var methods []models.GRPCMethod
+	for _, fd := range compiled {
+		add(fd)
+		services := fd.Services()
+		for i := 0; i < services.Len(); i++ {
+			svc := services.Get(i)
+			for j := 0; j < svc.Methods().Len(); j++ {
+				m := svc.Methods().Get(j)
+				methods = append(methods, models.GRPCMethod{
+					Name:            fmt.Sprintf("/%s/%s", svc.FullName(), m.Name()),
+					ClientStreaming: m.IsStreamingClient(),
+					ServerStreaming: m.IsStreamingServer(),
+				})
+			}
+		}
+	}
+	if len(methods) == 0 {
+		return nil, nil, fmt.Errorf("未定义任何 service")
+	}
+	data, err := proto.Marshal(&set)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, methods, nil
+}
+
+// grpcStatusFile 本次运行的 gRPC 状态码统计文件
+func grpcStatusFile(prefix string) string {
+	return filepath.Join(resultsDir, prefix+"_grpc.json")
+}
+
+func hasGRPCSteps(steps []RunnerStep) bool {
+	for _, s := range steps {
+		if s.Type == models.StepGRPC {
+			return true
+		}
+	}
+	return false
+}
+
+// grpcStatusCounts Runner 写出的单个方法的状态码分布
+type grpcStatusCounts struct {
+	Method      string         `json:"method"`
+	StatusCodes map[string]int `json:"status_codes"`
+}
+
+// grpcMethodResults 合并 stats CSV 中的 GRPC 条目与 Runner 写出的状态码分布，
+// 没有 gRPC 步骤时返回 nil
+func grpcMethodResults(stats locustStats, prefix string) ([]models.GRPCMethodResult, error) {
+	var list []models.GRPCMethodResult
+	for _, e := range stats.Entries {
+		if e.Type != grpcRequestType {
+			continue
+		}
+		list = append(list, models.GRPCMethodResult{
+			Method:          e.Name,
+			Requests:        e.Requests,
+			Failures:        e.Failures,
+			AvgResponseTime: round4(e.AvgResp),
+			P50ResponseTime: round4(e.P50),
+			P95ResponseTime: round4(e.P95),
+			P99ResponseTime: round4(e.P99),
+		})
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(grpcStatusFile(prefix))
+	if os.IsNotExist(err) {
+		return list, nil
+	}
+	if err != nil {
+		return list, err
+	}
+	var counts []grpcStatusCounts
+	if err := json.Unmarshal(data, &counts); err != nil {
+		return list, fmt.Errorf("解析 gRPC 状态码统计失败: %w", err)
+	}
+	for i := range list {
+		for _, c := range counts {
+			if c.Method == list[i].Method {
+				list[i].StatusCodes = c.StatusCodes
+			}
+		}
+	}
+	return list, nil
+}
+
+// saveGRPCResults 将各方法统计关联到已保存的结果
+func saveGRPCResults(result models.TestResult, methods []models.GRPCMethodResult) {
+	for i := range methods {
+		methods[i].TestID = result.TestID
+		methods[i].ResultID = result.ID
+		if err := models.CreateGRPCMethodResult(&methods[i]); err != nil {
+			fmt.Println("写入 gRPC 方法统计失败:", err)
+		}
+	}
+}
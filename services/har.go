@@ -0,0 +1,174 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"loadtest_project/models"
+)
+
+// HARFilterRules 导入 HAR 时过滤静态资源等条目的规则
+type HARFilterRules struct {
+	ExcludeExtensions []string `json:"exclude_extensions"` // 按 URL 路径扩展名排除
+	ExcludeMimeTypes  []string `json:"exclude_mime_types"` // 按响应 MIME 前缀排除
+	IncludeHosts      []string `json:"include_hosts"`      // 非空时只保留这些主机
+	ExcludeHosts      []string `json:"exclude_hosts"`
+	MaxThinkTime      int      `json:"max_think_time"` // 思考时间上限（毫秒），0 表示不限制
+}
+
+// DefaultHARFilterRules 默认排除常见静态资源
+func DefaultHARFilterRules() HARFilterRules {
+	return HARFilterRules{
+		ExcludeExtensions: []string{".css", ".js", ".map", ".png", ".jpg", ".jpeg", ".gif", ".svg",
+			".ico", ".webp", ".woff", ".woff2", ".ttf", ".eot"},
+		ExcludeMimeTypes: []string{"image/", "font/", "text/css", "application/javascript",
+			"text/javascript"},
+		MaxThinkTime: 10000,
+	}
+}
+
+type harFile struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	StartedDateTime time.Time `json:"startedDateTime"`
+	Time            float64   `json:"time"` // 毫秒
+	Request         struct {
+		Method  string `json:"method"`
+		URL     string `json:"url"`
+		Headers []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"headers"`
+		PostData *struct {
+			MimeType string `json:"mimeType"`
+			Text     string `json:"text"`
+		} `json:"postData"`
+	} `json:"request"`
+	Response struct {
+		Content struct {
+			MimeType string `json:"mimeType"`
+		} `json:"content"`
+	} `json:"response"`
+}
+
+// skippedImportHeaders 导入 HAR 时丢弃的请求头：由客户端自动生成或属于录制会话的凭据
+var skippedImportHeaders = map[string]bool{
+	"host": true, "content-length": true, "connection": true, "accept-encoding": true,
+	"cookie": true, "authorization": true, "user-agent": true,
+}
+
+// skippedDefinedHeaders 导入 Postman / curl 等手写请求时只丢弃由客户端自动生成的头
+var skippedDefinedHeaders = map[string]bool{
+	"host": true, "content-length": true,
+}
+
+// ImportHAR 将 HAR 中保留下来的请求按时间顺序转换为场景步骤，
+// 相邻请求的间隔作为前一步骤的思考时间。baseURL 同源的请求只保留路径
+func ImportHAR(data []byte, rules HARFilterRules, baseURL string) ([]models.ScenarioStep, error) {
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("HAR 解析失败: %w", err)
+	}
+
+	// HAR 不保证条目按时间排列（如合并多个页面的录制），思考时间依赖先后顺序
+	sort.SliceStable(har.Log.Entries, func(i, j int) bool {
+		return har.Log.Entries[i].StartedDateTime.Before(har.Log.Entries[j].StartedDateTime)
+	})
+
+	var (
+		steps   []models.ScenarioStep
+		prevEnd time.Time
+	)
+	for _, e := range har.Log.Entries {
+		u, err := url.Parse(e.Request.URL)
+		if err != nil || !rules.keep(u, e.Response.Content.MimeType) {
+			continue
+		}
+		if baseURL == "" {
+			baseURL = u.Scheme + "://" + u.Host
+		}
+
+		// 上一步结束到本步开始的间隔作为上一步的思考时间
+		if len(steps) > 0 {
+			gap := int(math.Max(0, float64(e.StartedDateTime.Sub(prevEnd).Milliseconds())))
+			if rules.MaxThinkTime > 0 && gap > rules.MaxThinkTime {
+				gap = rules.MaxThinkTime
+			}
+			steps[len(steps)-1].ThinkTime = gap
+		}
+		prevEnd = e.StartedDateTime.Add(time.Duration(e.Time * float64(time.Millisecond)))
+
+		step := models.ScenarioStep{
+			Name:   e.Request.Method + " " + u.Path,
+			Method: strings.ToUpper(e.Request.Method),
+			Path:   relativeURL(u, baseURL),
+		}
+		for _, h := range e.Request.Headers {
+			name := strings.ToLower(h.Name)
+			if strings.HasPrefix(name, ":") || skippedImportHeaders[name] {
+				continue
+			}
+			if step.Headers == nil {
+				step.Headers = map[string]string{}
+			}
+			step.Headers[h.Name] = h.Value
+		}
+		if e.Request.PostData != nil {
+			step.Body = e.Request.PostData.Text
+		}
+		steps = append(steps, step)
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("过滤后没有可导入的请求")
+	}
+	return steps, nil
+}
+
+// keep 判断条目是否应保留
+func (r HARFilterRules) keep(u *url.URL, mimeType string) bool {
+	host := u.Hostname()
+	if len(r.IncludeHosts) > 0 && !containsFold(r.IncludeHosts, host) {
+		return false
+	}
+	if containsFold(r.ExcludeHosts, host) {
+		return false
+	}
+	if ext := strings.ToLower(path.Ext(u.Path)); ext != "" && containsFold(r.ExcludeExtensions, ext) {
+		return false
+	}
+	mimeType = strings.ToLower(mimeType)
+	for _, prefix := range r.ExcludeMimeTypes {
+		if prefix != "" && strings.HasPrefix(mimeType, strings.ToLower(prefix)) {
+			return false
+		}
+	}
+	return true
+}
+
+// relativeURL 与 baseURL 同源时返回路径和查询串，否则返回完整 URL
+func relativeURL(u *url.URL, baseURL string) string {
+	base, err := url.Parse(baseURL)
+	if err == nil && strings.EqualFold(base.Scheme, u.Scheme) && strings.EqualFold(base.Host, u.Host) {
+		return u.RequestURI()
+	}
+	return u.String()
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
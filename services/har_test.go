@@ -0,0 +1,105 @@
+package services
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// harEntryJSON 构造一条 HAR 条目
+func harEntryJSON(started, method, url, mime string, ms int, extra string) string {
+	return `{"startedDateTime": "` + started + `", "time": ` + strconv.Itoa(ms) +
+		`, "request": {"method": "` + method + `", "url": "` + url + `", "headers": [
+			{"name": ":authority", "value": "api.example.com"},
+			{"name": "Cookie", "value": "sid=1"},
+			{"name": "X-Trace", "value": "t"}]` + extra + `},
+		"response": {"content": {"mimeType": "` + mime + `"}}}`
+}
+
+func harJSON(entries ...string) []byte {
+	return []byte(`{"log": {"entries": [` + strings.Join(entries, ",") + `]}}`)
+}
+
+func TestImportHAR(t *testing.T) {
+	data := harJSON(
+		// 故意乱序：按 startedDateTime 排序后才是 login → static → list → other
+		harEntryJSON("2024-01-01T00:00:02.500Z", "GET", "https://api.example.com/users?page=1", "application/json", 100, ""),
+		harEntryJSON("2024-01-01T00:00:00.000Z", "POST", "https://api.example.com/login", "application/json", 200,
+			`, "postData": {"mimeType": "application/json", "text": "{\"u\":1}"}`),
+		harEntryJSON("2024-01-01T00:00:01.000Z", "GET", "https://api.example.com/app.js", "application/javascript", 50, ""),
+		harEntryJSON("2024-01-01T00:01:00.000Z", "GET", "https://cdn.example.net/data", "application/json", 10, ""),
+	)
+	steps, err := ImportHAR(data, DefaultHARFilterRules(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	type view struct {
+		Name, Path, Body string
+		ThinkTime        int
+	}
+	var got []view
+	for _, s := range steps {
+		got = append(got, view{s.Name, s.Path, s.Body, s.ThinkTime})
+		if !reflect.DeepEqual(s.Headers, map[string]string{"X-Trace": "t"}) {
+			t.Errorf("%s: headers = %v, 应只保留 X-Trace", s.Name, s.Headers)
+		}
+	}
+	want := []view{
+		// login 结束于 0.2s，list 开始于 2.5s
+		{"POST /login", "/login", `{"u":1}`, 2300},
+		// list 结束于 2.6s，其他主机的请求开始于 60s，超过上限按 10s 计
+		{"GET /users", "/users?page=1", "", 10000},
+		{"GET /data", "https://cdn.example.net/data", "", 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("steps = %+v, want %+v", got, want)
+	}
+}
+
+func TestHARFilterRules(t *testing.T) {
+	entries := harJSON(
+		harEntryJSON("2024-01-01T00:00:00Z", "GET", "https://api.example.com/a", "application/json", 1, ""),
+		harEntryJSON("2024-01-01T00:00:01Z", "GET", "https://static.example.com/logo", "image/png", 1, ""),
+		harEntryJSON("2024-01-01T00:00:02Z", "GET", "https://ads.example.org/b", "text/html", 1, ""),
+	)
+	tests := []struct {
+		name  string
+		rules HARFilterRules
+		want  []string
+	}{
+		{"默认规则按 MIME 排除图片", DefaultHARFilterRules(), []string{"GET /a", "GET /b"}},
+		{"只保留指定主机", HARFilterRules{IncludeHosts: []string{"API.example.com"}}, []string{"GET /a"}},
+		{"排除主机", HARFilterRules{ExcludeHosts: []string{"ads.example.org"}}, []string{"GET /a", "GET /logo"}},
+	}
+	for _, tt := range tests {
+		steps, err := ImportHAR(entries, tt.rules, "")
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var got []string
+		for _, s := range steps {
+			got = append(got, s.Name)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: steps = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestImportHARErrors(t *testing.T) {
+	tests := []struct {
+		name, data, wantErr string
+	}{
+		{"非 JSON", "not json", "HAR 解析失败"},
+		{"全部被过滤", string(harJSON(harEntryJSON("2024-01-01T00:00:00Z", "GET", "https://a.example.com/x.css", "text/css", 1, ""))), "过滤后没有可导入的请求"},
+		{"没有条目", `{"log": {"entries": []}}`, "过滤后没有可导入的请求"},
+	}
+	for _, tt := range tests {
+		_, err := ImportHAR([]byte(tt.data), DefaultHARFilterRules(), "")
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"loadtest_project/models"
+	"loadtest_project/utils"
+)
+
+// histogramFile 本次运行的响应时间分布文件。分布式运行时由 master 写出，
+// 其中已包含 Locust 从各 worker 合并的数据
+func histogramFile(prefix string) string {
+	return filepath.Join(resultsDir, prefix+"_histograms.json")
+}
+
+// runnerHistogram Runner 导出的单个端点的响应时间分布
+type runnerHistogram struct {
+	Type          string           `json:"type"`
+	Name          string           `json:"name"`
+	ResponseTimes map[string]int64 `json:"response_times"` // 毫秒（Locust 已取整） -> 次数
+	Min           float64          `json:"min"`
+	Max           float64          `json:"max"`
+	Sum           float64          `json:"sum"`
+}
+
+// readHistograms 读取 Runner 导出的分布并转换为直方图，文件不存在时返回 nil
+func readHistograms(prefix string) ([]models.LatencyHistogram, error) {
+	data, err := os.ReadFile(histogramFile(prefix))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []runnerHistogram
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("解析响应时间分布失败: %w", err)
+	}
+
+	list := make([]models.LatencyHistogram, 0, len(entries))
+	for _, e := range entries {
+		h := utils.NewHistogram()
+		for ms, n := range e.ResponseTimes {
+			v, err := strconv.ParseFloat(ms, 64)
+			if err != nil {
+				continue
+			}
+			h.RecordN(v, n)
+		}
+		if h.Total > 0 {
+			// 取整前的精确值
+			h.Min, h.Max, h.Sum = e.Min, e.Max, e.Sum
+		}
+		list = append(list, models.LatencyHistogram{Type: e.Type, Name: e.Name, Histogram: h})
+	}
+	return list, nil
+}
+
+// saveHistograms 将直方图关联到已保存的结果
+func saveHistograms(result models.TestResult, list []models.LatencyHistogram) {
+	for i := range list {
+		list[i].TestID = result.TestID
+		list[i].ResultID = result.ID
+		if err := models.CreateLatencyHistogram(&list[i]); err != nil {
+			fmt.Println("写入延迟直方图失败:", err)
+		}
+	}
+}
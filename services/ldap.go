@@ -0,0 +1,113 @@
+package services
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"loadtest_project/config"
+)
+
+// LDAP 登录后端，未配置时为 nil
+var LDAP *LDAPAuthenticator
+
+// ldapTimeout 连接与单次请求的超时
+const ldapTimeout = 10 * time.Second
+
+// ErrLDAPInvalidCredentials 用户不存在或密码错误，两者不区分以免泄露目录中有哪些用户
+var ErrLDAPInvalidCredentials = errors.New("用户名或密码错误")
+
+// ldapConn 认证用到的目录操作，便于替换为进程内的目录桩
+type ldapConn interface {
+	Bind(username, password string) error
+	Search(req *ldap.SearchRequest) (*ldap.SearchResult, error)
+	Close() error
+}
+
+// dialLDAP 连接目录服务器，按配置启用 StartTLS
+var dialLDAP = func(cfg *config.LDAPConfig) (ldapConn, error) {
+	conn, err := ldap.DialURL(cfg.URL, ldap.DialWithDialer(&net.Dialer{Timeout: ldapTimeout}))
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(ldapTimeout)
+	if cfg.StartTLS {
+		host := strings.TrimPrefix(cfg.URL, "ldap://")
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if err := conn.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// LDAPAuthenticator 先用服务账号（或匿名）按过滤器查找用户条目，再以该条目的 DN 和用户密码绑定验证
+type LDAPAuthenticator struct {
+	cfg *config.LDAPConfig
+}
+
+// LDAPIdentity 认证通过的目录用户
+type LDAPIdentity struct {
+	DN     string
+	Groups []string // 组 DN，已转为小写
+	Role   string   // 按组映射的系统角色
+}
+
+func NewLDAPAuthenticator(cfg *config.LDAPConfig) *LDAPAuthenticator {
+	return &LDAPAuthenticator{cfg: cfg}
+}
+
+// Authenticate 验证用户名与密码；用户不存在或密码错误返回 ErrLDAPInvalidCredentials，其余错误表示目录不可用
+func (a *LDAPAuthenticator) Authenticate(username, password string) (*LDAPIdentity, error) {
+	// 空密码的绑定在 LDAP 中是“未认证绑定”，多数服务器会直接返回成功
+	if username == "" || password == "" {
+		return nil, ErrLDAPInvalidCredentials
+	}
+	conn, err := dialLDAP(a.cfg)
+	if err != nil {
+		return nil, fmt.Errorf("连接 LDAP 失败: %w", err)
+	}
+	defer conn.Close()
+
+	if a.cfg.BindDN != "" {
+		if err := conn.Bind(a.cfg.BindDN, a.cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("LDAP 服务账号绑定失败: %w", err)
+		}
+	}
+	res, err := conn.Search(ldap.NewSearchRequest(
+		a.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, int(ldapTimeout.Seconds()), false,
+		fmt.Sprintf(a.cfg.UserFilter, ldap.EscapeFilter(username)),
+		[]string{a.cfg.GroupAttr}, nil,
+	))
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("查找 LDAP 用户失败: %w", err)
+	}
+	// 过滤器匹配到多个条目时无法确定是谁，按认证失败处理
+	if res == nil || len(res.Entries) != 1 {
+		return nil, ErrLDAPInvalidCredentials
+	}
+	entry := res.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrLDAPInvalidCredentials
+		}
+		return nil, fmt.Errorf("LDAP 用户绑定失败: %w", err)
+	}
+
+	id := &LDAPIdentity{DN: entry.DN}
+	for _, g := range entry.GetAttributeValues(a.cfg.GroupAttr) {
+		id.Groups = append(id.Groups, strings.ToLower(g))
+	}
+	id.Role = a.cfg.RoleMap.Role(id.Groups)
+	return id, nil
+}
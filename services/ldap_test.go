@@ -0,0 +1,148 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"loadtest_project/config"
+)
+
+// stubEntry 目录桩中的一个用户条目
+type stubEntry struct {
+	dn       string
+	uid      string
+	password string
+	groups   []string
+}
+
+// stubDirectory 进程内的 LDAP 目录桩，按 uid 过滤器查找，按 DN 与密码绑定
+type stubDirectory struct {
+	serviceDN, servicePassword string
+	entries                    []stubEntry
+	dialErr                    error
+}
+
+type stubConn struct {
+	dir   *stubDirectory
+	bound string
+}
+
+func (d *stubDirectory) dial(*config.LDAPConfig) (ldapConn, error) {
+	if d.dialErr != nil {
+		return nil, d.dialErr
+	}
+	return &stubConn{dir: d}, nil
+}
+
+func (c *stubConn) Bind(dn, password string) error {
+	if dn == c.dir.serviceDN && password == c.dir.servicePassword {
+		c.bound = dn
+		return nil
+	}
+	for _, e := range c.dir.entries {
+		if e.dn == dn && e.password == password {
+			c.bound = dn
+			return nil
+		}
+	}
+	return ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials"))
+}
+
+func (c *stubConn) Search(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	if c.bound != c.dir.serviceDN {
+		return nil, ldap.NewError(ldap.LDAPResultInsufficientAccessRights, errors.New("bind required"))
+	}
+	res := &ldap.SearchResult{}
+	for _, e := range c.dir.entries {
+		if req.Filter == fmt.Sprintf("(uid=%s)", ldap.EscapeFilter(e.uid)) {
+			res.Entries = append(res.Entries, ldap.NewEntry(e.dn, map[string][]string{"memberOf": e.groups}))
+		}
+	}
+	return res, nil
+}
+
+func (c *stubConn) Close() error { return nil }
+
+func newStubAuthenticator(t *testing.T, dir *stubDirectory) *LDAPAuthenticator {
+	t.Helper()
+	orig := dialLDAP
+	dialLDAP = dir.dial
+	t.Cleanup(func() { dialLDAP = orig })
+	return NewLDAPAuthenticator(&config.LDAPConfig{
+		BindDN:       dir.serviceDN,
+		BindPassword: dir.servicePassword,
+		BaseDN:       "dc=example,dc=com",
+		UserFilter:   "(uid=%s)",
+		GroupAttr:    "memberOf",
+		RoleMap:      config.GroupRoleMap{"cn=lt-admins,ou=groups,dc=example,dc=com": "admin"},
+	})
+}
+
+func testDirectory() *stubDirectory {
+	return &stubDirectory{
+		serviceDN:       "cn=svc,dc=example,dc=com",
+		servicePassword: "svc-pw",
+		entries: []stubEntry{
+			{dn: "uid=alice,ou=people,dc=example,dc=com", uid: "alice", password: "alice-pw",
+				groups: []string{"CN=LT-Admins,OU=Groups,DC=example,DC=com"}},
+			{dn: "uid=bob,ou=people,dc=example,dc=com", uid: "bob", password: "bob-pw",
+				groups: []string{"cn=dev,ou=groups,dc=example,dc=com"}},
+			// 两个条目的 uid 相同
+			{dn: "uid=dup,ou=people,dc=example,dc=com", uid: "dup", password: "dup-pw"},
+			{dn: "uid=dup,ou=contractors,dc=example,dc=com", uid: "dup", password: "dup-pw"},
+		},
+	}
+}
+
+func TestLDAPAuthenticate(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		password string
+		wantErr  error
+		wantDN   string
+		wantRole string
+	}{
+		{"管理员组映射为 admin", "alice", "alice-pw", nil, "uid=alice,ou=people,dc=example,dc=com", "admin"},
+		{"未映射的组为 user", "bob", "bob-pw", nil, "uid=bob,ou=people,dc=example,dc=com", "user"},
+		{"密码错误", "bob", "wrong", ErrLDAPInvalidCredentials, "", ""},
+		{"空密码", "bob", "", ErrLDAPInvalidCredentials, "", ""},
+		{"用户不存在", "carol", "whatever", ErrLDAPInvalidCredentials, "", ""},
+		{"过滤器特殊字符被转义", "*", "bob-pw", ErrLDAPInvalidCredentials, "", ""},
+		{"匹配到多个条目", "dup", "dup-pw", ErrLDAPInvalidCredentials, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newStubAuthenticator(t, testDirectory())
+			id, err := a.Authenticate(tt.username, tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if id.DN != tt.wantDN || id.Role != tt.wantRole {
+				t.Fatalf("got DN %q role %q, want %q %q", id.DN, id.Role, tt.wantDN, tt.wantRole)
+			}
+		})
+	}
+}
+
+func TestLDAPAuthenticateDirectoryErrors(t *testing.T) {
+	dir := testDirectory()
+	dir.dialErr = errors.New("connection refused")
+	a := newStubAuthenticator(t, dir)
+	if _, err := a.Authenticate("bob", "bob-pw"); err == nil || errors.Is(err, ErrLDAPInvalidCredentials) {
+		t.Fatalf("连接失败应返回目录不可用错误，得到 %v", err)
+	}
+
+	dir = testDirectory()
+	a = newStubAuthenticator(t, dir)
+	a.cfg.BindPassword = "wrong"
+	if _, err := a.Authenticate("bob", "bob-pw"); err == nil || errors.Is(err, ErrLDAPInvalidCredentials) {
+		t.Fatalf("服务账号绑定失败应返回目录不可用错误，得到 %v", err)
+	}
+}
@@ -5,8 +5,13 @@ import (
 	"loadtest_project/models"
 )
 
-// StartLoadTest 由调度器调用，触发无 UI 模式的 Locust 压测
+// StartLoadTest 由调度器调用，按压测类型触发无 UI 模式的 Locust 压测
 func StartLoadTest(task models.LoadTest) {
-	// RunLocustForTask 会更新状态并保存结果
-	RunLocustForTask(task)
+	// 各 Runner 会更新状态并保存结果
+	switch task.TestType {
+	case models.TestTypeCapacity:
+		RunCapacitySearch(task)
+	default:
+		RunLocustForTask(task)
+	}
 }
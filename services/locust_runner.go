@@ -2,6 +2,7 @@ package services
 
 import (
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"math"
@@ -14,74 +15,185 @@ import (
 	"loadtest_project/models"
 )
 
+// resultsDir Locust CSV 输出目录
+const resultsDir = "results"
+
 // round4 保留 4 位小数
 func round4(f float64) float64 {
 	return math.Round(f*1e4) / 1e4
 }
 
-// RunLocustForTask 运行 Locust（无 UI），解析 CSV 结果并写入数据库
-func RunLocustForTask(task models.LoadTest) {
-	resultsDir := "results"
-	prefix := fmt.Sprintf("task_%d_%d", task.ID, time.Now().Unix())
-	_ = os.MkdirAll(resultsDir, 0755)
+// PythonExec Locust 所在 Python 解释器
+const PythonExec = `C:\Users\Pua Wei Jian\AppData\Local\Programs\Python\Python313\python.exe`
+
+// locustEntry stats CSV 中按 (Type, Name) 汇总的单个条目
+type locustEntry struct {
+	Type     string
+	Name     string
+	Requests int
+	Failures int
+	AvgResp  float64
+	P50      float64
+	P95      float64
+	P99      float64
+	RPS      float64
+}
+
+// locustStats 为 stats CSV 中 Aggregated 行的解析结果，Entries 为其余各条目
+type locustStats struct {
+	TotalRequests int
+	Failures      int
+	AvgResp       float64
+	MinResp       float64
+	MaxResp       float64
+	RPS           float64
+	P50           float64
+	P95           float64
+	P99           float64
+	Entries       []locustEntry
+}
+
+// ErrorRate 失败请求占比
+func (s locustStats) ErrorRate() float64 {
+	if s.TotalRequests == 0 {
+		return 0
+	}
+	return float64(s.Failures) / float64(s.TotalRequests)
+}
+
+// runLocust 以无 UI 模式运行一次 Locust，结果 CSV 以 prefix 为前缀写入 results 目录，
+// stdout/stderr 写入运行日志，失败时返回的 err 为从日志摘要的失败原因。
+// 若任务配置了中止条件，运行期间实时评估，触发时停止进程并返回中止原因；
+// 超过运行窗口加宽限时间仍未退出时停止进程并返回 errRunTimeout
+func runLocust(task models.LoadTest, users, spawnRate int, runTime time.Duration, prefix string) (abortReason string, err error) {
+	secrets := newSecretResolver(task.UserID, task.ProjectID)
+	run, output, err := startRun(task, prefix, secrets)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			fmt.Fprintf(output, "运行失败: %v\n", err)
+		}
+		finishRun(run, output, abortReason, err)
+		if err != nil {
+			err = &runError{reason: run.FailureReason, cause: err}
+		}
+	}()
 
 	// 获取 locustfile.py 的绝对路径
 	locustPath, err := filepath.Abs("locust/locustfile.py")
 	if err != nil {
-		fmt.Println("无法获取 locustfile 路径:", err)
-		models.UpdateLoadTestStatus(task.ID, "failed")
-		return
+		return "", fmt.Errorf("无法获取 locustfile 路径: %w", err)
 	}
 
 	// 构造命令
 	cmd := exec.Command(
-		`C:\Users\Pua Wei Jian\AppData\Local\Programs\Python\Python313\python.exe`,
+		PythonExec,
 		"-m", "locust",
 		"-f", locustPath,
 		"--headless",
-		"-u", strconv.Itoa(task.NumUsers),
-		"-r", strconv.Itoa(task.RampUp),
+		"-u", strconv.Itoa(users),
+		"-r", strconv.Itoa(spawnRate),
 		"--host", task.TargetURL,
-		"--run-time", fmt.Sprintf("%ds", int(task.EndTime.Sub(task.StartTime).Seconds())),
+		"--run-time", fmt.Sprintf("%ds", int(runTime.Seconds())),
 		"--csv", filepath.Join(resultsDir, prefix),
 		"--only-summary",
 	)
+	cmd.Stdout = output
+	cmd.Stderr = output
 
-	if output, err := cmd.CombinedOutput(); err != nil {
-		fmt.Printf("Locust 运行失败: %v\n日志:\n%s\n", err, string(output))
-		models.UpdateLoadTestStatus(task.ID, "failed")
-		return
+	// 目标请求头、认证、TLS 等选项通过配置文件传给 locustfile
+	configPath, cleanup, err := writeRunnerConfig(task, prefix, secrets)
+	defer cleanup()
+	if err != nil {
+		return "", fmt.Errorf("生成运行配置失败: %w", err)
 	}
+	cmd.Env = append(os.Environ(), RunnerConfigEnv+"="+configPath)
+
+	// 分布式任务：本机作为 master 汇总统计，虚拟用户由 worker agent 承担
+	masterArgs, agents, release, err := startDistributed(task, users, prefix, locustPath, secrets)
+	defer release()
+	if err != nil {
+		return "", fmt.Errorf("分配 worker 失败: %w", err)
+	}
+	cmd.Args = append(cmd.Args, masterArgs...)
+	stopWatch := watchSaturation(prefix, agents)
+	defer stopWatch()
+
+	// runner 置于独立进程组，超时或中止时整组结束，避免遗留卡死的子进程
+	setProcessGroup(cmd)
+	// 子进程仍持有输出管道时，Wait 最多再等待该时间
+	cmd.WaitDelay = stopGracePeriod
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+	waitErr := make(chan error, 1)
+	go func() { waitErr <- cmd.Wait() }()
+
+	deadline := runDeadline(runTime)
+	timer := time.NewTimer(deadline)
+	defer timer.Stop()
+
+	// 未配置中止条件时 aborted 为 nil，不会被选中
+	var aborted chan string
+	if cond, err := models.GetAbortCondition(task.ID); err == nil {
+		done := make(chan struct{})
+		defer close(done)
+		aborted = make(chan string, 1)
+		go func() {
+			aborted <- watchStatsHistory(filepath.Join(resultsDir, prefix+"_stats_history.csv"), cond, done)
+		}()
+	}
+
+	select {
+	case err = <-waitErr:
+		return "", err
+	case abortReason = <-aborted:
+		fmt.Printf("任务 %d 触发中止条件: %s\n", task.ID, abortReason)
+		// 先中断让 Locust 写出汇总 CSV，不退出再强制结束
+		stopRunner(cmd, waitErr)
+		return abortReason, nil
+	case <-timer.C:
+		fmt.Printf("任务 %d 运行超过 %s 仍未结束，停止 runner\n", task.ID, deadline)
+		stopRunner(cmd, waitErr)
+		return "", fmt.Errorf("%w: 超过 %s 仍未结束，已强制停止", errRunTimeout, deadline)
+	}
+}
+
+// parseLocustStats 读取 stats CSV，返回“Aggregated”或“Total”行
+func parseLocustStats(csvFile string) (locustStats, error) {
+	var stats locustStats
 
-	// 打开 stats CSV
-	csvFile := filepath.Join(resultsDir, prefix+"_stats.csv")
 	f, err := os.Open(csvFile)
 	if err != nil {
-		fmt.Println("打开 CSV 失败:", err)
-		models.UpdateLoadTestStatus(task.ID, "failed")
-		return
+		return stats, err
 	}
 	defer f.Close()
 
 	reader := csv.NewReader(f)
-	// 跳过表头
-	if _, err := reader.Read(); err != nil {
-		fmt.Println("读取 CSV 表头失败:", err)
-		models.UpdateLoadTestStatus(task.ID, "failed")
-		return
+	header, err := reader.Read()
+	if err != nil {
+		return stats, fmt.Errorf("读取 CSV 表头失败: %w", err)
+	}
+	// 按表头名称定位列，兼容不同 Locust 版本的列顺序
+	col := make(map[string]int, len(header))
+	for i, name := range header {
+		col[name] = i
+	}
+	str := func(record []string, name string) string {
+		i, ok := col[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return record[i]
+	}
+	num := func(record []string, name string) float64 {
+		v, _ := strconv.ParseFloat(str(record, name), 64)
+		return v
 	}
 
-	// 准备变量
-	var (
-		totalRequests int
-		failures      int
-		avgResp       float64
-		minResp       float64
-		maxResp       float64
-		rps           float64
-	)
-
-	// 解析“Aggregated”或“Total”行
+	found := false
 	for {
 		record, err := reader.Read()
 		if err == io.EOF {
@@ -93,32 +205,123 @@ func RunLocustForTask(task models.LoadTest) {
 		}
 		// 有些版本第一列是空字符串、第二列是名称；新版本直接第一列是名称
 		name := record[0]
-		if name == "" {
+		if name == "" && len(record) > 1 {
 			name = record[1]
 		}
 		if name == "Aggregated" || name == "Total" {
-			// 参考你的样例：
-			// record[2] = Request Count
-			// record[3] = Failure Count
-			// record[5] = Average response time
-			// record[6] = Min response time
-			// record[7] = Max response time
-			// record[9] = Requests/s
-			totalRequests, _ = strconv.Atoi(record[2])
-			failures, _ = strconv.Atoi(record[3])
-			avgResp, _ = strconv.ParseFloat(record[5], 64)
-			minResp, _ = strconv.ParseFloat(record[6], 64)
-			maxResp, _ = strconv.ParseFloat(record[7], 64)
-			rps, _ = strconv.ParseFloat(record[9], 64)
-			break
+			stats.TotalRequests = int(num(record, "Request Count"))
+			stats.Failures = int(num(record, "Failure Count"))
+			stats.AvgResp = num(record, "Average Response Time")
+			stats.MinResp = num(record, "Min Response Time")
+			stats.MaxResp = num(record, "Max Response Time")
+			stats.RPS = num(record, "Requests/s")
+			stats.P50 = num(record, "50%")
+			stats.P95 = num(record, "95%")
+			stats.P99 = num(record, "99%")
+			found = true
+			continue
 		}
+		stats.Entries = append(stats.Entries, locustEntry{
+			Type:     str(record, "Type"),
+			Name:     str(record, "Name"),
+			Requests: int(num(record, "Request Count")),
+			Failures: int(num(record, "Failure Count")),
+			AvgResp:  num(record, "Average Response Time"),
+			P50:      num(record, "50%"),
+			P95:      num(record, "95%"),
+			P99:      num(record, "99%"),
+			RPS:      num(record, "Requests/s"),
+		})
+	}
+	if !found {
+		return stats, fmt.Errorf("CSV 中未找到汇总行: %s", csvFile)
+	}
+	return stats, nil
+}
+
+// RunLocustForTask 运行 Locust（无 UI），解析 CSV 结果并写入数据库
+func RunLocustForTask(task models.LoadTest) {
+	prefix := fmt.Sprintf("task_%d_%d", task.ID, time.Now().Unix())
+	_ = os.MkdirAll(resultsDir, 0755)
+
+	runTime := task.EndTime.Sub(task.StartTime)
+	abortReason, err := runLocust(task, task.NumUsers, task.RampUp, runTime, prefix)
+	if err != nil {
+		fmt.Printf("任务 %d Locust 运行失败: %v\n", task.ID, err)
+		if errors.Is(err, errRunTimeout) {
+			models.TimeoutLoadTest(task.ID, err.Error())
+		} else {
+			models.FailLoadTest(task.ID, err.Error())
+		}
+		return
 	}
 
+	// 解析 stats CSV
+	stats, err := parseLocustStats(filepath.Join(resultsDir, prefix+"_stats.csv"))
+	if err != nil {
+		fmt.Println("解析 CSV 失败:", err)
+		if abortReason != "" {
+			// 被强制结束时可能没有汇总 CSV，仍记录中止原因
+			models.AbortLoadTest(task.ID, abortReason)
+		} else {
+			models.FailLoadTest(task.ID, "解析结果 CSV 失败，详见运行日志")
+		}
+		return
+	}
+
+	checks, err := readCheckResults(prefix)
+	if err != nil {
+		fmt.Println(err)
+	}
+	grpcMethods, err := grpcMethodResults(stats, prefix)
+	if err != nil {
+		fmt.Println(err)
+	}
+	histograms, err := readHistograms(prefix)
+	if err != nil {
+		fmt.Println(err)
+	}
+	warnings, err := readGeneratorWarnings(prefix)
+	if err != nil {
+		fmt.Println(err)
+	}
+	ws, err := readWebSocketMetrics(prefix, runTime)
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	result := buildTestResult(task, stats, ws, runTime)
+	result.CheckPassRate = checkPassRate(checks)
+	result.GeneratorWarnings = warnings
+	if err := saveRunResult(prefix, &result); err != nil {
+		fmt.Println("写入测试结果失败:", err)
+		models.FailLoadTest(task.ID, "写入测试结果失败")
+		return
+	}
+	saveCheckResults(result, checks)
+	saveGRPCResults(result, grpcMethods)
+	saveHistograms(result, histograms)
+	RecordVerdict(result)
+
+	if abortReason != "" {
+		models.AbortLoadTest(task.ID, abortReason)
+		fmt.Printf("任务 %d 已中止: %s\n", task.ID, abortReason)
+		return
+	}
+	models.UpdateLoadTestStatus(task.ID, "completed")
+	fmt.Printf("任务 %d 已完成，结果已保存\n", task.ID)
+}
+
+// buildTestResult 将 Locust 汇总数据转换为 TestResult，WebSocket 指标单独统计，不计入 HTTP 汇总
+func buildTestResult(task models.LoadTest, stats locustStats, ws wsMetrics, runTime time.Duration) models.TestResult {
+	totalRequests := stats.TotalRequests
+	failures := stats.Failures
+
 	// 四舍五入到 4 位小数
-	avgResp = round4(avgResp)
-	minResp = round4(minResp)
-	maxResp = round4(maxResp)
-	rps = round4(rps)
+	avgResp := round4(stats.AvgResp)
+	minResp := round4(stats.MinResp)
+	maxResp := round4(stats.MaxResp)
+	rps := round4(stats.RPS)
 
 	// 计算 errorRate 和 availability
 	var errorRate float64
@@ -127,7 +330,6 @@ func RunLocustForTask(task models.LoadTest) {
 	}
 	availability := round4(1 - errorRate)
 
-	// 构造 TestResult 并写入数据库
 	result := models.TestResult{
 		TestID:              task.ID,
 		TPS:                 rps,
@@ -140,20 +342,24 @@ func RunLocustForTask(task models.LoadTest) {
 		RPS:                 rps,
 		DownloadSpeed:       0, // 如需，可由 Python JSON 中解析并 round4
 		DownloadSize:        0,
-		DownloadDuration:    round4(task.EndTime.Sub(task.StartTime).Seconds()),
+		DownloadDuration:    round4(runTime.Seconds()),
 		DNSTime:             0,
 		ConnectTime:         0,
 		TTFB:                0,
 		ContentDownloadTime: 0,
 		Availability:        availability,
+		P50ResponseTime:     round4(stats.P50),
+		P95ResponseTime:     round4(stats.P95),
+		P99ResponseTime:     round4(stats.P99),
+		CheckPassRate:       1,
+		WSConnectTime:       round4(ws.connect),
+		WSRoundTripTime:     round4(ws.roundTrip),
+		WSSendRate:          round4(ws.sendRate),
+		WSReceiveRate:       round4(ws.receiveRate),
+		WSErrorRate:         round4(ws.errorRate),
 	}
-
-	if err := models.CreateTestResult(&result); err != nil {
-		fmt.Println("写入测试结果失败:", err)
-		models.UpdateLoadTestStatus(task.ID, "failed")
-		return
+	if transport, err := effectiveTransport(task.ID); err == nil {
+		result.Transport = &transport
 	}
-
-	models.UpdateLoadTestStatus(task.ID, "completed")
-	fmt.Printf("任务 %d 已完成，结果已保存\n", task.ID)
+	return result
 }
@@ -0,0 +1,386 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"loadtest_project/models"
+)
+
+// OpenAPIOperation 文档中的一个可选操作
+type OpenAPIOperation struct {
+	ID          string `json:"id"` // operationId，缺省时为 "METHOD /path"
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	Summary     string `json:"summary"`
+	OperationID string `json:"operation_id"`
+}
+
+// openAPIMethods 按常见顺序列出的 HTTP 方法
+var openAPIMethods = []string{"get", "post", "put", "patch", "delete", "head", "options"}
+
+// maxRefDepth 解析 $ref 与生成示例时的最大递归深度，防止循环引用
+const maxRefDepth = 8
+
+// OpenAPIDoc 已解析的 OpenAPI 3 文档（JSON 或 YAML）
+type OpenAPIDoc struct {
+	root map[string]interface{}
+}
+
+// ParseOpenAPI 解析 OpenAPI 3 文档，YAML 是 JSON 的超集，统一按 YAML 读取
+func ParseOpenAPI(data []byte) (*OpenAPIDoc, error) {
+	var root map[string]interface{}
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("OpenAPI 文档解析失败: %w", err)
+	}
+	version, _ := root["openapi"].(string)
+	if !strings.HasPrefix(version, "3.") {
+		return nil, fmt.Errorf("仅支持 OpenAPI 3.x，文档版本为 %q", version)
+	}
+	return &OpenAPIDoc{root: root}, nil
+}
+
+// Operations 列出文档中的全部操作
+func (d *OpenAPIDoc) Operations() []OpenAPIOperation {
+	paths, _ := d.root["paths"].(map[string]interface{})
+	var ops []OpenAPIOperation
+	for _, p := range sortedKeys(paths) {
+		item, _ := d.resolve(paths[p], 0).(map[string]interface{})
+		for _, m := range openAPIMethods {
+			op, ok := item[m].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			o := OpenAPIOperation{Method: strings.ToUpper(m), Path: p}
+			o.OperationID, _ = op["operationId"].(string)
+			o.Summary, _ = op["summary"].(string)
+			o.ID = o.OperationID
+			if o.ID == "" {
+				o.ID = o.Method + " " + p
+			}
+			ops = append(ops, o)
+		}
+	}
+	return ops
+}
+
+// Steps 按所选操作（operationId 或 "METHOD /path"）顺序生成场景步骤。
+// 参数优先使用文档中的示例，缺少示例的路径参数以 ${参数名} 占位，可由数据集或提取器填充
+func (d *OpenAPIDoc) Steps(selected []string) ([]models.ScenarioStep, error) {
+	ops := d.Operations()
+	index := make(map[string]OpenAPIOperation, len(ops)*2)
+	for _, o := range ops {
+		index[o.ID] = o
+		index[o.Method+" "+o.Path] = o
+	}
+
+	prefix := d.serverPathPrefix()
+	paths, _ := d.root["paths"].(map[string]interface{})
+	var steps []models.ScenarioStep
+	for _, sel := range selected {
+		o, ok := index[normalizeOperationSelector(sel)]
+		if !ok {
+			return nil, fmt.Errorf("未找到操作 %q", sel)
+		}
+		item, _ := d.resolve(paths[o.Path], 0).(map[string]interface{})
+		op, _ := item[strings.ToLower(o.Method)].(map[string]interface{})
+
+		step := models.ScenarioStep{Name: o.ID, Method: o.Method}
+		reqPath := o.Path
+		// 查询串手工拼接，避免 ${变量} 占位符被转义
+		var query []string
+		params := append(d.list(item["parameters"]), d.list(op["parameters"])...)
+		for _, raw := range params {
+			p, _ := d.resolve(raw, 0).(map[string]interface{})
+			name, _ := p["name"].(string)
+			in, _ := p["in"].(string)
+			required, _ := p["required"].(bool)
+			value, hasExample := d.parameterExample(p)
+			switch in {
+			case "path":
+				if hasExample {
+					value = url.PathEscape(value)
+				} else {
+					value = "${" + name + "}"
+				}
+				reqPath = strings.ReplaceAll(reqPath, "{"+name+"}", value)
+			case "query":
+				if required || hasExample {
+					if hasExample {
+						value = url.QueryEscape(value)
+					} else {
+						value = "${" + name + "}"
+					}
+					query = append(query, url.QueryEscape(name)+"="+value)
+				}
+			case "header":
+				if required || hasExample {
+					if !hasExample {
+						value = "${" + name + "}"
+					}
+					if step.Headers == nil {
+						step.Headers = map[string]string{}
+					}
+					step.Headers[name] = value
+				}
+			}
+		}
+		step.Path = prefix + reqPath
+		if len(query) > 0 {
+			step.Path += "?" + strings.Join(query, "&")
+		}
+
+		if body, contentType, ok := d.requestBodyExample(op); ok {
+			step.Body = body
+			if step.Headers == nil {
+				step.Headers = map[string]string{}
+			}
+			step.Headers["Content-Type"] = contentType
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// normalizeOperationSelector 将 "get /pets" 统一为 "GET /pets"
+func normalizeOperationSelector(sel string) string {
+	sel = strings.TrimSpace(sel)
+	if i := strings.IndexByte(sel, ' '); i > 0 {
+		method := strings.ToUpper(sel[:i])
+		for _, m := range openAPIMethods {
+			if strings.ToUpper(m) == method {
+				return method + " " + strings.TrimSpace(sel[i+1:])
+			}
+		}
+	}
+	return sel
+}
+
+// serverPathPrefix 取 servers[0].url 的路径部分，如 https://api.example.com/v1 → /v1
+func (d *OpenAPIDoc) serverPathPrefix() string {
+	servers := d.list(d.root["servers"])
+	if len(servers) == 0 {
+		return ""
+	}
+	server, _ := servers[0].(map[string]interface{})
+	raw, _ := server["url"].(string)
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimRight(u.Path, "/")
+}
+
+// parameterExample 参数示例：example → examples → schema.example/default/enum
+func (d *OpenAPIDoc) parameterExample(p map[string]interface{}) (string, bool) {
+	if v, ok := p["example"]; ok {
+		return scalarString(v), true
+	}
+	if examples, ok := p["examples"].(map[string]interface{}); ok {
+		for _, key := range sortedKeys(examples) {
+			if e, ok := d.resolve(examples[key], 0).(map[string]interface{}); ok {
+				if v, ok := e["value"]; ok {
+					return scalarString(v), true
+				}
+			}
+		}
+	}
+	schema, _ := d.resolve(p["schema"], 0).(map[string]interface{})
+	for _, key := range []string{"example", "default"} {
+		if v, ok := schema[key]; ok {
+			return scalarString(v), true
+		}
+	}
+	if enum := d.list(schema["enum"]); len(enum) > 0 {
+		return scalarString(enum[0]), true
+	}
+	return "", false
+}
+
+// requestBodyExample 生成 JSON 请求体示例，优先使用文档中的 example。
+// 有多种 JSON 内容类型时优先 application/json，其余按名称排序取第一个
+func (d *OpenAPIDoc) requestBodyExample(op map[string]interface{}) (string, string, bool) {
+	body, _ := d.resolve(op["requestBody"], 0).(map[string]interface{})
+	content, _ := body["content"].(map[string]interface{})
+	types := sortedKeys(content)
+	sort.SliceStable(types, func(i, j int) bool {
+		return isApplicationJSON(types[i]) && !isApplicationJSON(types[j])
+	})
+	for _, contentType := range types {
+		if !strings.Contains(contentType, "json") {
+			continue
+		}
+		media, _ := content[contentType].(map[string]interface{})
+		example, ok := media["example"]
+		if !ok {
+			if examples, ok2 := media["examples"].(map[string]interface{}); ok2 {
+				for _, key := range sortedKeys(examples) {
+					if e, ok3 := d.resolve(examples[key], 0).(map[string]interface{}); ok3 {
+						example, ok = e["value"]
+						break
+					}
+				}
+			}
+		}
+		if !ok {
+			example = d.schemaExample(media["schema"], 0)
+		}
+		data, err := json.Marshal(jsonCompatible(example))
+		if err != nil {
+			return "", "", false
+		}
+		return string(data), contentType, true
+	}
+	return "", "", false
+}
+
+// isApplicationJSON 内容类型是否为 application/json（忽略 charset 等参数）
+func isApplicationJSON(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	return strings.EqualFold(strings.TrimSpace(mediaType), "application/json")
+}
+
+// sortedKeys 按名称排序的 map 键，保证导入结果稳定
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// schemaExample 依据 schema 生成示例值
+func (d *OpenAPIDoc) schemaExample(raw interface{}, depth int) interface{} {
+	schema, _ := d.resolve(raw, 0).(map[string]interface{})
+	if schema == nil || depth > maxRefDepth {
+		return nil
+	}
+	for _, key := range []string{"example", "default"} {
+		if v, ok := schema[key]; ok {
+			return v
+		}
+	}
+	if enum := d.list(schema["enum"]); len(enum) > 0 {
+		return enum[0]
+	}
+	for _, key := range []string{"allOf", "oneOf", "anyOf"} {
+		if subs := d.list(schema[key]); len(subs) > 0 {
+			if key != "allOf" {
+				return d.schemaExample(subs[0], depth+1)
+			}
+			merged := map[string]interface{}{}
+			for _, sub := range subs {
+				if m, ok := d.schemaExample(sub, depth+1).(map[string]interface{}); ok {
+					for k, v := range m {
+						merged[k] = v
+					}
+				}
+			}
+			return merged
+		}
+	}
+
+	typ, _ := schema["type"].(string)
+	switch typ {
+	case "string":
+		switch schema["format"] {
+		case "date":
+			return "2024-01-01"
+		case "date-time":
+			return "2024-01-01T00:00:00Z"
+		case "email":
+			return "user@example.com"
+		case "uuid":
+			return "00000000-0000-0000-0000-000000000000"
+		}
+		return "string"
+	case "integer":
+		return 0
+	case "number":
+		return 0.0
+	case "boolean":
+		return false
+	case "array":
+		return []interface{}{d.schemaExample(schema["items"], depth+1)}
+	default:
+		obj := map[string]interface{}{}
+		props, _ := schema["properties"].(map[string]interface{})
+		for name, prop := range props {
+			obj[name] = d.schemaExample(prop, depth+1)
+		}
+		return obj
+	}
+}
+
+// resolve 解析文档内的 $ref（仅支持 #/ 开头的本地引用）
+func (d *OpenAPIDoc) resolve(v interface{}, depth int) interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok || depth > maxRefDepth {
+		return v
+	}
+	ref, ok := m["$ref"].(string)
+	if !ok || !strings.HasPrefix(ref, "#/") {
+		return v
+	}
+	var cur interface{} = d.root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		node, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		cur = node[part]
+	}
+	return d.resolve(cur, depth+1)
+}
+
+func (d *OpenAPIDoc) list(v interface{}) []interface{} {
+	l, _ := v.([]interface{})
+	return l
+}
+
+// scalarString 将示例值转为字符串
+func scalarString(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case nil:
+		return ""
+	default:
+		data, err := json.Marshal(jsonCompatible(x))
+		if err != nil {
+			return fmt.Sprint(x)
+		}
+		return string(data)
+	}
+}
+
+// jsonCompatible 将 YAML 解出的 map[interface{}]interface{} 转为可 JSON 编码的结构
+func jsonCompatible(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, val := range x {
+			m[fmt.Sprint(k)] = jsonCompatible(val)
+		}
+		return m
+	case map[string]interface{}:
+		for k, val := range x {
+			x[k] = jsonCompatible(val)
+		}
+		return x
+	case []interface{}:
+		for i, val := range x {
+			x[i] = jsonCompatible(val)
+		}
+		return x
+	default:
+		return v
+	}
+}
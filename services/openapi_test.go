@@ -0,0 +1,161 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const petstoreYAML = `
+openapi: 3.0.0
+servers:
+  - url: https://api.example.com/v1
+paths:
+  /pets/{petId}:
+    parameters:
+      - name: petId
+        in: path
+        required: true
+    get:
+      operationId: getPet
+      parameters:
+        - name: verbose
+          in: query
+          schema: {type: boolean, default: true}
+        - name: fields
+          in: query
+          required: true
+        - name: debug
+          in: query
+        - name: X-Request-Id
+          in: header
+          required: true
+        - name: X-Tenant
+          in: header
+          example: acme
+  /pets:
+    post:
+      requestBody:
+        content:
+          application/xml:
+            example: "<pet/>"
+          application/vnd.pet+json:
+            example: {kind: vendor}
+          application/json; charset=utf-8:
+            examples:
+              b: {value: {kind: b}}
+              a: {value: {kind: a}}
+    put:
+      operationId: putPet
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Pet'
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        name: {type: string}
+        age: {type: integer}
+        born: {type: string, format: date}
+        tags:
+          type: array
+          items: {type: string, enum: [cat, dog]}
+`
+
+func TestOpenAPIOperations(t *testing.T) {
+	doc, err := ParseOpenAPI([]byte(petstoreYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, o := range doc.Operations() {
+		got = append(got, o.ID)
+	}
+	want := []string{"POST /pets", "putPet", "getPet"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Operations = %q, want %q", got, want)
+	}
+}
+
+func TestOpenAPISteps(t *testing.T) {
+	doc, err := ParseOpenAPI([]byte(petstoreYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		selector string
+		wantPath string
+		wantBody string
+		wantHdrs map[string]string
+	}{
+		{
+			// 必填参数没有示例时转为运行时变量，可选且无示例的参数省略
+			selector: "getPet",
+			wantPath: "/v1/pets/${petId}?verbose=true&fields=${fields}",
+			wantHdrs: map[string]string{"X-Request-Id": "${X-Request-Id}", "X-Tenant": "acme"},
+		},
+		{
+			// 优先 application/json，多个 examples 按名称取第一个
+			selector: "post /pets",
+			wantPath: "/v1/pets",
+			wantBody: `{"kind":"a"}`,
+			wantHdrs: map[string]string{"Content-Type": "application/json; charset=utf-8"},
+		},
+		{
+			selector: "putPet",
+			wantPath: "/v1/pets",
+			wantBody: `{"age":0,"born":"2024-01-01","name":"string","tags":["cat"]}`,
+			wantHdrs: map[string]string{"Content-Type": "application/json"},
+		},
+	}
+	for _, tt := range tests {
+		// 多次生成结果应一致
+		for i := 0; i < 5; i++ {
+			steps, err := doc.Steps([]string{tt.selector})
+			if err != nil {
+				t.Fatalf("%s: %v", tt.selector, err)
+			}
+			s := steps[0]
+			if s.Path != tt.wantPath || s.Body != tt.wantBody || !reflect.DeepEqual(s.Headers, tt.wantHdrs) {
+				t.Errorf("%s: path=%q body=%q headers=%v, want path=%q body=%q headers=%v",
+					tt.selector, s.Path, s.Body, s.Headers, tt.wantPath, tt.wantBody, tt.wantHdrs)
+				break
+			}
+		}
+	}
+}
+
+func TestOpenAPIErrors(t *testing.T) {
+	doc, err := ParseOpenAPI([]byte(petstoreYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := doc.Steps([]string{"DELETE /pets"}); err == nil || !strings.Contains(err.Error(), "未找到操作") {
+		t.Errorf("未知操作: err = %v", err)
+	}
+	for _, data := range []string{"[1, 2", "swagger: '2.0'\npaths: {}"} {
+		if _, err := ParseOpenAPI([]byte(data)); err == nil {
+			t.Errorf("ParseOpenAPI(%q) 应当失败", data)
+		}
+	}
+}
+
+func TestIsApplicationJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"application/json", true},
+		{"Application/JSON; charset=utf-8", true},
+		{"application/problem+json", false},
+		{"text/plain", false},
+	}
+	for _, tt := range tests {
+		if got := isApplicationJSON(tt.in); got != tt.want {
+			t.Errorf("isApplicationJSON(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"loadtest_project/models"
+)
+
+// postmanVarPattern 匹配 Postman 变量 {{name}}，secret 引用 {{secret:x}} 不在此列
+var postmanVarPattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// placeholderPattern 导入结果中须原样保留的占位符：运行时变量 ${name} 与 secret 引用
+var placeholderPattern = regexp.MustCompile(`\$\{[A-Za-z0-9_.-]+\}|\{\{\s*secret:[A-Za-z0-9_.-]+\s*\}\}`)
+
+// basicAuthVar Basic 认证的用户名或密码在导入时未知（运行时变量或 secret）时，
+// Authorization 头改为引用该运行时变量，需由数据集提供 base64(用户名:密码)
+const basicAuthVar = "basic_auth"
+
+type postmanCollection struct {
+	Info struct {
+		Schema string `json:"schema"`
+	} `json:"info"`
+	Item     []postmanItem     `json:"item"`
+	Variable []postmanKeyValue `json:"variable"`
+	Auth     *postmanAuth      `json:"auth"`
+}
+
+type postmanItem struct {
+	Name    string          `json:"name"`
+	Item    []postmanItem   `json:"item"` // 文件夹
+	Request *postmanRequest `json:"request"`
+	Auth    *postmanAuth    `json:"auth"` // 文件夹级认证，由其中的请求继承
+}
+
+type postmanRequest struct {
+	Method string            `json:"method"`
+	URL    json.RawMessage   `json:"url"` // 字符串或对象
+	Header []postmanKeyValue `json:"header"`
+	Body   *struct {
+		Mode       string            `json:"mode"`
+		Raw        string            `json:"raw"`
+		URLEncoded []postmanKeyValue `json:"urlencoded"`
+		Options    struct {
+			Raw struct {
+				Language string `json:"language"`
+			} `json:"raw"`
+		} `json:"options"`
+	} `json:"body"`
+	Auth *postmanAuth `json:"auth"`
+}
+
+// postmanAuth 请求、文件夹或集合的认证；未设置或 type 为 inherit 时沿用上一级，noauth 表示不认证
+type postmanAuth struct {
+	Type   string            `json:"type"`
+	Bearer []postmanKeyValue `json:"bearer"`
+	Basic  []postmanKeyValue `json:"basic"`
+}
+
+// inherit 返回在 parent 之下生效的认证
+func (a *postmanAuth) inherit(parent *postmanAuth) *postmanAuth {
+	if a == nil || a.Type == "inherit" {
+		return parent
+	}
+	return a
+}
+
+type postmanKeyValue struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled"`
+}
+
+// ImportPostman 将 Postman v2.1 集合（含文件夹）按顺序转换为场景步骤。
+// 集合变量与 vars 中的值直接替换（vars 优先），未定义的 {{变量}} 转为运行时 ${变量}；
+// URL 以未定义变量开头时（如 {{baseUrl}}/users）视为压测目标地址，仅保留路径。
+// 认证按请求、文件夹、集合的顺序继承
+func ImportPostman(data []byte, vars map[string]string, baseURL string) ([]models.ScenarioStep, error) {
+	var col postmanCollection
+	if err := json.Unmarshal(data, &col); err != nil {
+		return nil, fmt.Errorf("Postman 集合解析失败: %w", err)
+	}
+	if !strings.Contains(col.Info.Schema, "v2.1") {
+		return nil, fmt.Errorf("仅支持 Postman v2.1 集合")
+	}
+
+	values := postmanVars{}
+	for _, v := range col.Variable {
+		if !v.Disabled {
+			values[v.Key] = v.Value
+		}
+	}
+	for k, v := range vars {
+		values[k] = v
+	}
+
+	var steps []models.ScenarioStep
+	var walk func(items []postmanItem, folder string, auth *postmanAuth) error
+	walk = func(items []postmanItem, folder string, auth *postmanAuth) error {
+		for _, it := range items {
+			name := it.Name
+			if folder != "" {
+				name = folder + "/" + it.Name
+			}
+			if it.Request == nil {
+				if err := walk(it.Item, name, it.Auth.inherit(auth)); err != nil {
+					return err
+				}
+				continue
+			}
+			step, err := postmanStep(it.Request, name, values, baseURL, it.Request.Auth.inherit(auth))
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			steps = append(steps, step)
+		}
+		return nil
+	}
+	if err := walk(col.Item, "", col.Auth.inherit(nil)); err != nil {
+		return nil, err
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("集合中没有请求")
+	}
+	return steps, nil
+}
+
+// postmanVars Postman 变量表
+type postmanVars map[string]string
+
+// expand 替换已定义的 {{变量}}，未定义的转为运行时 ${变量}
+func (v postmanVars) expand(s string) string {
+	return postmanVarPattern.ReplaceAllStringFunc(s, func(m string) string {
+		name := postmanVarPattern.FindStringSubmatch(m)[1]
+		if val, ok := v[name]; ok {
+			return val
+		}
+		return "${" + name + "}"
+	})
+}
+
+// postmanStep 转换单个请求，auth 为继承后生效的认证
+func postmanStep(r *postmanRequest, name string, vars postmanVars, baseURL string, auth *postmanAuth) (models.ScenarioStep, error) {
+	expand := vars.expand
+
+	step := models.ScenarioStep{Name: name, Method: strings.ToUpper(r.Method)}
+	if step.Method == "" {
+		step.Method = "GET"
+	}
+
+	// url 可能是字符串，也可能是带 raw 字段的对象
+	var rawURL string
+	if err := json.Unmarshal(r.URL, &rawURL); err != nil {
+		var obj struct {
+			Raw string `json:"raw"`
+		}
+		if err := json.Unmarshal(r.URL, &obj); err != nil {
+			return step, fmt.Errorf("无法解析 url")
+		}
+		rawURL = obj.Raw
+	}
+	// 以未定义变量开头的 URL 视为相对目标地址
+	if m := postmanVarPattern.FindStringSubmatchIndex(rawURL); m != nil && m[0] == 0 {
+		if _, defined := vars[rawURL[m[2]:m[3]]]; !defined {
+			rawURL = rawURL[m[1]:]
+		}
+	}
+	step.Path = importedURL(expand(rawURL), baseURL)
+
+	for _, h := range r.Header {
+		if h.Disabled || skippedDefinedHeaders[strings.ToLower(h.Key)] {
+			continue
+		}
+		setStepHeader(&step, h.Key, expand(h.Value))
+	}
+	if auth != nil {
+		switch auth.Type {
+		case "bearer":
+			setStepHeader(&step, "Authorization", "Bearer "+expand(postmanValue(auth.Bearer, "token")))
+		case "basic":
+			cred := expand(postmanValue(auth.Basic, "username")) + ":" + expand(postmanValue(auth.Basic, "password"))
+			if placeholderPattern.MatchString(cred) {
+				// 编码后占位符无法在运行时替换
+				setStepHeader(&step, "Authorization", "Basic ${"+basicAuthVar+"}")
+			} else {
+				setStepHeader(&step, "Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(cred)))
+			}
+		}
+	}
+	if r.Body != nil {
+		switch r.Body.Mode {
+		case "raw":
+			step.Body = expand(r.Body.Raw)
+			if r.Body.Options.Raw.Language == "json" && step.Headers["Content-Type"] == "" {
+				setStepHeader(&step, "Content-Type", "application/json")
+			}
+		case "urlencoded":
+			// 按原顺序编码，占位符保持原样以便运行时替换
+			var form []string
+			for _, kv := range r.Body.URLEncoded {
+				if !kv.Disabled {
+					form = append(form, formEscape(expand(kv.Key))+"="+formEscape(expand(kv.Value)))
+				}
+			}
+			step.Body = strings.Join(form, "&")
+			setStepHeader(&step, "Content-Type", "application/x-www-form-urlencoded")
+		}
+	}
+	return step, nil
+}
+
+// formEscape 按表单编码转义，占位符原样保留
+func formEscape(s string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range placeholderPattern.FindAllStringIndex(s, -1) {
+		b.WriteString(url.QueryEscape(s[last:m[0]]))
+		b.WriteString(s[m[0]:m[1]])
+		last = m[1]
+	}
+	b.WriteString(url.QueryEscape(s[last:]))
+	return b.String()
+}
+
+func postmanValue(list []postmanKeyValue, key string) string {
+	for _, kv := range list {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return ""
+}
+
+func setStepHeader(step *models.ScenarioStep, name, value string) {
+	if step.Headers == nil {
+		step.Headers = map[string]string{}
+	}
+	step.Headers[name] = value
+}
+
+// importedURL 将导入的 URL 转为步骤路径：同源或无主机时保留路径，否则保留完整 URL
+func importedURL(raw, baseURL string) string {
+	if strings.HasPrefix(raw, "/") {
+		return raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		// 主机部分含运行时变量等无法解析的情况，原样保留
+		return raw
+	}
+	if u.Host == "" {
+		return "/" + strings.TrimLeft(raw, "/")
+	}
+	return relativeURL(u, baseURL)
+}
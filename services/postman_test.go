@@ -0,0 +1,139 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const postmanSchema = `"info": {"schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"}`
+
+func TestImportPostman(t *testing.T) {
+	tests := []struct {
+		name     string
+		vars     map[string]string
+		items    string
+		extra    string // 集合级字段
+		wantPath string
+		wantBody string
+		wantHdrs map[string]string
+	}{
+		{
+			name:     "未定义的 baseUrl 视为目标地址",
+			items:    `{"name": "list", "request": {"method": "get", "url": "{{baseUrl}}/users?id={{userId}}"}}`,
+			wantPath: "/users?id=${userId}",
+		},
+		{
+			name:     "集合变量与 vars 覆盖",
+			vars:     map[string]string{"userId": "7"},
+			extra:    `"variable": [{"key": "userId", "value": "1"}, {"key": "token", "value": "abc"}],`,
+			items:    `{"name": "get", "request": {"method": "GET", "url": {"raw": "/users/{{userId}}"}, "header": [{"key": "X-Token", "value": "{{token}}"}, {"key": "Host", "value": "x"}]}}`,
+			wantPath: "/users/7",
+			wantHdrs: map[string]string{"X-Token": "abc"},
+		},
+		{
+			name:     "raw JSON 请求体",
+			items:    `{"name": "create", "request": {"method": "POST", "url": "/users", "body": {"mode": "raw", "raw": "{\"name\": \"{{name}}\"}", "options": {"raw": {"language": "json"}}}}}`,
+			wantPath: "/users",
+			wantBody: `{"name": "${name}"}`,
+			wantHdrs: map[string]string{"Content-Type": "application/json"},
+		},
+		{
+			name:     "表单按原顺序编码并保留占位符",
+			items:    `{"name": "login", "request": {"method": "POST", "url": "/login", "body": {"mode": "urlencoded", "urlencoded": [{"key": "user", "value": "{{user}}"}, {"key": "pass", "value": "a&b c"}, {"key": "off", "value": "1", "disabled": true}]}}}`,
+			wantPath: "/login",
+			wantBody: "user=${user}&pass=a%26b+c",
+			wantHdrs: map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+		},
+		{
+			name:     "Basic 认证已知凭据直接编码",
+			items:    `{"name": "b", "request": {"method": "GET", "url": "/b", "auth": {"type": "basic", "basic": [{"key": "username", "value": "admin"}, {"key": "password", "value": "secret"}]}}}`,
+			wantPath: "/b",
+			wantHdrs: map[string]string{"Authorization": "Basic YWRtaW46c2VjcmV0"},
+		},
+		{
+			name:     "Basic 认证含运行时变量",
+			items:    `{"name": "b", "request": {"method": "GET", "url": "/b", "auth": {"type": "basic", "basic": [{"key": "username", "value": "{{user}}"}, {"key": "password", "value": "p"}]}}}`,
+			wantPath: "/b",
+			wantHdrs: map[string]string{"Authorization": "Basic ${basic_auth}"},
+		},
+		{
+			name:     "继承集合级认证",
+			extra:    `"auth": {"type": "bearer", "bearer": [{"key": "token", "value": "{{token}}"}]},`,
+			items:    `{"name": "c", "request": {"method": "GET", "url": "/c"}}`,
+			wantPath: "/c",
+			wantHdrs: map[string]string{"Authorization": "Bearer ${token}"},
+		},
+		{
+			name:     "文件夹认证覆盖集合认证",
+			extra:    `"auth": {"type": "bearer", "bearer": [{"key": "token", "value": "col"}]},`,
+			items:    `{"name": "f", "auth": {"type": "bearer", "bearer": [{"key": "token", "value": "folder"}]}, "item": [{"name": "d", "request": {"method": "GET", "url": "/d", "auth": {"type": "inherit"}}}]}`,
+			wantPath: "/d",
+			wantHdrs: map[string]string{"Authorization": "Bearer folder"},
+		},
+		{
+			name:     "noauth 不继承",
+			extra:    `"auth": {"type": "bearer", "bearer": [{"key": "token", "value": "col"}]},`,
+			items:    `{"name": "e", "request": {"method": "GET", "url": "/e", "auth": {"type": "noauth"}}}`,
+			wantPath: "/e",
+		},
+	}
+	for _, tt := range tests {
+		data := `{` + postmanSchema + `, ` + tt.extra + ` "item": [` + tt.items + `]}`
+		steps, err := ImportPostman([]byte(data), tt.vars, "")
+		if err != nil {
+			t.Errorf("%s: 意外错误 %v", tt.name, err)
+			continue
+		}
+		if len(steps) != 1 {
+			t.Errorf("%s: 得到 %d 个步骤, want 1", tt.name, len(steps))
+			continue
+		}
+		s := steps[0]
+		if s.Path != tt.wantPath || s.Body != tt.wantBody {
+			t.Errorf("%s: path=%q body=%q, want path=%q body=%q", tt.name, s.Path, s.Body, tt.wantPath, tt.wantBody)
+		}
+		if len(s.Headers) != len(tt.wantHdrs) || (len(tt.wantHdrs) > 0 && !reflect.DeepEqual(s.Headers, tt.wantHdrs)) {
+			t.Errorf("%s: headers = %v, want %v", tt.name, s.Headers, tt.wantHdrs)
+		}
+	}
+}
+
+func TestImportPostmanFolders(t *testing.T) {
+	data := `{` + postmanSchema + `, "item": [
+		{"name": "users", "item": [
+			{"name": "list", "request": {"method": "GET", "url": "/users"}},
+			{"name": "admin", "item": [{"name": "ban", "request": {"method": "POST", "url": "/ban"}}]}
+		]},
+		{"name": "health", "request": {"url": "/health"}}
+	]}`
+	steps, err := ImportPostman([]byte(data), nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, s := range steps {
+		got = append(got, s.Method+" "+s.Name)
+	}
+	want := []string{"GET users/list", "POST users/admin/ban", "GET health"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("steps = %q, want %q", got, want)
+	}
+}
+
+func TestImportPostmanErrors(t *testing.T) {
+	tests := []struct {
+		name, data, wantErr string
+	}{
+		{"非 JSON", `not json`, "Postman 集合解析失败"},
+		{"v2.0 集合", `{"info": {"schema": "https://schema.getpostman.com/json/collection/v2.0.0/collection.json"}, "item": []}`, "仅支持 Postman v2.1"},
+		{"没有请求", `{` + postmanSchema + `, "item": [{"name": "empty", "item": []}]}`, "集合中没有请求"},
+		{"url 无法解析", `{` + postmanSchema + `, "item": [{"name": "bad", "request": {"url": 1}}]}`, "无法解析 url"},
+	}
+	for _, tt := range tests {
+		_, err := ImportPostman([]byte(tt.data), nil, "")
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// runGracePeriod 运行窗口之外额外允许的时间（启动、等待 worker、写出结果），超过即视为卡死
+const runGracePeriod = 2 * time.Minute
+
+// stopGracePeriod 发送中断后等待进程自行退出的时间，超时则强制结束整个进程组
+const stopGracePeriod = 15 * time.Second
+
+// errRunTimeout runner 超过硬性期限仍未退出
+var errRunTimeout = errors.New("运行超时")
+
+// runDeadline 单次运行的硬性期限：运行窗口加宽限时间
+func runDeadline(runTime time.Duration) time.Duration {
+	return runTime + runGracePeriod
+}
+
+// stopRunner 先中断整个进程组，stopGracePeriod 内未退出则强制结束；waitErr 为 cmd.Wait 的结果
+func stopRunner(cmd *exec.Cmd, waitErr <-chan error) {
+	if err := interruptProcessGroup(cmd); err == nil {
+		select {
+		case <-waitErr:
+			// 主进程已退出，仍清理可能残留的子进程
+			killProcessGroup(cmd)
+			return
+		case <-time.After(stopGracePeriod):
+			fmt.Printf("进程 %d 在 %s 内未退出，强制结束\n", cmd.Process.Pid, stopGracePeriod)
+		}
+	}
+	if err := killProcessGroup(cmd); err != nil {
+		fmt.Printf("结束进程 %d 失败: %v\n", cmd.Process.Pid, err)
+	}
+	<-waitErr
+}
@@ -0,0 +1,28 @@
+//go:build windows
+
+package services
+
+import (
+	"errors"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// setProcessGroup 让 runner 及其子进程处于独立的进程组，便于整组结束
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// interruptProcessGroup Windows 无法向其他控制台进程发送 Ctrl+C，调用方应直接结束进程
+func interruptProcessGroup(cmd *exec.Cmd) error {
+	return errors.New("Windows 不支持中断进程组")
+}
+
+// killProcessGroup 结束进程及其全部子进程
+func killProcessGroup(cmd *exec.Cmd) error {
+	if err := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run(); err != nil {
+		return cmd.Process.Kill()
+	}
+	return nil
+}
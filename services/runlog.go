@@ -0,0 +1,219 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"loadtest_project/models"
+)
+
+// 单次运行日志的大小上限。超出后不再写入中间部分，结束时在文件末尾补上最后 runLogTailSize 字节，
+// 失败原因通常在末尾
+const (
+	maxRunLogSize  = 10 << 20
+	runLogTailSize = 256 << 10
+)
+
+// runLogMaxLine 没有换行时按该长度强制切分，避免异常输出占满内存
+const runLogMaxLine = 64 << 10
+
+// failureContextLines 用于摘要失败原因的末尾行数
+const failureContextLines = 50
+
+// maxFailureReason 失败原因摘要的最大字节数，与 failure_reason 列宽一致
+const maxFailureReason = 1024
+
+// runLogFile 本次运行的日志文件
+func runLogFile(prefix string) string {
+	return filepath.Join(resultsDir, prefix+".log")
+}
+
+// runLog 收集 runner 的 stdout/stderr：按行脱敏 secrets 后写入文件，超过上限时只保留开头与末尾
+type runLog struct {
+	mu      sync.Mutex
+	f       *os.File
+	secrets *secretResolver
+	pending []byte   // 尚未遇到换行的部分
+	written int64    // 已写入文件的字节数
+	dropped int64    // 超过上限未写入文件的字节数
+	tail    []byte   // 超过上限后的末尾内容
+	recent  []string // 最近的非空行，用于摘要失败原因
+}
+
+func newRunLog(path string, secrets *secretResolver) (*runLog, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &runLog{f: f, secrets: secrets}, nil
+}
+
+// Write 实现 io.Writer，cmd.Stdout 与 cmd.Stderr 共用同一个 runLog
+func (l *runLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pending = append(l.pending, p...)
+	for {
+		i := bytes.IndexByte(l.pending, '\n')
+		if i < 0 && len(l.pending) < runLogMaxLine {
+			break
+		}
+		if i < 0 {
+			i = runLogMaxLine - 1
+		}
+		l.emit(l.pending[:i+1])
+		l.pending = l.pending[i+1:]
+	}
+	return len(p), nil
+}
+
+// emit 写出一整行，调用方持有锁
+func (l *runLog) emit(line []byte) {
+	line = l.secrets.Redact(line)
+	if s := strings.TrimSpace(string(line)); s != "" {
+		l.recent = append(l.recent, s)
+		if len(l.recent) > failureContextLines {
+			l.recent = l.recent[1:]
+		}
+	}
+
+	if l.dropped == 0 && l.written+int64(len(line)) <= maxRunLogSize-runLogTailSize {
+		n, _ := l.f.Write(line)
+		l.written += int64(n)
+		return
+	}
+	l.dropped += int64(len(line))
+	l.tail = append(l.tail, line...)
+	if len(l.tail) > runLogTailSize {
+		l.tail = append(l.tail[:0:0], l.tail[len(l.tail)-runLogTailSize:]...)
+	}
+}
+
+// Close 写出剩余内容并关闭文件，返回文件大小与是否被截断
+func (l *runLog) Close() (size int64, truncated bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.pending) > 0 {
+		l.emit(append(l.pending, '\n'))
+		l.pending = nil
+	}
+	if l.dropped > 0 {
+		omitted := l.dropped - int64(len(l.tail))
+		if omitted > 0 {
+			n, _ := fmt.Fprintf(l.f, "\n...... 日志超过 %d MB，中间省略 %d 字节 ......\n\n", maxRunLogSize>>20, omitted)
+			l.written += int64(n)
+		}
+		n, _ := l.f.Write(l.tail)
+		l.written += int64(n)
+	}
+	l.f.Close()
+	return l.written, l.dropped > 0
+}
+
+// pythonExceptionPattern Python traceback 的最后一行，如 "ModuleNotFoundError: No module named 'x'"
+var pythonExceptionPattern = regexp.MustCompile(`^[A-Za-z_][\w.]*(Error|Exception|Exit)\b`)
+
+// FailureReason 根据日志末尾摘要失败原因：优先取 Python 异常行，其次取最后一条错误日志。
+// 应在 Close 之后调用，以包含最后一行不完整的输出
+func (l *runLog) FailureReason(runErr error) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var reason string
+	for i := len(l.recent) - 1; i >= 0 && reason == ""; i-- {
+		if pythonExceptionPattern.MatchString(l.recent[i]) {
+			reason = l.recent[i]
+		}
+	}
+	for i := len(l.recent) - 1; i >= 0 && reason == ""; i-- {
+		// Locust 日志形如 "[时间] 主机/ERROR/locust.main: ..."
+		if strings.Contains(l.recent[i], "/ERROR/") || strings.Contains(l.recent[i], "/CRITICAL/") {
+			reason = l.recent[i]
+		}
+	}
+	switch {
+	case reason == "":
+		reason = runErr.Error()
+	case runErr != nil:
+		reason = fmt.Sprintf("%s (%v)", reason, runErr)
+	}
+	return truncateUTF8(reason, maxFailureReason)
+}
+
+// truncateUTF8 按字节截断且不切断多字节字符
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// startRun 创建运行记录与日志文件
+func startRun(task models.LoadTest, prefix string, secrets *secretResolver) (*models.Run, *runLog, error) {
+	run := &models.Run{TestID: task.ID, Prefix: prefix, LogPath: runLogFile(prefix)}
+	output, err := newRunLog(run.LogPath, secrets)
+	if err != nil {
+		return nil, nil, fmt.Errorf("创建运行日志失败: %w", err)
+	}
+	if err := models.CreateRun(run); err != nil {
+		output.Close()
+		return nil, nil, fmt.Errorf("创建运行记录失败: %w", err)
+	}
+	return run, output, nil
+}
+
+// saveRunResult 以 prefix 对应的运行保存结果；该运行已有结果（如已经 /api/upload_result 上传）时返回错误
+func saveRunResult(prefix string, result *models.TestResult) error {
+	run, err := models.GetRunByPrefix(prefix)
+	if err != nil {
+		return err
+	}
+	saved, err := models.SaveRunResult(run.ID, result)
+	if err != nil {
+		return err
+	}
+	if !saved {
+		return errors.New("该运行已有结果")
+	}
+	return nil
+}
+
+// finishRun 关闭日志并记录运行结果；runErr 非空时摘要失败原因
+func finishRun(run *models.Run, output *runLog, abortReason string, runErr error) {
+	run.LogSize, run.LogTruncated = output.Close()
+	switch {
+	case errors.Is(runErr, errRunTimeout):
+		run.Status = models.RunStatusTimeout
+		run.FailureReason = truncateUTF8(runErr.Error(), maxFailureReason)
+	case runErr != nil:
+		run.Status = models.RunStatusFailed
+		run.FailureReason = output.FailureReason(runErr)
+	case abortReason != "":
+		run.Status = models.RunStatusAborted
+		run.FailureReason = truncateUTF8("已中止: "+abortReason, maxFailureReason)
+	default:
+		run.Status = models.RunStatusSucceeded
+	}
+	if err := models.FinishRun(run); err != nil {
+		fmt.Println("更新运行记录失败:", err)
+	}
+}
+
+// runError 运行失败：Error 返回从日志摘要的失败原因，Unwrap 返回原始错误
+type runError struct {
+	reason string
+	cause  error
+}
+
+func (e *runError) Error() string { return e.reason }
+
+func (e *runError) Unwrap() error { return e.cause }
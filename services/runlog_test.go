@@ -0,0 +1,186 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func newTestRunLog(t *testing.T) (*runLog, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "run.log")
+	secrets := newSecretResolver(1, 1)
+	secrets.cache["db_password"] = "hunter2"
+	l, err := newRunLog(path, secrets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l, path
+}
+
+func TestRunLogBelowLimit(t *testing.T) {
+	l, path := newTestRunLog(t)
+	fmt.Fprintf(l, "connecting with password hunter2\n")
+	l.Write([]byte("partial "))
+	l.Write([]byte("line\nno newline at end"))
+	size, truncated := l.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "connecting with password ******\npartial line\nno newline at end\n"
+	if string(data) != want || size != int64(len(want)) || truncated {
+		t.Errorf("日志 = %q（size %d, truncated %v），want %q", data, size, truncated, want)
+	}
+}
+
+func TestRunLogTruncatesAtSizeCap(t *testing.T) {
+	l, path := newTestRunLog(t)
+	line := strings.Repeat("x", 1023) + "\n"
+	total := maxRunLogSize/len(line) + 512 // 超过上限约 512 KB
+	for i := 0; i < total; i++ {
+		if i == 0 {
+			fmt.Fprintf(l, "first line\n")
+			continue
+		}
+		if i == total-1 {
+			fmt.Fprintf(l, "Traceback: last line hunter2\n")
+			continue
+		}
+		l.Write([]byte(line))
+	}
+	size, truncated := l.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !truncated {
+		t.Fatal("超过上限时应标记为已截断")
+	}
+	if size != int64(len(data)) || size > maxRunLogSize+200 {
+		t.Errorf("文件大小 %d 应约为上限 %d", size, maxRunLogSize)
+	}
+	if !bytes.HasPrefix(data, []byte("first line\n")) {
+		t.Error("应保留日志开头")
+	}
+	if !bytes.HasSuffix(data, []byte("Traceback: last line ******\n")) {
+		t.Error("应保留已脱敏的日志末尾")
+	}
+	if !bytes.Contains(data, []byte("中间省略")) {
+		t.Error("应说明中间省略的字节数")
+	}
+	marker := []byte(" ......\n\n")
+	if tail := data[bytes.LastIndex(data, marker)+len(marker):]; len(tail) > runLogTailSize {
+		t.Errorf("末尾保留 %d 字节，超过 %d", len(tail), runLogTailSize)
+	}
+}
+
+func TestRunLogSplitsLongLines(t *testing.T) {
+	l, path := newTestRunLog(t)
+	output := bytes.Repeat([]byte("y"), runLogMaxLine*2+10)
+	l.Write(output)
+	if len(l.pending) >= runLogMaxLine {
+		t.Errorf("没有换行的输出应按 %d 字节切分写出，仍缓存 %d 字节", runLogMaxLine, len(l.pending))
+	}
+	l.Close()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, append(output, '\n')) {
+		t.Errorf("切分不应改变写入文件的内容，got %d 字节", len(data))
+	}
+	if len(l.recent) != 3 {
+		t.Errorf("切分后的每段各算一行，got %d 行", len(l.recent))
+	}
+}
+
+func TestRunLogFailureReason(t *testing.T) {
+	exitErr := errors.New("exit status 1")
+	tests := []struct {
+		name   string
+		output string
+		runErr error
+		want   string
+	}{
+		{
+			name:   "优先取 Python 异常行",
+			output: "Traceback (most recent call last):\n  File \"locustfile.py\", line 3\nModuleNotFoundError: No module named 'grpc'\n[2024-01-01] host/ERROR/locust.main: shutting down\n",
+			runErr: exitErr,
+			want:   "ModuleNotFoundError: No module named 'grpc' (exit status 1)",
+		},
+		{
+			name:   "多个异常时取最后一个",
+			output: "ValueError: first\nsome output\nKeyError: 'user'\n",
+			runErr: exitErr,
+			want:   "KeyError: 'user' (exit status 1)",
+		},
+		{
+			name:   "SystemExit 也算异常",
+			output: "SystemExit: 2\n",
+			runErr: exitErr,
+			want:   "SystemExit: 2 (exit status 1)",
+		},
+		{
+			name:   "没有异常时取最后一条 ERROR 日志",
+			output: "[t] host/ERROR/locust.main: first\n[t] host/INFO/locust.main: info\n[t] host/ERROR/locust.runners: second\n",
+			runErr: exitErr,
+			want:   "[t] host/ERROR/locust.runners: second (exit status 1)",
+		},
+		{
+			name:   "CRITICAL 日志",
+			output: "[t] host/CRITICAL/locust.main: unhandled\n",
+			runErr: exitErr,
+			want:   "[t] host/CRITICAL/locust.main: unhandled (exit status 1)",
+		},
+		{
+			name:   "日志中没有线索时使用运行错误",
+			output: "all good\n",
+			runErr: exitErr,
+			want:   "exit status 1",
+		},
+		{
+			name:   "最后一行没有换行也会被采用",
+			output: "RuntimeError: crashed",
+			runErr: exitErr,
+			want:   "RuntimeError: crashed (exit status 1)",
+		},
+		{
+			name:   "异常行中的 secret 已脱敏",
+			output: "ConnectionError: auth failed for hunter2\n",
+			runErr: exitErr,
+			want:   "ConnectionError: auth failed for ****** (exit status 1)",
+		},
+		{
+			name:   "超出最近行数的异常不再采用",
+			output: "OldError: too early\n" + strings.Repeat("noise\n", failureContextLines),
+			runErr: exitErr,
+			want:   "exit status 1",
+		},
+	}
+	for _, tt := range tests {
+		l, _ := newTestRunLog(t)
+		l.Write([]byte(tt.output))
+		l.Close()
+		if got := l.FailureReason(tt.runErr); got != tt.want {
+			t.Errorf("%s: FailureReason = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRunLogFailureReasonTruncated(t *testing.T) {
+	l, _ := newTestRunLog(t)
+	l.Write([]byte("ValueError: " + strings.Repeat("错", maxFailureReason) + "\n"))
+	l.Close()
+	got := l.FailureReason(errors.New("exit status 1"))
+	if len(got) > maxFailureReason || !utf8.ValidString(got) || !strings.HasPrefix(got, "ValueError: 错") {
+		t.Errorf("失败原因应截断到 %d 字节且不切断多字节字符，got %d 字节", maxFailureReason, len(got))
+	}
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"loadtest_project/config"
+	"loadtest_project/models"
+	"loadtest_project/utils"
+)
+
+// RunnerConfigEnv Locust 通过该环境变量读取运行配置文件路径
+const RunnerConfigEnv = "LOADTEST_CONFIG"
+
+// RunnerConfig 传给 Runner 的运行配置，写入 JSON 文件后由 locustfile.py 读取
+type RunnerConfig struct {
+	Headers map[string]string `json:"headers,omitempty"`
+	Cookies map[string]string `json:"cookies,omitempty"`
+	Auth    *RunnerAuth       `json:"auth,omitempty"`
+	TLS     RunnerTLS         `json:"tls"`
+	Steps   []RunnerStep      `json:"steps,omitempty"`
+	Dataset *RunnerDataset    `json:"dataset,omitempty"`
+	// ChecksOutput Runner 退出时写入断言统计的文件
+	ChecksOutput string      `json:"checks_output,omitempty"`
+	GRPC         *RunnerGRPC `json:"grpc,omitempty"`
+	// HistogramOutput Runner 退出时写入各端点响应时间分布的文件
+	HistogramOutput string `json:"histogram_output,omitempty"`
+	// Transport HTTP 版本、连接复用、超时与重定向选项
+	Transport models.TransportOptions `json:"transport"`
+	// ResultToken 本次运行上传结果（POST /api/upload_result 的 X-Run-Token 头）所需的签名令牌。
+	// 内置 locustfile 不上传，结果由 Go 端解析 CSV 后保存；供自定义 locustfile 使用，每次运行只保存一条结果
+	ResultToken string `json:"result_token,omitempty"`
+	// WSOutput Runner 退出时写入 WebSocket 事件统计的文件，WS 事件不计入 HTTP 汇总
+	WSOutput string `json:"ws_output,omitempty"`
+	// CPUOutput 运行期间定期写入各 Locust 进程（本机或 master 及各 worker）CPU 使用率的文件
+	CPUOutput string `json:"cpu_output,omitempty"`
+}
+
+// RunnerGRPC gRPC 步骤的消息描述来源，DescriptorFile 为空时使用服务端反射
+type RunnerGRPC struct {
+	DescriptorFile string `json:"descriptor_file,omitempty"`
+	// StatusOutput Runner 退出时写入各方法状态码分布的文件
+	StatusOutput string `json:"status_output"`
+}
+
+// RunnerStep 场景步骤，${变量} 由 Runner 按虚拟用户替换
+type RunnerStep struct {
+	Type       string                 `json:"type"`
+	Name       string                 `json:"name"`
+	Method     string                 `json:"method"`
+	Path       string                 `json:"path"`
+	Headers    map[string]string      `json:"headers,omitempty"`
+	Body       string                 `json:"body,omitempty"`
+	ThinkTime  int                    `json:"think_time"`
+	Timeout    int                    `json:"timeout,omitempty"`
+	Checks     []models.StepCheck     `json:"checks,omitempty"`
+	Extractors []models.StepExtractor `json:"extractors,omitempty"`
+}
+
+// RunnerDataset 参数化数据集文件及分配方式
+type RunnerDataset struct {
+	File string `json:"file"`
+	Mode string `json:"mode"`
+}
+
+// RunnerAuth 目标认证信息
+type RunnerAuth struct {
+	Type     string `json:"type"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Token    string `json:"token,omitempty"`
+}
+
+// RunnerTLS 证书相关选项，PEM 内容另存为文件后传递路径
+type RunnerTLS struct {
+	SkipVerify bool   `json:"skip_verify"`
+	CAFile     string `json:"ca_file,omitempty"`
+	CertFile   string `json:"cert_file,omitempty"`
+	KeyFile    string `json:"key_file,omitempty"`
+}
+
+// writeRunnerConfig 生成本次运行的配置文件（仅所有者可读），返回路径与清理函数。
+// 配置中的 secret 引用由 secrets 解析为明文，运行结束后必须调用 cleanup 删除
+func writeRunnerConfig(task models.LoadTest, prefix string, secrets *secretResolver) (path string, cleanup func(), err error) {
+	var files []string
+	cleanup = func() {
+		for _, f := range files {
+			os.Remove(f)
+		}
+	}
+	writeFile := func(suffix string, data []byte) (string, error) {
+		p, err := filepath.Abs(filepath.Join(resultsDir, prefix+suffix))
+		if err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(p, data, 0600); err != nil {
+			return "", err
+		}
+		files = append(files, p)
+		return p, nil
+	}
+
+	var cfg RunnerConfig
+	opts, err := models.GetTargetOptions(task.ID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		// 未配置目标选项
+	case err != nil:
+		return "", cleanup, err
+	default:
+		if cfg.Headers, err = secrets.ResolveMap(opts.Headers); err != nil {
+			return "", cleanup, err
+		}
+		if cfg.Cookies, err = secrets.ResolveMap(opts.Cookies); err != nil {
+			return "", cleanup, err
+		}
+		if opts.AuthType != models.AuthNone {
+			cfg.Auth = &RunnerAuth{Type: opts.AuthType, Username: opts.Username}
+			if cfg.Auth.Password, err = secrets.Resolve(opts.Password); err != nil {
+				return "", cleanup, err
+			}
+			if cfg.Auth.Token, err = secrets.Resolve(opts.Token); err != nil {
+				return "", cleanup, err
+			}
+		}
+		cfg.TLS.SkipVerify = opts.TLSSkipVerify
+		if opts.CABundle != "" {
+			if cfg.TLS.CAFile, err = writeFile("_ca.pem", []byte(opts.CABundle)); err != nil {
+				return "", cleanup, err
+			}
+		}
+		if opts.ClientCert != "" {
+			if cfg.TLS.CertFile, err = writeFile("_client.pem", []byte(opts.ClientCert)); err != nil {
+				return "", cleanup, err
+			}
+		}
+		if opts.ClientKey != "" {
+			key, err := secrets.Resolve(opts.ClientKey)
+			if err != nil {
+				return "", cleanup, err
+			}
+			if cfg.TLS.KeyFile, err = writeFile("_client.key", []byte(key)); err != nil {
+				return "", cleanup, err
+			}
+		}
+	}
+
+	if cfg.HistogramOutput, err = filepath.Abs(histogramFile(prefix)); err != nil {
+		return "", cleanup, err
+	}
+	if cfg.CPUOutput, err = filepath.Abs(cpuFile(prefix)); err != nil {
+		return "", cleanup, err
+	}
+	if cfg.Transport, err = effectiveTransport(task.ID); err != nil {
+		return "", cleanup, err
+	}
+	// 上传结果的令牌在运行的硬性期限内有效
+	cfg.ResultToken = utils.SignRunToken(config.RunTokenKey, utils.RunTokenClaims{
+		TestID:    task.ID,
+		RunPrefix: prefix,
+		ExpiresAt: time.Now().Add(runDeadline(task.EndTime.Sub(task.StartTime))),
+	})
+	if cfg.Steps, err = runnerSteps(task.ID, secrets); err != nil {
+		return "", cleanup, err
+	}
+	if hasChecks(cfg.Steps) {
+		// 断言统计文件在运行后由 Go 端读取，不随 cleanup 删除
+		if cfg.ChecksOutput, err = filepath.Abs(checksFile(prefix)); err != nil {
+			return "", cleanup, err
+		}
+	}
+	if hasWebSocketSteps(cfg.Steps) {
+		if cfg.WSOutput, err = filepath.Abs(wsFile(prefix)); err != nil {
+			return "", cleanup, err
+		}
+	}
+	if hasGRPCSteps(cfg.Steps) {
+		if cfg.GRPC, err = runnerGRPC(task.ID, prefix); err != nil {
+			return "", cleanup, err
+		}
+	}
+	td, err := models.GetTestDataset(task.ID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return "", cleanup, err
+	default:
+		ds, err := models.GetDatasetByID(td.DatasetID)
+		if err != nil {
+			return "", cleanup, err
+		}
+		file, err := filepath.Abs(ds.FilePath)
+		if err != nil {
+			return "", cleanup, err
+		}
+		cfg.Dataset = &RunnerDataset{File: file, Mode: td.Mode}
+	}
+
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return "", cleanup, err
+	}
+	path, err = writeFile("_config.json", data)
+	return path, cleanup, err
+}
+
+// runnerSteps 读取任务的场景步骤并解析其中的 secret 引用
+func runnerSteps(testID int, secrets *secretResolver) ([]RunnerStep, error) {
+	steps, err := models.GetScenarioSteps(testID)
+	if err != nil {
+		return nil, err
+	}
+	var out []RunnerStep
+	for _, s := range steps {
+		step := RunnerStep{
+			Type:       s.Type,
+			Name:       s.Name,
+			Method:     s.Method,
+			ThinkTime:  s.ThinkTime,
+			Timeout:    s.Timeout,
+			Checks:     s.Checks,
+			Extractors: s.Extractors,
+		}
+		if step.Path, err = secrets.Resolve(s.Path); err != nil {
+			return nil, err
+		}
+		if step.Headers, err = secrets.ResolveMap(s.Headers); err != nil {
+			return nil, err
+		}
+		if step.Body, err = secrets.Resolve(s.Body); err != nil {
+			return nil, err
+		}
+		out = append(out, step)
+	}
+	return out, nil
+}
+
+// runnerGRPC 读取任务的 gRPC 描述来源，未配置时使用服务端反射
+func runnerGRPC(testID int, prefix string) (*RunnerGRPC, error) {
+	var (
+		cfg RunnerGRPC
+		err error
+	)
+	if cfg.StatusOutput, err = filepath.Abs(grpcStatusFile(prefix)); err != nil {
+		return nil, err
+	}
+	opts, err := models.GetGRPCOptions(testID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && opts.DescriptorID == 0) {
+		return &cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	desc, err := models.GetProtoDescriptorByID(opts.DescriptorID)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.DescriptorFile, err = filepath.Abs(desc.FilePath); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
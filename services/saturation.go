@@ -0,0 +1,160 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"loadtest_project/models"
+	"loadtest_project/utils"
+)
+
+// 负载生成器资源使用率达到该值时视为饱和，此时测得的延迟可能包含生成器自身的排队
+const (
+	saturationCPU = 90.0
+	saturationMem = 90.0
+)
+
+// saturationInterval 运行期间检查负载生成器资源的间隔
+const saturationInterval = 5 * time.Second
+
+// warningsFile 本次运行的负载生成器告警文件
+func warningsFile(prefix string) string {
+	return filepath.Join(resultsDir, prefix+"_warnings.json")
+}
+
+// cpuFile Runner 运行期间定期写入各 Locust 进程 CPU 使用率的文件
+func cpuFile(prefix string) string {
+	return filepath.Join(resultsDir, prefix+"_cpu.json")
+}
+
+// processCPU 一个 Locust 进程的 CPU 使用率，由 Locust 按进程采集（单核占满即为 100）
+type processCPU struct {
+	Name string  `json:"name"` // local、master 或 worker <id>
+	CPU  float64 `json:"cpu"`
+}
+
+// readProcessCPU 读取 Runner 最近一次写入的各进程 CPU 使用率，文件尚未写入时返回 nil
+func readProcessCPU(prefix string) []processCPU {
+	data, err := os.ReadFile(cpuFile(prefix))
+	if err != nil {
+		return nil
+	}
+	var list []processCPU
+	if json.Unmarshal(data, &list) != nil {
+		return nil
+	}
+	return list
+}
+
+// memUnsupportedOnce 当前平台无法采集内存使用率时只提示一次
+var memUnsupportedOnce sync.Once
+
+// watchSaturation 运行期间定期检查各 Locust 进程的 CPU，以及本机与参与运行的 agent 的内存是否饱和，
+// 返回的 stop 结束检查，并在有告警时写出告警文件
+func watchSaturation(prefix string, agents []models.Agent) (stop func()) {
+	var (
+		mu       sync.Mutex
+		warnings []string
+		seen     = make(map[string]bool)
+		done     = make(chan struct{})
+		finished = make(chan struct{})
+	)
+	warn := func(generator, resource string, percent float64) {
+		key := generator + "/" + resource
+		mu.Lock()
+		defer mu.Unlock()
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		msg := fmt.Sprintf("%s %s 使用率达到 %.0f%%，压测结果可能失真", generator, resource, percent)
+		fmt.Printf("运行 %s: %s\n", prefix, msg)
+		warnings = append(warnings, msg)
+	}
+	check := func() {
+		// Locust 每个进程只用一个核，整机 CPU 使用率无法反映单个进程已占满，按进程判断
+		for _, p := range readProcessCPU(prefix) {
+			if p.CPU >= saturationCPU {
+				warn("Locust "+p.Name, "CPU", p.CPU)
+			}
+		}
+		mem, err := utils.MemoryPercent()
+		if err != nil {
+			memUnsupportedOnce.Do(func() {
+				fmt.Printf("警告: 无法采集本机内存使用率（%v），负载生成器内存饱和检查已关闭\n", err)
+			})
+		} else if mem >= saturationMem {
+			warn("本机", "内存", mem)
+		}
+		if len(agents) == 0 {
+			return
+		}
+		// agent 资源数据来自其心跳
+		online, err := models.ListAgents(time.Now().Add(-AgentOnlineWindow))
+		if err != nil {
+			return
+		}
+		for _, a := range online {
+			for _, participant := range agents {
+				if a.ID != participant.ID {
+					continue
+				}
+				if a.MemPercent >= saturationMem {
+					warn("agent "+a.Name, "内存", a.MemPercent)
+				}
+			}
+		}
+	}
+
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(saturationInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				check()
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-finished
+		os.Remove(cpuFile(prefix))
+		mu.Lock()
+		defer mu.Unlock()
+		if len(warnings) == 0 {
+			return
+		}
+		data, err := json.Marshal(warnings)
+		if err == nil {
+			err = os.WriteFile(warningsFile(prefix), data, 0644)
+		}
+		if err != nil {
+			fmt.Println("写入负载生成器告警失败:", err)
+		}
+	}
+}
+
+// readGeneratorWarnings 读取运行期间的负载生成器告警，文件不存在时返回 nil
+func readGeneratorWarnings(prefix string) ([]string, error) {
+	data, err := os.ReadFile(warningsFile(prefix))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var warnings []string
+	if err := json.Unmarshal(data, &warnings); err != nil {
+		return nil, fmt.Errorf("解析负载生成器告警失败: %w", err)
+	}
+	return warnings, nil
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"loadtest_project/config"
+	"loadtest_project/models"
+	"loadtest_project/utils"
+)
+
+// secretRefPattern 匹配配置中的 secret 引用，如 {{secret:api_token}}
+var secretRefPattern = regexp.MustCompile(`\{\{\s*secret:([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// SecretNamePattern 合法的 secret 名称
+var SecretNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,128}$`)
+
+// secretRefOnlyPattern 整个值只是一个 secret 引用
+var secretRefOnlyPattern = regexp.MustCompile(`^\s*\{\{\s*secret:[A-Za-z0-9_.-]+\s*\}\}\s*$`)
+
+// IsSecretRef 值是否只是一个 secret 引用，如 {{secret:api_token}}
+func IsSecretRef(s string) bool {
+	return secretRefOnlyPattern.MatchString(s)
+}
+
+// HasSecretRef 值中是否包含 secret 引用，如 "Bearer {{secret:api_token}}"
+func HasSecretRef(s string) bool {
+	return secretRefPattern.MatchString(s)
+}
+
+// EncryptSecret 使用主密钥加密 secret 明文
+func EncryptSecret(value string) (string, error) {
+	return utils.EncryptAESGCM(config.MasterKey, []byte(value))
+}
+
+// secretResolver 按任务所属项目与所有者解析 secret 引用，并记住已解析的明文用于日志脱敏
+type secretResolver struct {
+	userID    int
+	projectID int
+	cache     map[string]string
+	// refs 非 nil 时只校验 secret 存在并保留引用原文，记录用到的名称（见 references）
+	refs map[string]bool
+}
+
+func newSecretResolver(userID, projectID int) *secretResolver {
+	return &secretResolver{userID: userID, projectID: projectID, cache: map[string]string{}}
+}
+
+// references 返回与 r 共享缓存、但保留引用原文的解析器，用于下发给 worker agent 的配置：
+// 配置中只有 {{secret:名称}}，明文由 agent 凭据单独拉取
+func (r *secretResolver) references() *secretResolver {
+	return &secretResolver{userID: r.userID, projectID: r.projectID, cache: r.cache, refs: map[string]bool{}}
+}
+
+// referencedValues 返回 references 解析器用到的 secret 明文
+func (r *secretResolver) referencedValues() map[string]string {
+	values := make(map[string]string, len(r.refs))
+	for name := range r.refs {
+		values[name] = r.cache[name]
+	}
+	return values
+}
+
+// Resolve 将字符串中的所有 secret 引用替换为明文
+func (r *secretResolver) Resolve(s string) (string, error) {
+	var firstErr error
+	out := secretRefPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		name := secretRefPattern.FindStringSubmatch(ref)[1]
+		value, err := r.lookup(name)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return ref
+		}
+		if r.refs != nil {
+			r.refs[name] = true
+			return ref
+		}
+		return value
+	})
+	return out, firstErr
+}
+
+// SubstituteSecrets 将 data 中的 secret 引用替换为 values 中的明文，供 worker agent 还原配置；
+// jsonString 为 true 时 data 为 JSON，明文按 JSON 字符串转义后写入
+func SubstituteSecrets(data []byte, values map[string]string, jsonString bool) ([]byte, error) {
+	var missing string
+	out := secretRefPattern.ReplaceAllFunc(data, func(ref []byte) []byte {
+		name := string(secretRefPattern.FindSubmatch(ref)[1])
+		value, ok := values[name]
+		if !ok {
+			missing = name
+			return ref
+		}
+		if jsonString {
+			quoted, _ := json.Marshal(value)
+			return quoted[1 : len(quoted)-1]
+		}
+		return []byte(value)
+	})
+	if missing != "" {
+		return nil, fmt.Errorf("未收到 secret %q", missing)
+	}
+	return out, nil
+}
+
+// ResolveMap 解析 map 中每个值的 secret 引用
+func (r *secretResolver) ResolveMap(m map[string]string) (map[string]string, error) {
+	if m == nil {
+		return nil, nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		resolved, err := r.Resolve(v)
+		if err != nil {
+			return nil, err
+		}
+		out[k] = resolved
+	}
+	return out, nil
+}
+
+func (r *secretResolver) lookup(name string) (string, error) {
+	if v, ok := r.cache[name]; ok {
+		return v, nil
+	}
+	// 先查项目共享的 secret，再查任务所有者自己的
+	secret, err := models.GetProjectSecret(r.projectID, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		secret, err = models.GetSecret(r.userID, name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("secret %q 不存在: %w", name, err)
+	}
+	plain, err := utils.DecryptAESGCM(config.MasterKey, secret.Ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("secret %q 解密失败: %w", name, err)
+	}
+	r.cache[name] = string(plain)
+	return string(plain), nil
+}
+
+// Redact 将输出中出现的 secret 明文替换为占位符
+func (r *secretResolver) Redact(data []byte) []byte {
+	// 先替换较长的值，避免短值是长值子串时留下残片
+	values := make([]string, 0, len(r.cache))
+	for _, v := range r.cache {
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	for _, v := range values {
+		data = bytes.ReplaceAll(data, []byte(v), []byte("******"))
+	}
+	return data
+}
+
+// credentialHeaders 视为凭据的请求头（小写）
+var credentialHeaders = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+	"x-api-key":           true,
+	"x-auth-token":        true,
+	"x-access-token":      true,
+}
+
+// IsCredentialHeader 请求头是否携带凭据：常见认证头，或名称中含 token / secret / password / api-key / session
+func IsCredentialHeader(name string) bool {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if credentialHeaders[name] {
+		return true
+	}
+	for _, part := range []string{"token", "secret", "password", "api-key", "apikey", "session"} {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckTargetCredentials 凭据不以明文保存：password、token、client_key 只能是 secret 引用，
+// 凭据类请求头与 Cookie 的值必须通过 secret 引用提供（如 "Bearer {{secret:api_token}}"）
+func CheckTargetCredentials(o *models.TargetOptions) error {
+	for _, f := range []struct{ name, value string }{
+		{"password", o.Password}, {"token", o.Token}, {"client_key", o.ClientKey},
+	} {
+		if f.value != "" && !IsSecretRef(f.value) {
+			return fmt.Errorf("%s 只能是 secret 引用，如 {{secret:名称}}，请先通过 /api/secrets 保存", f.name)
+		}
+	}
+	for name, v := range o.Headers {
+		if IsCredentialHeader(name) && !HasSecretRef(v) {
+			return fmt.Errorf("请求头 %s 含凭据，值必须通过 {{secret:名称}} 引用", name)
+		}
+	}
+	for name, v := range o.Cookies {
+		if !HasSecretRef(v) {
+			return fmt.Errorf("Cookie %s 的值必须通过 {{secret:名称}} 引用", name)
+		}
+	}
+	return nil
+}
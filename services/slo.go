@@ -0,0 +1,71 @@
+package services
+
+import (
+	"fmt"
+
+	"loadtest_project/models"
+)
+
+// ThresholdMetrics 可用于 SLO 阈值的指标及其取值方式
+var ThresholdMetrics = map[string]func(models.TestResult) float64{
+	"avg_response_time":  func(r models.TestResult) float64 { return r.AvgResponseTime },
+	"max_response_time":  func(r models.TestResult) float64 { return r.MaxResponseTime },
+	"p50_response_time":  func(r models.TestResult) float64 { return r.P50ResponseTime },
+	"p95_response_time":  func(r models.TestResult) float64 { return r.P95ResponseTime },
+	"p99_response_time":  func(r models.TestResult) float64 { return r.P99ResponseTime },
+	"error_rate":         func(r models.TestResult) float64 { return r.ErrorRate },
+	"availability":       func(r models.TestResult) float64 { return r.Availability },
+	"rps":                func(r models.TestResult) float64 { return r.RPS },
+	"tps":                func(r models.TestResult) float64 { return r.TPS },
+	"check_pass_rate":    func(r models.TestResult) float64 { return r.CheckPassRate },
+	"ws_connect_time":    func(r models.TestResult) float64 { return r.WSConnectTime },
+	"ws_round_trip_time": func(r models.TestResult) float64 { return r.WSRoundTripTime },
+	"ws_send_rate":       func(r models.TestResult) float64 { return r.WSSendRate },
+	"ws_receive_rate":    func(r models.TestResult) float64 { return r.WSReceiveRate },
+	"ws_error_rate":      func(r models.TestResult) float64 { return r.WSErrorRate },
+}
+
+// ThresholdOperators 支持的比较运算符
+var ThresholdOperators = map[string]func(actual, value float64) bool{
+	"<":  func(a, v float64) bool { return a < v },
+	"<=": func(a, v float64) bool { return a <= v },
+	">":  func(a, v float64) bool { return a > v },
+	">=": func(a, v float64) bool { return a >= v },
+}
+
+// EvaluateThresholds 逐个评估阈值，全部满足才算通过
+func EvaluateThresholds(thresholds []models.Threshold, result models.TestResult) models.Verdict {
+	verdict := models.Verdict{TestID: result.TestID, ResultID: result.ID, Passed: true}
+	for _, t := range thresholds {
+		outcome := models.ThresholdOutcome{Metric: t.Metric, Operator: t.Operator, Value: t.Value}
+		metric, okMetric := ThresholdMetrics[t.Metric]
+		compare, okOp := ThresholdOperators[t.Operator]
+		if okMetric && okOp {
+			outcome.Actual = metric(result)
+			outcome.Passed = compare(outcome.Actual, t.Value)
+		}
+		if !outcome.Passed {
+			verdict.Passed = false
+		}
+		verdict.Details = append(verdict.Details, outcome)
+	}
+	return verdict
+}
+
+// RecordVerdict 在结果写入后评估任务的 SLO 阈值并保存结论；未配置阈值时不生成结论
+func RecordVerdict(result models.TestResult) {
+	thresholds, err := models.GetThresholdsByTestID(result.TestID)
+	if err != nil {
+		fmt.Println("读取 SLO 阈值失败:", err)
+		return
+	}
+	if len(thresholds) == 0 {
+		return
+	}
+	verdict := EvaluateThresholds(thresholds, result)
+	if err := models.CreateVerdict(&verdict); err != nil {
+		fmt.Println("写入 SLO 结论失败:", err)
+		return
+	}
+	fmt.Printf("任务 %d SLO 结论: passed=%v\n", result.TestID, verdict.Passed)
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"testing"
+
+	"loadtest_project/models"
+)
+
+func TestEvaluateThresholdsOperators(t *testing.T) {
+	result := models.TestResult{P95ResponseTime: 200}
+	tests := []struct {
+		operator string
+		value    float64
+		want     bool
+	}{
+		{"<", 201, true},
+		{"<", 200, false},
+		{"<=", 200, true},
+		{"<=", 199, false},
+		{">", 199, true},
+		{">", 200, false},
+		{">=", 200, true},
+		{">=", 201, false},
+	}
+	for _, tt := range tests {
+		th := models.Threshold{Metric: "p95_response_time", Operator: tt.operator, Value: tt.value}
+		v := EvaluateThresholds([]models.Threshold{th}, result)
+		if v.Passed != tt.want || len(v.Details) != 1 || v.Details[0].Passed != tt.want || v.Details[0].Actual != 200 {
+			t.Errorf("200 %s %v: verdict = %+v, want passed=%v", tt.operator, tt.value, v, tt.want)
+		}
+	}
+}
+
+func TestEvaluateThresholdsUnknown(t *testing.T) {
+	// 即使数值上“满足”，无法评估的阈值也按未通过处理，不会悄悄放行
+	tests := []struct {
+		name string
+		th   models.Threshold
+	}{
+		{"未知指标", models.Threshold{Metric: "p42_response_time", Operator: "<", Value: 1e9}},
+		{"未知运算符", models.Threshold{Metric: "error_rate", Operator: "!=", Value: 1}},
+		{"运算符为空", models.Threshold{Metric: "error_rate", Value: 1}},
+	}
+	for _, tt := range tests {
+		v := EvaluateThresholds([]models.Threshold{tt.th}, models.TestResult{})
+		if v.Passed || len(v.Details) != 1 || v.Details[0].Passed {
+			t.Errorf("%s: 应判为未通过，got %+v", tt.name, v)
+		}
+	}
+}
+
+func TestEvaluateThresholdsVerdict(t *testing.T) {
+	result := models.TestResult{ID: 9, TestID: 3, P95ResponseTime: 300, ErrorRate: 0.002, RPS: 120, WSErrorRate: 0.1}
+	p95 := models.Threshold{Metric: "p95_response_time", Operator: "<", Value: 500}
+	errRate := models.Threshold{Metric: "error_rate", Operator: "<=", Value: 0.01}
+	rps := models.Threshold{Metric: "rps", Operator: ">=", Value: 200}
+	ws := models.Threshold{Metric: "ws_error_rate", Operator: "<", Value: 0.05}
+	tests := []struct {
+		name       string
+		thresholds []models.Threshold
+		want       bool
+		wantFailed []string // 未通过的指标
+	}{
+		{"没有阈值时通过", nil, true, nil},
+		{"全部满足", []models.Threshold{p95, errRate}, true, nil},
+		{"任一不满足即不通过", []models.Threshold{p95, rps, errRate}, false, []string{"rps"}},
+		{"多个不满足时逐个记录", []models.Threshold{rps, ws, p95}, false, []string{"rps", "ws_error_rate"}},
+	}
+	for _, tt := range tests {
+		v := EvaluateThresholds(tt.thresholds, result)
+		if v.TestID != 3 || v.ResultID != 9 {
+			t.Errorf("%s: 结论应关联任务 3 与结果 9，got %d/%d", tt.name, v.TestID, v.ResultID)
+		}
+		if v.Passed != tt.want || len(v.Details) != len(tt.thresholds) {
+			t.Errorf("%s: passed = %v（%d 条明细），want %v（%d 条）", tt.name, v.Passed, len(v.Details), tt.want, len(tt.thresholds))
+			continue
+		}
+		var failed []string
+		for i, d := range v.Details {
+			if d.Metric != tt.thresholds[i].Metric {
+				t.Errorf("%s: 明细应按阈值顺序排列，第 %d 条为 %s", tt.name, i, d.Metric)
+			}
+			if !d.Passed {
+				failed = append(failed, d.Metric)
+			}
+		}
+		if len(failed) != len(tt.wantFailed) {
+			t.Errorf("%s: 未通过的指标 %v，want %v", tt.name, failed, tt.wantFailed)
+			continue
+		}
+		for i := range failed {
+			if failed[i] != tt.wantFailed[i] {
+				t.Errorf("%s: 未通过的指标 %v，want %v", tt.name, failed, tt.wantFailed)
+				break
+			}
+		}
+	}
+}
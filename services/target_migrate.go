@@ -0,0 +1,88 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"loadtest_project/config"
+	"loadtest_project/models"
+)
+
+// secretNameInvalid secret 名称中不允许的字符
+var secretNameInvalid = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// MigrateTargetCredentials 早期版本把目标凭据以明文保存在 target_options 中：
+// 逐个转存为任务提交者的加密 secret（名为 target_<任务ID>_<字段>），原字段改为 secret 引用。
+// 未配置主密钥且存在明文凭据时返回错误，凭据保持原样
+func MigrateTargetCredentials() error {
+	owners, err := models.ListTargetOwners()
+	if err != nil {
+		return err
+	}
+	migrated := 0
+	for _, owner := range owners {
+		opts, err := models.GetTargetOptions(owner.TestID)
+		if err != nil {
+			return err
+		}
+		if CheckTargetCredentials(&opts) == nil {
+			continue
+		}
+		if config.MasterKey == nil {
+			return errors.New("target_options 中存在明文凭据，配置 " + config.MasterKeyEnv + " 后重启即可转为加密 secret")
+		}
+		if err := moveTargetCredentials(owner.UserID, &opts); err != nil {
+			return fmt.Errorf("任务 %d: %w", owner.TestID, err)
+		}
+		migrated++
+	}
+	if migrated > 0 {
+		fmt.Printf("已将 %d 个任务的明文目标凭据转存为加密 secret\n", migrated)
+	}
+	return nil
+}
+
+func moveTargetCredentials(userID int, o *models.TargetOptions) error {
+	var firstErr error
+	move := func(label, value string) string {
+		if firstErr != nil {
+			return value
+		}
+		name := secretNameInvalid.ReplaceAllString(fmt.Sprintf("target_%d_%s", o.TestID, label), "_")
+		ciphertext, err := EncryptSecret(value)
+		if err == nil {
+			err = models.SaveSecret(&models.Secret{UserID: userID, Name: name, Ciphertext: ciphertext})
+		}
+		if err != nil {
+			firstErr = err
+			return value
+		}
+		return "{{secret:" + name + "}}"
+	}
+
+	if o.Password != "" && !IsSecretRef(o.Password) {
+		o.Password = move("password", o.Password)
+	}
+	if o.Token != "" && !IsSecretRef(o.Token) {
+		o.Token = move("token", o.Token)
+	}
+	if o.ClientKey != "" && !IsSecretRef(o.ClientKey) {
+		o.ClientKey = move("client_key", o.ClientKey)
+	}
+	for name, v := range o.Headers {
+		if IsCredentialHeader(name) && !HasSecretRef(v) {
+			o.Headers[name] = move("header_"+strings.ToLower(name), v)
+		}
+	}
+	for name, v := range o.Cookies {
+		if !HasSecretRef(v) {
+			o.Cookies[name] = move("cookie_"+name, v)
+		}
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+	return models.UpdateTargetCredentials(o)
+}
@@ -0,0 +1,24 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+
+	"loadtest_project/models"
+)
+
+// effectiveTransport 返回任务运行时生效的传输层选项，未配置时为默认值
+func effectiveTransport(testID int) (models.TransportOptions, error) {
+	opts, err := models.GetTransportOptions(testID)
+	if errors.Is(err, sql.ErrNoRows) {
+		opts, err = models.TransportOptions{}, nil
+	}
+	if err != nil {
+		return opts, err
+	}
+	opts.TestID = 0
+	if opts.HTTPVersion == "" {
+		opts.HTTPVersion = models.HTTPVersion11
+	}
+	return opts, nil
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"loadtest_project/models"
+)
+
+// Runner 汇总 WebSocket 事件时使用的名称（见 locustfile.py 的 WSStats）。
+// WS 事件不进入 Locust 的请求统计，避免近乎 0 毫秒的收发事件拉低 HTTP 的平均值、分位数与 RPS
+const (
+	wsConnect   = "connect"   // 建立连接耗时
+	wsSend      = "send"      // 每条发送的消息
+	wsReceive   = "receive"   // 每条收到的消息
+	wsRoundTrip = "roundtrip" // 发送到收到期望消息的往返耗时
+)
+
+// wsMetrics WebSocket 汇总指标
+type wsMetrics struct {
+	connect     float64 // 平均连接耗时（毫秒）
+	roundTrip   float64 // 平均消息往返耗时（毫秒）
+	sendRate    float64 // 每秒发送消息数
+	receiveRate float64 // 每秒接收消息数
+	errorRate   float64 // 各类 WS 事件中失败的比例
+}
+
+// wsEntry Runner 写出的单个 WS 事件名的统计
+type wsEntry struct {
+	Name      string  `json:"name"`
+	Requests  int     `json:"requests"`
+	Failures  int     `json:"failures"`
+	TotalTime float64 `json:"total_time"` // 成功事件的耗时之和（毫秒）
+}
+
+// wsFile 本次运行的 WebSocket 统计文件
+func wsFile(prefix string) string {
+	return filepath.Join(resultsDir, prefix+"_ws.json")
+}
+
+func hasWebSocketSteps(steps []RunnerStep) bool {
+	for _, s := range steps {
+		if s.Type == models.StepWSConnect {
+			return true
+		}
+	}
+	return false
+}
+
+// readWebSocketMetrics 读取 Runner 写出的 WS 统计，按运行时长换算消息速率；没有 WS 步骤时全为 0
+func readWebSocketMetrics(prefix string, runTime time.Duration) (wsMetrics, error) {
+	var m wsMetrics
+	data, err := os.ReadFile(wsFile(prefix))
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return m, err
+	}
+	var entries []wsEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return m, fmt.Errorf("解析 WebSocket 统计失败: %w", err)
+	}
+	return websocketMetrics(entries, runTime), nil
+}
+
+func websocketMetrics(entries []wsEntry, runTime time.Duration) wsMetrics {
+	var (
+		m               wsMetrics
+		total, failures int
+	)
+	avg := func(e wsEntry) float64 {
+		if ok := e.Requests - e.Failures; ok > 0 {
+			return e.TotalTime / float64(ok)
+		}
+		return 0
+	}
+	rate := func(e wsEntry) float64 {
+		if runTime <= 0 {
+			return 0
+		}
+		return float64(e.Requests-e.Failures) / runTime.Seconds()
+	}
+	for _, e := range entries {
+		total += e.Requests
+		failures += e.Failures
+		switch e.Name {
+		case wsConnect:
+			m.connect = avg(e)
+		case wsRoundTrip:
+			m.roundTrip = avg(e)
+		case wsSend:
+			m.sendRate = rate(e)
+		case wsReceive:
+			m.receiveRate = rate(e)
+		}
+	}
+	if total > 0 {
+		m.errorRate = float64(failures) / float64(total)
+	}
+	return m
+}
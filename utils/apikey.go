@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// APIKeyPrefix API 令牌的固定前缀，用于与 JWT 区分
+const APIKeyPrefix = "ltk_"
+
+// GenerateAPIKey 生成新的 API 令牌，返回明文（只展示一次）与其哈希
+func GenerateAPIKey() (plain, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	plain = APIKeyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	return plain, HashToken(plain), nil
+}
+
+// AgentCredentialPrefix agent 凭据的固定前缀
+const AgentCredentialPrefix = "agc_"
+
+// GenerateAgentCredential 生成 agent 注册时签发的凭据，返回明文（只下发给该 agent）与其哈希
+func GenerateAgentCredential() (plain, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	plain = AgentCredentialPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	return plain, HashToken(plain), nil
+}
+
+// HashToken 服务端保存的随机令牌（API 令牌、refresh token）的哈希。
+// 令牌为 256 位随机值，直接用 SHA-256 保存即可抵御离线猜测
+func HashToken(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsAPIKey 是否为 API 令牌（而非 JWT）
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
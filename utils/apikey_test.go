@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	plain, hash, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(plain, APIKeyPrefix) || !IsAPIKey(plain) {
+		t.Errorf("API 令牌应以 %s 开头，got %q", APIKeyPrefix, plain)
+	}
+	if hash != HashToken(plain) || len(hash) != 64 || strings.Contains(hash, plain) {
+		t.Errorf("应只保存明文的 SHA-256 十六进制哈希，got %q", hash)
+	}
+	other, otherHash, err := GenerateAPIKey()
+	if err != nil || other == plain || otherHash == hash {
+		t.Error("每次生成的令牌应不同")
+	}
+}
+
+func TestIsAPIKey(t *testing.T) {
+	tests := []struct {
+		token string
+		want  bool
+	}{
+		{"ltk_abc", true},
+		{"eyJhbGciOiJIUzI1NiJ9.e30.sig", false}, // 登录得到的 JWT
+		{"agc_abc", false},                      // agent 凭据
+		{"LTK_abc", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsAPIKey(tt.token); got != tt.want {
+			t.Errorf("IsAPIKey(%q) = %v, want %v", tt.token, got, tt.want)
+		}
+	}
+}
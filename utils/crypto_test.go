@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+)
+
+func TestAESGCMRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		key       []byte
+		plaintext []byte
+	}{
+		{"AES-128", bytes.Repeat([]byte{1}, 16), []byte("secret")},
+		{"AES-256", bytes.Repeat([]byte{2}, 32), []byte("Bearer eyJhbGciOi...")},
+		{"空明文", bytes.Repeat([]byte{3}, 32), []byte{}},
+	}
+	for _, tt := range tests {
+		encoded, err := EncryptAESGCM(tt.key, tt.plaintext)
+		if err != nil {
+			t.Errorf("%s: 加密失败 %v", tt.name, err)
+			continue
+		}
+		got, err := DecryptAESGCM(tt.key, encoded)
+		if err != nil || !bytes.Equal(got, tt.plaintext) {
+			t.Errorf("%s: 解密得到 %q, %v, want %q", tt.name, got, err, tt.plaintext)
+		}
+		// 每次加密使用随机 nonce，密文不重复
+		if again, _ := EncryptAESGCM(tt.key, tt.plaintext); again == encoded {
+			t.Errorf("%s: 两次加密结果相同", tt.name)
+		}
+	}
+}
+
+func TestDecryptAESGCMErrors(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	encoded, err := EncryptAESGCM(key, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := base64.StdEncoding.DecodeString(encoded)
+	tampered := append([]byte{}, raw...)
+	tampered[len(tampered)-1] ^= 0xff
+
+	tests := []struct {
+		name    string
+		key     []byte
+		encoded string
+	}{
+		{"未配置密钥", nil, encoded},
+		{"密钥长度无效", []byte("short"), encoded},
+		{"密钥不同", bytes.Repeat([]byte{8}, 32), encoded},
+		{"非 base64", key, "not base64!"},
+		{"密文长度不足", key, base64.StdEncoding.EncodeToString([]byte("abc"))},
+		{"密文被篡改", key, base64.StdEncoding.EncodeToString(tampered)},
+	}
+	for _, tt := range tests {
+		if _, err := DecryptAESGCM(tt.key, tt.encoded); err == nil {
+			t.Errorf("%s: 应当解密失败", tt.name)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"math"
+	"math/bits"
+	"sort"
+)
+
+// histogramSubBuckets 每个数量级内的子桶数，决定相对精度（约 1/64）。
+// 小于该值的延迟按毫秒精确计数
+const histogramSubBuckets = 128
+
+// Histogram HDR 风格的对数-线性延迟直方图（毫秒）。
+// 分桶方式固定，任意两个直方图可按桶相加合并，合并后仍能准确计算百分位
+type Histogram struct {
+	Counts map[int]int64 `json:"counts"` // 桶下标 -> 次数
+	Total  int64         `json:"total"`
+	Sum    float64       `json:"sum"`
+	Min    float64       `json:"min"`
+	Max    float64       `json:"max"`
+}
+
+// NewHistogram 创建空直方图
+func NewHistogram() *Histogram {
+	return &Histogram{Counts: make(map[int]int64)}
+}
+
+// histogramBucket 返回毫秒值所在的桶
+func histogramBucket(ms int64) int {
+	if ms < histogramSubBuckets {
+		return int(ms)
+	}
+	shift := bits.Len64(uint64(ms)) - bits.Len64(histogramSubBuckets-1)
+	top := ms >> shift // 落在 [sub/2, sub) 区间
+	return histogramSubBuckets + (shift-1)*histogramSubBuckets/2 + int(top-histogramSubBuckets/2)
+}
+
+// histogramBucketRange 返回桶覆盖的毫秒区间 [low, high)
+func histogramBucketRange(idx int) (low, high int64) {
+	if idx < histogramSubBuckets {
+		return int64(idx), int64(idx) + 1
+	}
+	half := histogramSubBuckets / 2
+	shift := (idx-histogramSubBuckets)/half + 1
+	top := int64((idx-histogramSubBuckets)%half + half)
+	return top << shift, (top + 1) << shift
+}
+
+// RecordN 记录 n 次耗时为 ms 的请求
+func (h *Histogram) RecordN(ms float64, n int64) {
+	if n <= 0 {
+		return
+	}
+	if ms < 0 {
+		ms = 0
+	}
+	if h.Total == 0 || ms < h.Min {
+		h.Min = ms
+	}
+	if ms > h.Max {
+		h.Max = ms
+	}
+	h.Counts[histogramBucket(int64(math.Round(ms)))] += n
+	h.Total += n
+	h.Sum += ms * float64(n)
+}
+
+// Merge 把另一个直方图累加进来
+func (h *Histogram) Merge(o *Histogram) {
+	if o == nil || o.Total == 0 {
+		return
+	}
+	if h.Total == 0 || o.Min < h.Min {
+		h.Min = o.Min
+	}
+	if o.Max > h.Max {
+		h.Max = o.Max
+	}
+	for idx, n := range o.Counts {
+		h.Counts[idx] += n
+	}
+	h.Total += o.Total
+	h.Sum += o.Sum
+}
+
+// Mean 平均耗时
+func (h *Histogram) Mean() float64 {
+	if h.Total == 0 {
+		return 0
+	}
+	return h.Sum / float64(h.Total)
+}
+
+// Percentile 返回 q（0~100）百分位的耗时，取所在桶的中点并限制在 [Min, Max] 内
+func (h *Histogram) Percentile(q float64) float64 {
+	if h.Total == 0 {
+		return 0
+	}
+	idxs := make([]int, 0, len(h.Counts))
+	for idx := range h.Counts {
+		idxs = append(idxs, idx)
+	}
+	sort.Ints(idxs)
+
+	rank := int64(math.Ceil(q / 100 * float64(h.Total)))
+	if rank < 1 {
+		rank = 1
+	}
+	var seen int64
+	for _, idx := range idxs {
+		seen += h.Counts[idx]
+		if seen >= rank {
+			low, high := histogramBucketRange(idx)
+			v := float64(low+high-1) / 2
+			return math.Min(math.Max(v, h.Min), h.Max)
+		}
+	}
+	return h.Max
+}
@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHistogramBucket(t *testing.T) {
+	tests := []int64{0, 1, 63, 127, 128, 129, 255, 256, 1000, 4095, 4096, 65535, 1 << 20, 123456789}
+	for _, ms := range tests {
+		idx := histogramBucket(ms)
+		low, high := histogramBucketRange(idx)
+		if ms < low || ms >= high {
+			t.Errorf("histogramBucket(%d) = %d, 区间 [%d, %d) 不包含该值", ms, idx, low, high)
+		}
+		// 小于子桶数时按毫秒精确计数，其余桶宽不超过下界的 1/64
+		if ms < histogramSubBuckets && high-low != 1 {
+			t.Errorf("histogramBucket(%d) 桶宽 %d, 应为 1", ms, high-low)
+		}
+		if ms >= histogramSubBuckets && float64(high-low)/float64(low) > 1.0/64 {
+			t.Errorf("histogramBucket(%d) 桶宽 %d 超过下界 %d 的 1/64", ms, high-low, low)
+		}
+	}
+	// 桶下标随耗时单调不减
+	prev := -1
+	for ms := int64(0); ms < 100000; ms += 7 {
+		idx := histogramBucket(ms)
+		if idx < prev {
+			t.Fatalf("histogramBucket(%d) = %d 小于前一个值 %d", ms, idx, prev)
+		}
+		prev = idx
+	}
+}
+
+func TestHistogramPercentile(t *testing.T) {
+	uniform := NewHistogram()
+	for ms := 1; ms <= 1000; ms++ {
+		uniform.RecordN(float64(ms), 1)
+	}
+	skewed := NewHistogram()
+	skewed.RecordN(10, 99)
+	skewed.RecordN(5000, 1)
+
+	tests := []struct {
+		name string
+		h    *Histogram
+		q    float64
+		want float64
+	}{
+		{"空直方图", NewHistogram(), 95, 0},
+		{"p50", uniform, 50, 500},
+		{"p95", uniform, 95, 950},
+		{"p99", uniform, 99, 990},
+		{"p100 取最大值", uniform, 100, 1000},
+		{"p0 取最小值", uniform, 0, 1},
+		{"长尾 p99", skewed, 99, 10},
+		{"长尾 p100", skewed, 100, 5000},
+	}
+	for _, tt := range tests {
+		got := tt.h.Percentile(tt.q)
+		if math.Abs(got-tt.want) > tt.want/64+0.5 {
+			t.Errorf("%s: Percentile(%v) = %v, want %v（误差 1/64 内）", tt.name, tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestHistogramMerge(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float64
+	}{
+		{"两个非空", []float64{1, 5, 200, 3000}, []float64{2, 7000, 40}},
+		{"合并空直方图", []float64{3, 9}, nil},
+		{"合并进空直方图", nil, []float64{0, 150, 999}},
+	}
+	for _, tt := range tests {
+		a, b, all := NewHistogram(), NewHistogram(), NewHistogram()
+		for _, v := range tt.a {
+			a.RecordN(v, 1)
+			all.RecordN(v, 1)
+		}
+		for _, v := range tt.b {
+			b.RecordN(v, 1)
+			all.RecordN(v, 1)
+		}
+		a.Merge(b)
+		if a.Total != all.Total || a.Sum != all.Sum || a.Min != all.Min || a.Max != all.Max {
+			t.Errorf("%s: 合并后 total/sum/min/max = %d/%v/%v/%v, want %d/%v/%v/%v",
+				tt.name, a.Total, a.Sum, a.Min, a.Max, all.Total, all.Sum, all.Min, all.Max)
+		}
+		for _, q := range []float64{50, 95, 99} {
+			if a.Percentile(q) != all.Percentile(q) {
+				t.Errorf("%s: 合并后 p%v = %v, 直接记录为 %v", tt.name, q, a.Percentile(q), all.Percentile(q))
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RunTokenClaims runner 结果上传令牌携带的信息
+type RunTokenClaims struct {
+	TestID    int
+	RunPrefix string // 运行的结果文件前缀，唯一标识一次运行
+	ExpiresAt time.Time
+}
+
+func runTokenMAC(key []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// SignRunToken 签发绑定到单次运行的令牌：base64url(任务ID|前缀|过期时间) + "." + base64url(HMAC-SHA256)
+func SignRunToken(key []byte, c RunTokenClaims) string {
+	payload := fmt.Sprintf("%d|%s|%d", c.TestID, c.RunPrefix, c.ExpiresAt.Unix())
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString([]byte(payload)) + "." + enc.EncodeToString(runTokenMAC(key, payload))
+}
+
+// VerifyRunToken 校验签名与有效期，返回令牌携带的信息
+func VerifyRunToken(key []byte, token string) (RunTokenClaims, error) {
+	var c RunTokenClaims
+	enc := base64.RawURLEncoding
+	encodedPayload, encodedMAC, ok := strings.Cut(token, ".")
+	if !ok {
+		return c, errors.New("令牌格式错误")
+	}
+	payload, err1 := enc.DecodeString(encodedPayload)
+	sig, err2 := enc.DecodeString(encodedMAC)
+	if err1 != nil || err2 != nil {
+		return c, errors.New("令牌格式错误")
+	}
+	if !hmac.Equal(sig, runTokenMAC(key, string(payload))) {
+		return c, errors.New("令牌签名无效")
+	}
+
+	parts := strings.Split(string(payload), "|")
+	if len(parts) != 3 {
+		return c, errors.New("令牌格式错误")
+	}
+	testID, err1 := strconv.Atoi(parts[0])
+	exp, err2 := strconv.ParseInt(parts[2], 10, 64)
+	if err1 != nil || err2 != nil {
+		return c, errors.New("令牌格式错误")
+	}
+	c = RunTokenClaims{TestID: testID, RunPrefix: parts[1], ExpiresAt: time.Unix(exp, 0)}
+	if time.Now().After(c.ExpiresAt) {
+		return c, errors.New("令牌已过期")
+	}
+	return c, nil
+}
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyRunToken(t *testing.T) {
+	key := []byte("run-token-test-key")
+	claims := RunTokenClaims{TestID: 42, RunPrefix: "task_42_1700000000", ExpiresAt: time.Now().Add(time.Hour)}
+	valid := SignRunToken(key, claims)
+	payload, mac, _ := strings.Cut(valid, ".")
+	expired := SignRunToken(key, RunTokenClaims{TestID: 42, RunPrefix: "p", ExpiresAt: time.Now().Add(-time.Minute)})
+	// 篡改任务 ID 但沿用原签名
+	forged := SignRunToken(key, RunTokenClaims{TestID: 43, RunPrefix: claims.RunPrefix, ExpiresAt: claims.ExpiresAt})
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+
+	tests := []struct {
+		name    string
+		key     []byte
+		token   string
+		wantErr string
+	}{
+		{"有效令牌", key, valid, ""},
+		{"密钥不同", []byte("other-key"), valid, "令牌签名无效"},
+		{"已过期", key, expired, "令牌已过期"},
+		{"篡改内容", key, forgedPayload + "." + mac, "令牌签名无效"},
+		{"缺少签名", key, payload, "令牌格式错误"},
+		{"非 base64", key, "!!!." + mac, "令牌格式错误"},
+		{"空令牌", key, "", "令牌格式错误"},
+	}
+	for _, tt := range tests {
+		got, err := VerifyRunToken(tt.key, tt.token)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: 意外错误 %v", tt.name, err)
+			continue
+		}
+		if got.TestID != claims.TestID || got.RunPrefix != claims.RunPrefix || got.ExpiresAt.Unix() != claims.ExpiresAt.Unix() {
+			t.Errorf("%s: claims = %+v, want %+v", tt.name, got, claims)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package utils
+
+import "sync"
+
+// CPUSampler 以相邻两次读数之差计算整机 CPU 使用率
+type CPUSampler struct {
+	mu        sync.Mutex
+	lastIdle  uint64
+	lastTotal uint64
+}
+
+// Percent 返回自上次调用以来的 CPU 使用率（0~100），首次调用返回开机以来的平均值
+func (s *CPUSampler) Percent() (float64, error) {
+	idle, total, err := readCPUTimes()
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dIdle, dTotal := idle-s.lastIdle, total-s.lastTotal
+	s.lastIdle, s.lastTotal = idle, total
+	if dTotal == 0 {
+		return 0, nil
+	}
+	return 100 * float64(dTotal-dIdle) / float64(dTotal), nil
+}
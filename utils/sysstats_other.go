@@ -0,0 +1,16 @@
+//go:build !linux
+
+package utils
+
+import "errors"
+
+var errSysStatsUnsupported = errors.New("当前平台暂不支持采集 CPU / 内存使用率")
+
+func readCPUTimes() (idle, total uint64, err error) {
+	return 0, 0, errSysStatsUnsupported
+}
+
+// MemoryPercent 返回已用内存占比（0~100）
+func MemoryPercent() (float64, error) {
+	return 0, errSysStatsUnsupported
+}
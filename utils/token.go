@@ -1,37 +1,83 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 )
 
-var jwtKey = []byte("your_secret_key")
+// jwtKey access token 的 HS256 签名密钥，启动时由 SetJWTKey 设置
+var jwtKey []byte
+
+// errNoJWTKey 未设置签名密钥
+var errNoJWTKey = errors.New("未设置 JWT 签名密钥")
+
+// SetJWTKey 设置 access token 的签名密钥
+func SetJWTKey(key []byte) {
+	jwtKey = key
+}
+
+// access token 只短期有效，过期后用 refresh token 换取新的；refresh token 保存在服务端，每次使用后轮换
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 14 * 24 * time.Hour
+)
+
+// refreshTokenPrefix refresh token 的固定前缀
+const refreshTokenPrefix = "rt_"
+
+// randomToken 返回 n 字节随机数的 base64url 编码
+func randomToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
 
 type Claims struct {
 	UserID int    `json:"user_id"`
 	Role   string `json:"role"`
+	// 通过 API 令牌认证时为令牌 ID，JWT 认证时为 0
+	APIKeyID int `json:"-"`
 	jwt.RegisteredClaims
 }
 
+// GenerateToken 签发短期 access token，ID（jti）用于登出时加入黑名单
 func GenerateToken(userID int, role string) (string, error) {
+	if len(jwtKey) == 0 {
+		return "", errNoJWTKey
+	}
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+	now := time.Now()
 	claims := &Claims{
 		UserID: userID,
 		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        hex.EncodeToString(jti),
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(jwtKey)
 }
 
+// ParseToken 校验签名（只接受 HS256）与有效期
 func ParseToken(tokenString string) (*Claims, error) {
+	if len(jwtKey) == 0 {
+		return nil, errNoJWTKey
+	}
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(t *jwt.Token) (interface{}, error) {
 		return jwtKey, nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 	if err != nil {
 		return nil, err
 	}
@@ -40,3 +86,18 @@ func ParseToken(tokenString string) (*Claims, error) {
 	}
 	return nil, errors.New("invalid token")
 }
+
+// GenerateRefreshToken 生成 refresh token，返回明文与服务端保存的哈希
+func GenerateRefreshToken() (plain, hash string, err error) {
+	body, err := randomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	plain = refreshTokenPrefix + body
+	return plain, HashToken(plain), nil
+}
+
+// NewTokenFamily 生成 refresh token 家族标识，同一次登录轮换出的 refresh token 属于同一家族
+func NewTokenFamily() (string, error) {
+	return randomToken(16)
+}